@@ -6,10 +6,13 @@ import (
 	"errors"
 	"fmt"
 	"os/signal"
+	"path/filepath"
+	"strings"
 	"sync"
 	"syscall"
 	"time"
 
+	"github.com/Pro100x3mal/go-musthave-metrics/internal/agent/collectors"
 	"github.com/Pro100x3mal/go-musthave-metrics/internal/agent/configs"
 	"github.com/Pro100x3mal/go-musthave-metrics/internal/agent/infrastructure"
 	"github.com/Pro100x3mal/go-musthave-metrics/internal/agent/models"
@@ -49,9 +52,31 @@ func run() error {
 	}
 	defer logger.Sync()
 
-	repo := repositories.NewMemStorage()
-	collectService := services.NewMetricsCollectService(repo)
-	queryService := services.NewMetricsQueryService(repo)
+	enabled, err := collectors.Build(cfg)
+	if err != nil {
+		return fmt.Errorf("failed to configure collectors: %w", err)
+	}
+	names := make([]string, 0, len(enabled))
+	for _, c := range enabled {
+		names = append(names, c.Name())
+	}
+	logger.Info("collectors enabled", zap.Strings("collectors", names))
+
+	repo := repositories.NewMemStorage(cfg.GaugeAggregation)
+	collectService := services.NewMetricsCollectService(repo, enabled, logger)
+	var deadband *services.Deadband
+	if cfg.ChangeOnly {
+		deadband = services.NewDeadband(services.DeadbandOptions{
+			Absolute:         cfg.DeadbandAbs,
+			Relative:         cfg.DeadbandRel,
+			FullRefreshEvery: cfg.FullRefreshEvery,
+		})
+		logger.Info("reporting changed gauges only",
+			zap.Float64("deadband_abs", cfg.DeadbandAbs),
+			zap.Float64("deadband_rel", cfg.DeadbandRel),
+			zap.Int("full_refresh_every", cfg.FullRefreshEvery),
+		)
+	}
 
 	var publicKey *rsa.PublicKey
 	if cfg.PublicKeyPath != "" {
@@ -63,20 +88,86 @@ func run() error {
 		logger.Info("public key loaded successfully")
 	}
 
-	newClient := services.NewClient(cfg, publicKey)
+	var (
+		targets  []services.Target
+		failover *services.FailoverTransport
+	)
+	switch {
+	case cfg.GRPCAddr != "":
+		grpcClient, err := services.NewGRPCClient(cfg, publicKey)
+		if err != nil {
+			logger.Error("failed to create gRPC client", zap.Error(err))
+			return err
+		}
+		defer grpcClient.Close()
+		spool, err := openSpool(cfg, cfg.SpoolDir, logger)
+		if err != nil {
+			return err
+		}
+		targets = append(targets, services.Target{Name: cfg.GRPCAddr, Transport: grpcClient, Spool: spool})
+		logger.Info("sending metrics over gRPC", zap.String("address", cfg.GRPCAddr))
+
+	case cfg.ServerStrategy == configs.StrategyFanout:
+		for _, addr := range cfg.ServerAddrs() {
+			// Every server gets its own spool, so that an outage of one does not hold back the others.
+			var dir string
+			if cfg.SpoolDir != "" {
+				dir = filepath.Join(cfg.SpoolDir, spoolDirName(addr))
+			}
+			spool, err := openSpool(cfg, dir, logger)
+			if err != nil {
+				return err
+			}
+			targets = append(targets, services.Target{Name: addr, Transport: services.NewClient(cfg, addr, publicKey), Spool: spool})
+		}
+		logger.Info("sending metrics to all servers", zap.Strings("servers", cfg.ServerAddrs()))
+
+	default:
+		spool, err := openSpool(cfg, cfg.SpoolDir, logger)
+		if err != nil {
+			return err
+		}
+		addrs := cfg.ServerAddrs()
+		if len(addrs) == 1 {
+			targets = append(targets, services.Target{Name: addrs[0], Transport: services.NewClient(cfg, addrs[0], publicKey), Spool: spool})
+			break
+		}
+		servers := make([]services.Server, 0, len(addrs))
+		for _, addr := range addrs {
+			servers = append(servers, services.NewClient(cfg, addr, publicKey))
+		}
+		failover = services.NewFailoverTransport(addrs, servers, logger)
+		targets = append(targets, services.Target{Name: strings.Join(addrs, ","), Transport: failover, Spool: spool})
+		logger.Info("sending metrics to the first healthy server", zap.Strings("servers", addrs))
+	}
+
+	queryService := services.NewMetricsQueryService(repo, cfg.Labels, deadband, targets...)
+
 	pool := services.NewWorkerPool(cfg)
 	pool.Start()
 
-	tickerPoll := time.NewTicker(cfg.PollInterval)
 	tickerReport := time.NewTicker(cfg.ReportInterval)
 
 	var wg sync.WaitGroup
 
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		collectService.Run(ctx)
+	}()
+
+	if failover != nil {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			failover.RunHealthChecks(ctx)
+		}()
+	}
+
 	for {
 		select {
 		case <-ctx.Done():
 			logger.Info("shutdown signal received, waiting for operations to complete...")
-			tickerPoll.Stop()
 			tickerReport.Stop()
 			pool.Stop()
 
@@ -84,36 +175,44 @@ func run() error {
 			logger.Info("all operations completed, shutting down gracefully")
 			return nil
 
-		case <-tickerPoll.C:
-			wg.Add(1)
-			go func() {
-				defer wg.Done()
-				if err := collectService.UpdateAllMetrics(ctx); err != nil {
-					if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
-						logger.Debug("request cancelled")
-						return
-					}
-					logger.Error("failed to update metrics", zap.Error(err))
-					return
-				}
-			}()
-
 		case <-tickerReport.C:
 			pool.Submit(func() {
-				if err := queryService.SendMetrics(ctx, newClient); err != nil {
-					if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
-						logger.Debug("request cancelled")
-						return
-					}
+				err := queryService.SendMetrics(ctx)
+				switch {
+				case err == nil:
+					logger.Info("metrics sent successfully")
+				case errors.Is(err, services.ErrSpooled):
+					logger.Warn("failed to send metrics, spooled for later delivery", zap.Error(err))
+				case errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded):
+					logger.Debug("request cancelled")
+				default:
 					logger.Error("failed to send metrics", zap.Error(err))
-					return
-				}
-				logger.Info("metrics sent successfully")
-
-				if err := collectService.ResetPollCount(); err != nil {
-					logger.Error("failed to reset poll count", zap.Error(err))
 				}
 			})
 		}
 	}
 }
+
+// openSpool opens the spool in dir, or returns nil if dir is empty.
+func openSpool(cfg *configs.AgentConfig, dir string, logger *zap.Logger) (services.Spooler, error) {
+	if dir == "" {
+		return nil, nil
+	}
+	spool, err := repositories.NewSpool(dir, repositories.SpoolOptions{
+		MaxSize: cfg.SpoolMaxSize,
+		MaxAge:  cfg.SpoolMaxAge,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open spool: %w", err)
+	}
+	logger.Info("spooling undelivered metrics",
+		zap.String("dir", dir),
+		zap.Int("pending", spool.Len()),
+	)
+	return spool, nil
+}
+
+// spoolDirName turns a server address into a directory name, e.g. "localhost:8080" into "localhost_8080".
+func spoolDirName(addr string) string {
+	return strings.NewReplacer(":", "_", "/", "_", "\\", "_").Replace(addr)
+}
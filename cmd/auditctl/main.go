@@ -0,0 +1,156 @@
+// Command auditctl searches the audit log written by the server, including its rotated and compressed segments.
+//
+// Usage:
+//
+//	auditctl [-file audit.json | -server http://localhost:8080 -token secret]
+//	         [-metric Alloc*] [-ip 10.0.0.0/8] [-from 2024-01-01T00:00:00Z] [-to 1704067200]
+//	         [-limit 100] [-format table|json]
+//
+// With -file the log is read directly; with -server the query is sent to the server's /audit endpoint,
+// which requires the admin token. Times are RFC 3339 timestamps or Unix seconds. Events are listed newest first.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"text/tabwriter"
+	"time"
+
+	"github.com/Pro100x3mal/go-musthave-metrics/internal/server/infrastructure/audit"
+	"github.com/Pro100x3mal/go-musthave-metrics/internal/server/models"
+)
+
+const requestTimeout = 30 * time.Second
+
+type options struct {
+	file   string
+	server string
+	token  string
+	format string
+	query  url.Values
+}
+
+func main() {
+	var opts options
+	var metric, ip, from, to, limit string
+	flag.StringVar(&opts.file, "file", "audit.json", "path to audit log file")
+	flag.StringVar(&opts.server, "server", "", "server URL to query instead of reading the file, e.g. http://localhost:8080")
+	flag.StringVar(&opts.token, "token", "", "admin bearer token for -server")
+	flag.StringVar(&opts.format, "format", "table", "output format: table or json")
+	flag.StringVar(&metric, "metric", "", "metric name or glob pattern")
+	flag.StringVar(&ip, "ip", "", "client address or CIDR prefix")
+	flag.StringVar(&from, "from", "", "earliest event time (RFC 3339 or Unix seconds)")
+	flag.StringVar(&to, "to", "", "latest event time (RFC 3339 or Unix seconds)")
+	flag.StringVar(&limit, "limit", "", fmt.Sprintf("maximum number of events (default %d)", audit.DefaultQueryLimit))
+	flag.Parse()
+
+	opts.query = url.Values{}
+	for name, value := range map[string]string{"metric": metric, "ip": ip, "from": from, "to": to, "limit": limit} {
+		if value != "" {
+			opts.query.Set(name, value)
+		}
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer cancel()
+
+	if err := run(ctx, opts, os.Stdout); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func run(ctx context.Context, opts options, out io.Writer) error {
+	if opts.format != "table" && opts.format != "json" {
+		return fmt.Errorf("unknown format %q", opts.format)
+	}
+
+	q, err := audit.ParseQuery(opts.query)
+	if err != nil {
+		return err
+	}
+
+	var events []models.AuditEvent
+	if opts.server != "" {
+		events, err = queryServer(ctx, opts.server, opts.token, q)
+	} else {
+		events, err = audit.NewLogReader(opts.file).Query(ctx, q)
+	}
+	if err != nil {
+		return err
+	}
+
+	if opts.format == "json" {
+		enc := json.NewEncoder(out)
+		enc.SetIndent("", "  ")
+		return enc.Encode(events)
+	}
+	return writeTable(out, events)
+}
+
+func queryServer(ctx context.Context, server, token string, q audit.Query) ([]models.AuditEvent, error) {
+	ctx, cancel := context.WithTimeout(ctx, requestTimeout)
+	defer cancel()
+
+	endpoint := strings.TrimSuffix(server, "/") + "/audit?" + q.Values().Encode()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query server: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return nil, fmt.Errorf("server returned %s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+
+	var events []models.AuditEvent
+	if err = json.NewDecoder(resp.Body).Decode(&events); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+	return events, nil
+}
+
+func writeTable(out io.Writer, events []models.AuditEvent) error {
+	tw := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "TIME\tSEQ\tIP\tREQUEST\tAGENT\tMETRICS")
+	for _, e := range events {
+		agent := "-"
+		if e.Agent != nil {
+			agent = strings.Trim(e.Agent.KeyID+"/"+e.Agent.TLSCommonName, "/")
+		}
+		fmt.Fprintf(tw, "%s\t%d\t%s\t%s\t%s\t%s\n",
+			time.Unix(e.Timestamp, 0).UTC().Format(time.RFC3339),
+			e.Seq,
+			orDash(e.IPAddress),
+			orDash(e.RequestID),
+			agent,
+			strings.Join(e.Metrics, ","),
+		)
+	}
+	return tw.Flush()
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
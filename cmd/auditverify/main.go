@@ -0,0 +1,114 @@
+// Command auditverify checks the hash chain of an audit log written by the server
+// and reports the first broken or missing link.
+//
+// Usage:
+//
+//	auditverify -file audit.json [-key secret] [-start-seq N [-start-hash hash]]
+//
+// The key must match the server's audit HMAC key if one is configured.
+// The rotated segments of the log are checked oldest first, followed by the active file, as one chain;
+// compressed segments (.gz) are read transparently.
+// The chain must start at seq 1. If older segments were removed by retention, pass the sequence number
+// of the first record expected with -start-seq, and optionally the hash of the record before it with -start-hash.
+// The exit status is 1 if the chain is broken and 2 if the log cannot be read.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/Pro100x3mal/go-musthave-metrics/internal/server/infrastructure/audit"
+)
+
+func main() {
+	path := flag.String("file", "audit.json", "path to audit log file")
+	key := flag.String("key", "", "audit HMAC key (plain SHA-256 chaining if empty)")
+	startSeq := flag.Uint64("start-seq", 1, "sequence number the chain is expected to start at")
+	startHash := flag.String("start-hash", "", "hash of the record before -start-seq (not checked if empty)")
+	flag.Parse()
+
+	if *startSeq == 0 {
+		fmt.Fprintln(os.Stderr, "-start-seq must be at least 1")
+		os.Exit(2)
+	}
+	start := audit.ChainStart{Seq: *startSeq - 1, PrevHash: *startHash}
+
+	result, err := verify(*path, *key, start)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+
+	report := result.report
+	fmt.Printf("checked %d records in %d segments", report.Records, result.segments)
+	if report.Unchained > 0 {
+		fmt.Printf(" (%d written before chaining)", report.Unchained)
+	}
+	if report.LastSeq > 0 {
+		fmt.Printf(", seq %d..%d", report.FirstSeq, report.LastSeq)
+	}
+	fmt.Println()
+
+	if report.Broken != nil {
+		fmt.Printf("chain broken in %s at %s\n", result.brokenIn, report.Broken)
+		os.Exit(1)
+	}
+	fmt.Println("chain intact")
+}
+
+type verifyResult struct {
+	// report sums up the reports of the segments checked.
+	report   audit.ChainReport
+	segments int
+	// brokenIn is the segment the chain breaks in, if it does.
+	brokenIn string
+}
+
+// verify checks the segments of the audit log at path as one chain, continuing each segment from the last
+// record of the previous one, and stops at the first break.
+func verify(path, key string, start audit.ChainStart) (*verifyResult, error) {
+	segments, err := audit.Segments(path)
+	if err != nil {
+		return nil, err
+	}
+	if len(segments) == 0 {
+		return nil, fmt.Errorf("audit log %s not found", path)
+	}
+
+	result := &verifyResult{}
+	for _, name := range segments {
+		report, err := verifySegment(name, key, start)
+		if err != nil {
+			return nil, err
+		}
+		result.segments++
+
+		sum := &result.report
+		sum.Records += report.Records
+		sum.Unchained += report.Unchained
+		if sum.FirstSeq == 0 {
+			sum.FirstSeq = report.FirstSeq
+		}
+		if report.LastSeq > 0 {
+			sum.LastSeq = report.LastSeq
+		}
+		if report.Broken != nil {
+			sum.Broken = report.Broken
+			result.brokenIn = name
+			return result, nil
+		}
+		start = report.Next(start)
+	}
+	return result, nil
+}
+
+func verifySegment(name, key string, start audit.ChainStart) (*audit.ChainReport, error) {
+	r, err := audit.OpenSegment(name)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	return audit.VerifyChain(r, key, start)
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Pro100x3mal/go-musthave-metrics/internal/server/infrastructure/audit"
+	"github.com/Pro100x3mal/go-musthave-metrics/internal/server/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeSegments(t *testing.T, key string) (string, []string) {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "audit.json")
+	obs, err := audit.NewFileAuditObserver(path, key, audit.RotationOptions{MaxSize: 1, Compress: true})
+	require.NoError(t, err)
+	for i := 0; i < 4; i++ {
+		require.NoError(t, obs.Notify(context.Background(), &models.AuditEvent{Timestamp: int64(i), Metrics: []string{"Alloc"}}))
+	}
+	require.NoError(t, obs.Close())
+
+	segments, err := audit.Segments(path)
+	require.NoError(t, err)
+	require.Len(t, segments, 4)
+	return path, segments
+}
+
+func TestVerify(t *testing.T) {
+	path, segments := writeSegments(t, "secret")
+
+	result, err := verify(path, "secret", audit.ChainStart{})
+	require.NoError(t, err)
+	assert.Nil(t, result.report.Broken)
+	assert.Equal(t, 4, result.segments)
+	assert.Equal(t, 4, result.report.Records)
+	assert.Equal(t, uint64(1), result.report.FirstSeq)
+	assert.Equal(t, uint64(4), result.report.LastSeq)
+
+	// A wrong key breaks the link between the first and the second segment.
+	result, err = verify(path, "wrong", audit.ChainStart{})
+	require.NoError(t, err)
+	require.NotNil(t, result.report.Broken)
+	assert.Equal(t, segments[1], result.brokenIn)
+}
+
+func TestVerify_RemovedSegments(t *testing.T) {
+	path, segments := writeSegments(t, "")
+
+	// A removed middle segment is a missing link.
+	require.NoError(t, os.Remove(segments[1]))
+	result, err := verify(path, "", audit.ChainStart{})
+	require.NoError(t, err)
+	require.NotNil(t, result.report.Broken)
+	assert.Equal(t, segments[2], result.brokenIn)
+	assert.Contains(t, result.report.Broken.Reason, "expected seq 2")
+
+	// A removed head is a break unless the expected start is given.
+	require.NoError(t, os.Remove(segments[0]))
+	result, err = verify(path, "", audit.ChainStart{})
+	require.NoError(t, err)
+	require.NotNil(t, result.report.Broken)
+	assert.Contains(t, result.report.Broken.Reason, "chain starts at seq 3")
+
+	result, err = verify(path, "", audit.ChainStart{Seq: 2})
+	require.NoError(t, err)
+	assert.Nil(t, result.report.Broken)
+	assert.Equal(t, uint64(3), result.report.FirstSeq)
+}
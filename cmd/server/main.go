@@ -4,12 +4,15 @@ import (
 	"context"
 	"crypto/rsa"
 	"fmt"
+	"os"
 	"os/signal"
 	"sync"
 	"syscall"
 	"time"
 
+	"github.com/Pro100x3mal/go-musthave-metrics/internal/server/alerting"
 	"github.com/Pro100x3mal/go-musthave-metrics/internal/server/configs"
+	"github.com/Pro100x3mal/go-musthave-metrics/internal/server/grpcserver"
 	"github.com/Pro100x3mal/go-musthave-metrics/internal/server/handlers"
 	"github.com/Pro100x3mal/go-musthave-metrics/internal/server/infrastructure/audit"
 	"github.com/Pro100x3mal/go-musthave-metrics/internal/server/infrastructure/logger"
@@ -17,10 +20,13 @@ import (
 	"github.com/Pro100x3mal/go-musthave-metrics/internal/server/repositories"
 	"github.com/Pro100x3mal/go-musthave-metrics/internal/server/repositories/retry"
 	"github.com/Pro100x3mal/go-musthave-metrics/internal/server/services"
+	"github.com/Pro100x3mal/go-musthave-metrics/internal/server/statsd"
 	"github.com/Pro100x3mal/go-musthave-metrics/pkg/crypto"
 	"go.uber.org/zap"
 )
 
+const auditShutdownTimeout = 10 * time.Second
+
 func main() {
 	mainLogger := zap.NewExample()
 	defer mainLogger.Sync()
@@ -67,18 +73,21 @@ func run() error {
 	}
 
 	var repo repositories.Repository
+	var dbRepo *repositories.DB
+	var historyStore repositories.HistoryPruner
 	var wg sync.WaitGroup
 
 	switch {
 	case cfg.DatabaseDSN != "":
 		dbLogger := zLog.Named("database")
-		dbRepo, err := repositories.NewDB(ctx, cfg, dbLogger)
+		dbRepo, err = repositories.NewDB(ctx, cfg, dbLogger)
 		if err != nil {
 			dbLogger.Error("failed to initialize database storage", zap.Error(err))
 			return err
 		}
 		defer dbRepo.Close()
 		repo = retry.NewRepoWithRetry(dbRepo, []time.Duration{}, 0)
+		historyStore = dbRepo
 	case cfg.FileStoragePath != "":
 		fsLogger := zLog.Named("file_storage")
 		msRepo := repositories.NewMemStorage()
@@ -87,20 +96,60 @@ func run() error {
 			fsLogger.Error("failed to initialize file storage", zap.Error(err))
 			return err
 		}
+		historyStore = msRepo
 	default:
 		msLogger := zLog.Named("memory_storage")
 		msLogger.Info("initializing in-memory storage")
-		repo = repositories.NewMemStorage()
+		msRepo := repositories.NewMemStorage()
+		repo = msRepo
+		historyStore = msRepo
 		msLogger.Info("in-memory storage initialized successfully")
 	}
 
+	historyLogger := zLog.Named("history")
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		repositories.RunHistoryRetention(ctx, historyStore, cfg.HistoryRetention, historyLogger)
+	}()
+	historyLogger.Info("metric history retention enabled", zap.Duration("retention", cfg.HistoryRetention))
+
 	service := services.NewMetricsService(repo)
 
 	auditLogger := zLog.Named("audit")
-	auditManager := audit.NewAuditManager(auditLogger)
+	auditManager := audit.NewAuditManager(auditLogger, audit.Options{
+		QueueSize:      cfg.AuditQueueSize,
+		Workers:        cfg.AuditWorkers,
+		DeadLetterPath: cfg.AuditDeadLetter,
+	})
+
+	fileFilter, err := newAuditFilter(cfg.AuditFileFilter)
+	if err != nil {
+		return err
+	}
+	urlFilter, err := newAuditFilter(cfg.AuditURLFilter)
+	if err != nil {
+		return err
+	}
+	syslogFilter, err := newAuditFilter(cfg.AuditSyslogFilter)
+	if err != nil {
+		return err
+	}
+	dbFilter, err := newAuditFilter(cfg.AuditDBFilter)
+	if err != nil {
+		return err
+	}
 
 	if cfg.AuditFile != "" {
-		fileObserver, err := audit.NewFileAuditObserver(cfg.AuditFile)
+		fileObserver, err := audit.NewFileAuditObserver(cfg.AuditFile, cfg.AuditHMACKey, audit.RotationOptions{
+			MaxSize:    int64(cfg.AuditMaxSize) << 20,
+			MaxAge:     cfg.AuditMaxAge,
+			MaxBackups: cfg.AuditMaxBackups,
+			Compress:   cfg.AuditCompress,
+			OnError: func(err error) {
+				auditLogger.Error("audit file error", zap.Error(err))
+			},
+		})
 		if err != nil {
 			auditLogger.Error("failed to initialize file audit observer", zap.Error(err))
 			return err
@@ -111,18 +160,129 @@ func run() error {
 				auditLogger.Error("failed to close file audit observer", zap.Error(err))
 			}
 		}(fileObserver)
-		auditManager.Attach(fileObserver)
+		auditManager.AttachFiltered(fileObserver, fileFilter)
 		auditLogger.Info("file audit observer enabled", zap.String("file", cfg.AuditFile))
+
+		// SIGHUP reopens the audit file after it has been moved by external log rotation.
+		hup := make(chan os.Signal, 1)
+		signal.Notify(hup, syscall.SIGHUP)
+		defer signal.Stop(hup)
+		go func() {
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case <-hup:
+					if err := fileObserver.Reopen(); err != nil {
+						auditLogger.Error("failed to reopen audit file", zap.Error(err))
+						continue
+					}
+					auditLogger.Info("audit file reopened", zap.String("file", cfg.AuditFile))
+				}
+			}
+		}()
 	}
 
 	if cfg.AuditURL != "" {
 		httpObserver := audit.NewHTTPAuditObserver(cfg.AuditURL)
-		auditManager.Attach(httpObserver)
+		auditManager.AttachFiltered(httpObserver, urlFilter)
 		auditLogger.Info("HTTP audit observer enabled", zap.String("url", cfg.AuditURL))
 	}
 
+	if cfg.AuditSyslog != "" {
+		syslogObserver, err := audit.NewSyslogAuditObserver(audit.SyslogOptions{
+			Address:  cfg.AuditSyslog,
+			Facility: cfg.AuditSyslogFacility,
+			AppName:  cfg.AuditSyslogAppName,
+		})
+		if err != nil {
+			auditLogger.Error("failed to initialize syslog audit observer", zap.Error(err))
+			return err
+		}
+		defer func() {
+			if err := syslogObserver.Close(); err != nil {
+				auditLogger.Error("failed to close syslog audit observer", zap.Error(err))
+			}
+		}()
+		auditManager.AttachFiltered(syslogObserver, syslogFilter)
+		auditLogger.Info("syslog audit observer enabled", zap.String("address", cfg.AuditSyslog))
+	}
+
+	if dbRepo != nil {
+		dbObserver := audit.NewDBAuditObserver(dbRepo, auditLogger)
+		auditManager.AttachFiltered(dbObserver, dbFilter)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			dbObserver.RunRetention(ctx, cfg.AuditDBRetention)
+		}()
+		auditLogger.Info("database audit observer enabled", zap.Duration("retention", cfg.AuditDBRetention))
+	}
+
+	// Registered after the observers' Close so that queued events are delivered before the files are closed.
+	defer func() {
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), auditShutdownTimeout)
+		defer cancel()
+		if err := auditManager.Shutdown(shutdownCtx); err != nil {
+			auditLogger.Error("failed to drain audit queue", zap.Error(err))
+		}
+	}()
+
 	handler := handlers.NewMetricsHandler(service, srvLogger, cfg, auditManager, privateKey)
 
+	if cfg.AlertRulesFile != "" {
+		alertLogger := zLog.Named("alerting")
+		rules, err := alerting.LoadRules(cfg.AlertRulesFile)
+		if err != nil {
+			alertLogger.Error("failed to load alert rules", zap.Error(err))
+			return err
+		}
+
+		notifiers := []alerting.Notifier{alerting.NewLogNotifier(alertLogger)}
+		if cfg.AlertWebhookURL != "" {
+			notifiers = append(notifiers, alerting.NewWebhookNotifier(cfg.AlertWebhookURL))
+			alertLogger.Info("alert webhook enabled", zap.String("url", cfg.AlertWebhookURL))
+		}
+
+		engine := alerting.NewEngine(repo, rules, cfg.AlertInterval, alertLogger, notifiers...)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			engine.Run(ctx)
+		}()
+		handler.SetAlertsProvider(engine)
+	}
+
+	if cfg.StatsDAddr != "" {
+		statsdLogger := zLog.Named("statsd")
+		statsdServer, err := statsd.NewServer(cfg.StatsDAddr, cfg.StatsDFlush, service, auditManager, statsdLogger)
+		if err != nil {
+			statsdLogger.Error("failed to start statsd listener", zap.Error(err))
+			return err
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			statsdServer.Run(ctx)
+		}()
+	}
+
+	if cfg.GRPCAddr != "" {
+		grpcLogger := zLog.Named("grpc")
+		grpcServer, err := grpcserver.NewServer(cfg.GRPCAddr, service, auditManager, cfg.Key, privateKey, grpcLogger)
+		if err != nil {
+			grpcLogger.Error("failed to start gRPC server", zap.Error(err))
+			return err
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := grpcServer.Run(ctx); err != nil {
+				grpcLogger.Error("gRPC server failed", zap.Error(err))
+			}
+		}()
+	}
+
 	if err = handler.StartServer(ctx); err != nil {
 		srvLogger.Error("server failed", zap.Error(err))
 	}
@@ -131,3 +291,12 @@ func run() error {
 	mainLogger.Info("application stopped gracefully")
 	return err
 }
+
+// newAuditFilter compiles an audit observer filter spec; an empty spec delivers all events.
+func newAuditFilter(spec string) (*audit.Filter, error) {
+	rules, err := audit.ParseFilterRules(spec)
+	if err != nil {
+		return nil, err
+	}
+	return audit.NewFilter(rules)
+}
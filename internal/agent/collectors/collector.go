@@ -0,0 +1,134 @@
+// Package collectors provides the metric sources of the agent.
+//
+// A collector is registered under a unique name from an init function with Register, and the agent builds
+// the collectors enabled in its configuration with Build. Each collector is polled on its own interval,
+// so adding a collector only takes a new file in this package (or any package imported by the agent).
+package collectors
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/Pro100x3mal/go-musthave-metrics/internal/agent/configs"
+	"github.com/Pro100x3mal/go-musthave-metrics/internal/agent/models"
+)
+
+// Collector is a source of metrics polled by the agent.
+type Collector interface {
+	// Name is the name the collector is registered and configured under.
+	Name() string
+	// Interval is how often the collector is polled.
+	Interval() time.Duration
+	// Collect returns the current values of the collector's metrics.
+	Collect(ctx context.Context) ([]*models.Metrics, error)
+}
+
+// Factory creates a collector from the agent configuration.
+type Factory func(cfg *configs.AgentConfig) (Collector, error)
+
+type funcCollector struct {
+	name     string
+	interval time.Duration
+	collect  func(ctx context.Context) ([]*models.Metrics, error)
+}
+
+// New returns a collector with the given name and interval that calls collect.
+func New(name string, interval time.Duration, collect func(ctx context.Context) ([]*models.Metrics, error)) Collector {
+	return &funcCollector{
+		name:     name,
+		interval: interval,
+		collect:  collect,
+	}
+}
+
+func (c *funcCollector) Name() string            { return c.name }
+func (c *funcCollector) Interval() time.Duration { return c.interval }
+func (c *funcCollector) Collect(ctx context.Context) ([]*models.Metrics, error) {
+	return c.collect(ctx)
+}
+
+var (
+	registryMu sync.Mutex
+	registry   = make(map[string]Factory)
+	duplicates []string
+)
+
+// Register makes a collector available under name. It is meant to be called from init functions;
+// registering a name twice is reported by Build.
+func Register(name string, factory Factory) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	if _, ok := registry[name]; ok {
+		duplicates = append(duplicates, name)
+		return
+	}
+	registry[name] = factory
+}
+
+// Names returns the names of the registered collectors in alphabetical order.
+func Names() []string {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	names := make([]string, 0, len(registry))
+	for name := range registry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Build creates the collectors enabled in cfg: those listed in Collectors, or all registered ones if the list is empty,
+// except those listed in DisabledCollectors. Unknown names in either list are an error.
+func Build(cfg *configs.AgentConfig) ([]Collector, error) {
+	registryMu.Lock()
+	if len(duplicates) > 0 {
+		registryMu.Unlock()
+		return nil, fmt.Errorf("collectors registered more than once: %v", duplicates)
+	}
+	registryMu.Unlock()
+
+	names := Names()
+	for _, name := range append(slices.Clone(cfg.Collectors), cfg.DisabledCollectors...) {
+		if !slices.Contains(names, name) {
+			return nil, fmt.Errorf("unknown collector %q, available: %v", name, names)
+		}
+	}
+
+	enabled := names
+	if len(cfg.Collectors) > 0 {
+		enabled = cfg.Collectors
+	}
+
+	var collectors []Collector
+	built := make(map[string]bool, len(enabled))
+	for _, name := range enabled {
+		if built[name] || slices.Contains(cfg.DisabledCollectors, name) {
+			continue
+		}
+		built[name] = true
+		registryMu.Lock()
+		factory := registry[name]
+		registryMu.Unlock()
+
+		c, err := factory(cfg)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create collector %q: %w", name, err)
+		}
+		collectors = append(collectors, c)
+	}
+	return collectors, nil
+}
+
+func gauge(name string, value float64) *models.Metrics {
+	return &models.Metrics{ID: name, MType: models.Gauge, Value: &value}
+}
+
+func counter(name string, delta int64) *models.Metrics {
+	return &models.Metrics{ID: name, MType: models.Counter, Delta: &delta}
+}
@@ -0,0 +1,55 @@
+package collectors
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Pro100x3mal/go-musthave-metrics/internal/agent/configs"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func builtNames(t *testing.T, cfg *configs.AgentConfig) []string {
+	t.Helper()
+	cs, err := Build(cfg)
+	require.NoError(t, err)
+	names := make([]string, 0, len(cs))
+	for _, c := range cs {
+		names = append(names, c.Name())
+	}
+	return names
+}
+
+func TestBuild(t *testing.T) {
+	assert.Equal(t, Names(), builtNames(t, &configs.AgentConfig{PollInterval: time.Second}))
+
+	assert.Equal(t, []string{MemoryName, RuntimeName}, builtNames(t, &configs.AgentConfig{
+		PollInterval: time.Second,
+		Collectors:   []string{MemoryName, RuntimeName, MemoryName},
+	}))
+
+	names := builtNames(t, &configs.AgentConfig{
+		PollInterval:       time.Second,
+		DisabledCollectors: []string{CPUName, RandomName},
+	})
+	assert.NotContains(t, names, CPUName)
+	assert.NotContains(t, names, RandomName)
+	assert.Contains(t, names, RuntimeName)
+
+	_, err := Build(&configs.AgentConfig{Collectors: []string{"nope"}})
+	assert.Error(t, err)
+	_, err = Build(&configs.AgentConfig{DisabledCollectors: []string{"nope"}})
+	assert.Error(t, err)
+}
+
+func TestBuild_Intervals(t *testing.T) {
+	cs, err := Build(&configs.AgentConfig{
+		PollInterval:       2 * time.Second,
+		Collectors:         []string{RuntimeName, MemoryName},
+		CollectorIntervals: map[string]time.Duration{MemoryName: 30 * time.Second},
+	})
+	require.NoError(t, err)
+	require.Len(t, cs, 2)
+	assert.Equal(t, 2*time.Second, cs[0].Interval())
+	assert.Equal(t, 30*time.Second, cs[1].Interval())
+}
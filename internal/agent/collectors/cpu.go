@@ -0,0 +1,119 @@
+package collectors
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/Pro100x3mal/go-musthave-metrics/internal/agent/configs"
+	"github.com/Pro100x3mal/go-musthave-metrics/internal/agent/models"
+	"github.com/shirou/gopsutil/v4/cpu"
+)
+
+// CPUName is the name of the collector of CPU utilization.
+const CPUName = "cpu"
+
+func init() {
+	Register(CPUName, func(cfg *configs.AgentConfig) (Collector, error) {
+		s := &cpuSampler{}
+		// Prime the sampler so that the first poll already reports utilization over the poll interval.
+		// A failure here is not fatal: the first poll then only takes the snapshot.
+		_ = s.sample(context.Background())
+		return New(CPUName, cfg.CollectorInterval(CPUName), s.collect), nil
+	})
+}
+
+// cpuSampler computes CPU utilization from the difference between two cpu.Times snapshots,
+// so a poll returns immediately instead of sleeping through a measurement interval.
+type cpuSampler struct {
+	mu       sync.Mutex
+	prevAll  *cpu.TimesStat
+	prevCPUs []cpu.TimesStat
+}
+
+// cpuUsage is the share of CPU time, in percent, spent in each state between two snapshots.
+type cpuUsage struct {
+	busy, user, system, iowait, steal float64
+}
+
+// collect reports CPUutilizationTotal, CPUutilizationUser, CPUutilizationSystem, CPUutilizationIOWait and
+// CPUutilizationSteal for all CPUs together and CPUutilizationN for each CPU, over the time since the previous poll.
+// The first poll without a previous snapshot reports nothing.
+func (s *cpuSampler) collect(ctx context.Context) ([]*models.Metrics, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	prevAll, prevCPUs := s.prevAll, s.prevCPUs
+	if err := s.sample(ctx); err != nil {
+		return nil, err
+	}
+	if prevAll == nil {
+		return nil, nil
+	}
+
+	usage := cpuPercentages(*prevAll, *s.prevAll)
+	metrics := []*models.Metrics{
+		gauge("CPUutilizationTotal", usage.busy),
+		gauge("CPUutilizationUser", usage.user),
+		gauge("CPUutilizationSystem", usage.system),
+		gauge("CPUutilizationIOWait", usage.iowait),
+		gauge("CPUutilizationSteal", usage.steal),
+	}
+
+	// Per-CPU values are only comparable if the set of CPUs has not changed, e.g. through hotplug.
+	if len(prevCPUs) == len(s.prevCPUs) {
+		for i := range s.prevCPUs {
+			metrics = append(metrics, gauge(fmt.Sprintf("CPUutilization%d", i), cpuPercentages(prevCPUs[i], s.prevCPUs[i]).busy))
+		}
+	}
+	return metrics, nil
+}
+
+// sample replaces the stored snapshots with the current CPU times. The caller holds s.mu, except during construction.
+func (s *cpuSampler) sample(ctx context.Context) error {
+	all, err := cpu.TimesWithContext(ctx, false)
+	if err != nil {
+		return fmt.Errorf("failed to get CPU times: %w", err)
+	}
+	if len(all) == 0 {
+		return fmt.Errorf("failed to get CPU times: no data")
+	}
+	perCPU, err := cpu.TimesWithContext(ctx, true)
+	if err != nil {
+		return fmt.Errorf("failed to get per-CPU times: %w", err)
+	}
+
+	s.prevAll = &all[0]
+	s.prevCPUs = perCPU
+	return nil
+}
+
+// cpuPercentages returns the CPU usage between the snapshots prev and cur of the same CPU.
+// Guest time is already accounted for in user time and is not counted again.
+func cpuPercentages(prev, cur cpu.TimesStat) cpuUsage {
+	total := cpuTotal(cur) - cpuTotal(prev)
+	if total <= 0 {
+		return cpuUsage{}
+	}
+
+	percent := func(prev, cur float64) float64 {
+		return clampPercent((cur - prev) / total * 100)
+	}
+	idle := (cur.Idle + cur.Iowait) - (prev.Idle + prev.Iowait)
+	return cpuUsage{
+		busy:   clampPercent((total - idle) / total * 100),
+		user:   percent(prev.User+prev.Nice, cur.User+cur.Nice),
+		system: percent(prev.System+prev.Irq+prev.Softirq, cur.System+cur.Irq+cur.Softirq),
+		iowait: percent(prev.Iowait, cur.Iowait),
+		steal:  percent(prev.Steal, cur.Steal),
+	}
+}
+
+func cpuTotal(t cpu.TimesStat) float64 {
+	return t.User + t.Nice + t.System + t.Idle + t.Iowait + t.Irq + t.Softirq + t.Steal
+}
+
+// clampPercent keeps a percentage in [0, 100]; counters read non-atomically may be slightly inconsistent.
+func clampPercent(p float64) float64 {
+	return max(0, min(100, p))
+}
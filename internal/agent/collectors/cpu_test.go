@@ -0,0 +1,46 @@
+package collectors
+
+import (
+	"context"
+	"testing"
+
+	"github.com/shirou/gopsutil/v4/cpu"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCPUPercentages(t *testing.T) {
+	prev := cpu.TimesStat{User: 100, System: 50, Idle: 800, Iowait: 30, Steal: 20}
+	cur := cpu.TimesStat{User: 140, System: 60, Idle: 830, Iowait: 40, Steal: 30}
+
+	usage := cpuPercentages(prev, cur)
+	assert.InDelta(t, 60, usage.busy, 1e-9)
+	assert.InDelta(t, 40, usage.user, 1e-9)
+	assert.InDelta(t, 10, usage.system, 1e-9)
+	assert.InDelta(t, 10, usage.iowait, 1e-9)
+	assert.InDelta(t, 10, usage.steal, 1e-9)
+
+	assert.Equal(t, cpuUsage{}, cpuPercentages(cur, cur))
+	assert.Equal(t, cpuUsage{}, cpuPercentages(cur, prev))
+}
+
+func TestCPUSampler(t *testing.T) {
+	s := &cpuSampler{}
+	metrics, err := s.collect(context.Background())
+	if err != nil {
+		t.Skipf("CPU times are not available: %v", err)
+	}
+	assert.Empty(t, metrics)
+
+	metrics, err = s.collect(context.Background())
+	require.NoError(t, err)
+	ids := make(map[string]bool, len(metrics))
+	for _, m := range metrics {
+		ids[m.ID] = true
+		assert.GreaterOrEqual(t, *m.Value, 0.0)
+		assert.LessOrEqual(t, *m.Value, 100.0)
+	}
+	for _, id := range []string{"CPUutilizationTotal", "CPUutilizationUser", "CPUutilizationSystem", "CPUutilizationIOWait", "CPUutilizationSteal", "CPUutilization0"} {
+		assert.True(t, ids[id], id)
+	}
+}
@@ -0,0 +1,50 @@
+package collectors
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Pro100x3mal/go-musthave-metrics/internal/agent/configs"
+	"github.com/Pro100x3mal/go-musthave-metrics/internal/agent/models"
+	"github.com/shirou/gopsutil/v4/disk"
+)
+
+// DiskIOName is the name of the collector of per-device disk IO statistics.
+const DiskIOName = "diskio"
+
+func init() {
+	Register(DiskIOName, func(cfg *configs.AgentConfig) (Collector, error) {
+		filter, err := newPatternFilter(cfg.DiskInclude, cfg.DiskExclude)
+		if err != nil {
+			return nil, err
+		}
+		return New(DiskIOName, cfg.CollectorInterval(DiskIOName), func(ctx context.Context) ([]*models.Metrics, error) {
+			return collectDiskIO(ctx, filter)
+		}), nil
+	})
+}
+
+// collectDiskIO reports the cumulative DiskReadBytes_<dev>, DiskWriteBytes_<dev>, DiskReadCount_<dev>,
+// DiskWriteCount_<dev> and DiskIOTime_<dev> (in milliseconds) of each selected block device since boot.
+func collectDiskIO(ctx context.Context, filter *patternFilter) ([]*models.Metrics, error) {
+	counters, err := disk.IOCountersWithContext(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get disk IO counters: %w", err)
+	}
+
+	metrics := make([]*models.Metrics, 0, 5*len(counters))
+	for name, c := range counters {
+		if !filter.match(name) {
+			continue
+		}
+		suffix := metricSuffix(name)
+		metrics = append(metrics,
+			gauge("DiskReadBytes_"+suffix, float64(c.ReadBytes)),
+			gauge("DiskWriteBytes_"+suffix, float64(c.WriteBytes)),
+			gauge("DiskReadCount_"+suffix, float64(c.ReadCount)),
+			gauge("DiskWriteCount_"+suffix, float64(c.WriteCount)),
+			gauge("DiskIOTime_"+suffix, float64(c.IoTime)),
+		)
+	}
+	return metrics, nil
+}
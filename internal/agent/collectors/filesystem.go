@@ -0,0 +1,70 @@
+package collectors
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/Pro100x3mal/go-musthave-metrics/internal/agent/configs"
+	"github.com/Pro100x3mal/go-musthave-metrics/internal/agent/models"
+	"github.com/shirou/gopsutil/v4/disk"
+)
+
+// FilesystemName is the name of the collector of per-mountpoint filesystem usage.
+const FilesystemName = "filesystem"
+
+func init() {
+	Register(FilesystemName, func(cfg *configs.AgentConfig) (Collector, error) {
+		filter, err := newPatternFilter(cfg.FilesystemInclude, cfg.FilesystemExclude)
+		if err != nil {
+			return nil, err
+		}
+		return New(FilesystemName, cfg.CollectorInterval(FilesystemName), func(ctx context.Context) ([]*models.Metrics, error) {
+			return collectFilesystems(ctx, filter)
+		}), nil
+	})
+}
+
+// collectFilesystems reports FilesystemTotal_<mount>, FilesystemUsed_<mount>, FilesystemFree_<mount> (in bytes)
+// and FilesystemUsedPercent_<mount> for each selected physical filesystem. Filesystems whose usage cannot be read,
+// e.g. for lack of permissions, are skipped unless none can be read.
+func collectFilesystems(ctx context.Context, filter *patternFilter) ([]*models.Metrics, error) {
+	partitions, err := disk.PartitionsWithContext(ctx, false)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list partitions: %w", err)
+	}
+
+	var (
+		metrics []*models.Metrics
+		errs    []error
+		seen    = make(map[string]bool, len(partitions))
+	)
+	for _, p := range partitions {
+		if seen[p.Mountpoint] || !filter.match(p.Mountpoint) {
+			continue
+		}
+		seen[p.Mountpoint] = true
+
+		usage, err := disk.UsageWithContext(ctx, p.Mountpoint)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			errs = append(errs, fmt.Errorf("failed to get usage of %s: %w", p.Mountpoint, err))
+			continue
+		}
+
+		suffix := metricSuffix(p.Mountpoint)
+		metrics = append(metrics,
+			gauge("FilesystemTotal_"+suffix, float64(usage.Total)),
+			gauge("FilesystemUsed_"+suffix, float64(usage.Used)),
+			gauge("FilesystemFree_"+suffix, float64(usage.Free)),
+			gauge("FilesystemUsedPercent_"+suffix, usage.UsedPercent),
+		)
+	}
+
+	if len(metrics) == 0 && len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+	return metrics, nil
+}
@@ -0,0 +1,33 @@
+package collectors
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Pro100x3mal/go-musthave-metrics/internal/agent/configs"
+	"github.com/Pro100x3mal/go-musthave-metrics/internal/agent/models"
+	"github.com/shirou/gopsutil/v4/load"
+)
+
+// LoadName is the name of the collector of the system load averages.
+const LoadName = "load"
+
+func init() {
+	Register(LoadName, func(cfg *configs.AgentConfig) (Collector, error) {
+		return New(LoadName, cfg.CollectorInterval(LoadName), collectLoad), nil
+	})
+}
+
+// collectLoad reports the 1, 5 and 15-minute load averages as Load1, Load5 and Load15.
+func collectLoad(ctx context.Context) ([]*models.Metrics, error) {
+	avg, err := load.AvgWithContext(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get load averages: %w", err)
+	}
+
+	return []*models.Metrics{
+		gauge("Load1", avg.Load1),
+		gauge("Load5", avg.Load5),
+		gauge("Load15", avg.Load15),
+	}, nil
+}
@@ -0,0 +1,31 @@
+package collectors
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Pro100x3mal/go-musthave-metrics/internal/agent/configs"
+	"github.com/Pro100x3mal/go-musthave-metrics/internal/agent/models"
+	"github.com/shirou/gopsutil/v4/mem"
+)
+
+// MemoryName is the name of the collector of system memory statistics.
+const MemoryName = "memory"
+
+func init() {
+	Register(MemoryName, func(cfg *configs.AgentConfig) (Collector, error) {
+		return New(MemoryName, cfg.CollectorInterval(MemoryName), collectMemory), nil
+	})
+}
+
+func collectMemory(ctx context.Context) ([]*models.Metrics, error) {
+	vm, err := mem.VirtualMemoryWithContext(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get virtual memory statistics: %w", err)
+	}
+
+	return []*models.Metrics{
+		gauge("TotalMemory", float64(vm.Total)),
+		gauge("FreeMemory", float64(vm.Free)),
+	}, nil
+}
@@ -0,0 +1,54 @@
+package collectors
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Pro100x3mal/go-musthave-metrics/internal/agent/configs"
+	"github.com/Pro100x3mal/go-musthave-metrics/internal/agent/models"
+	"github.com/shirou/gopsutil/v4/net"
+)
+
+// NetworkName is the name of the collector of per-interface network statistics.
+const NetworkName = "network"
+
+func init() {
+	Register(NetworkName, func(cfg *configs.AgentConfig) (Collector, error) {
+		filter, err := newPatternFilter(cfg.NetInclude, cfg.NetExclude)
+		if err != nil {
+			return nil, err
+		}
+		return New(NetworkName, cfg.CollectorInterval(NetworkName), func(ctx context.Context) ([]*models.Metrics, error) {
+			return collectNetwork(ctx, filter)
+		}), nil
+	})
+}
+
+// collectNetwork reports the cumulative NetBytesSent_<if>, NetBytesRecv_<if>, NetPacketsSent_<if>,
+// NetPacketsRecv_<if>, NetErrIn_<if>, NetErrOut_<if>, NetDropIn_<if> and NetDropOut_<if> of each
+// selected interface since boot.
+func collectNetwork(ctx context.Context, filter *patternFilter) ([]*models.Metrics, error) {
+	counters, err := net.IOCountersWithContext(ctx, true)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get network IO counters: %w", err)
+	}
+
+	metrics := make([]*models.Metrics, 0, 8*len(counters))
+	for _, c := range counters {
+		if !filter.match(c.Name) {
+			continue
+		}
+		suffix := metricSuffix(c.Name)
+		metrics = append(metrics,
+			gauge("NetBytesSent_"+suffix, float64(c.BytesSent)),
+			gauge("NetBytesRecv_"+suffix, float64(c.BytesRecv)),
+			gauge("NetPacketsSent_"+suffix, float64(c.PacketsSent)),
+			gauge("NetPacketsRecv_"+suffix, float64(c.PacketsRecv)),
+			gauge("NetErrIn_"+suffix, float64(c.Errin)),
+			gauge("NetErrOut_"+suffix, float64(c.Errout)),
+			gauge("NetDropIn_"+suffix, float64(c.Dropin)),
+			gauge("NetDropOut_"+suffix, float64(c.Dropout)),
+		)
+	}
+	return metrics, nil
+}
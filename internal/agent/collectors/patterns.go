@@ -0,0 +1,70 @@
+package collectors
+
+import (
+	"fmt"
+	"hash/fnv"
+	"path"
+	"strings"
+)
+
+// patternFilter selects devices, mountpoints or interfaces by name glob in path.Match syntax.
+// An empty include list matches every name; exclusions take precedence.
+type patternFilter struct {
+	include []string
+	exclude []string
+}
+
+func newPatternFilter(include, exclude []string) (*patternFilter, error) {
+	for _, pattern := range append(append([]string(nil), include...), exclude...) {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("invalid pattern %q: %w", pattern, err)
+		}
+	}
+	return &patternFilter{include: include, exclude: exclude}, nil
+}
+
+func (f *patternFilter) match(name string) bool {
+	if matchAny(f.exclude, name) {
+		return false
+	}
+	return len(f.include) == 0 || matchAny(f.include, name)
+}
+
+func matchAny(patterns []string, name string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// metricSuffix turns a device, mountpoint or interface name into a metric name suffix. A plain name made of
+// letters, digits and underscores, such as "eth0", is used as is. Other names are sanitized, e.g. "/var/lib"
+// into "var_lib" and "/" into "root", and get a short hash of the name appended, because sanitizing may map
+// different names, such as "/var/lib" and "/var-lib" or "/" and "/root", to the same suffix.
+// The suffix depends on the name only, so the metrics of a name keep their IDs whatever other names exist.
+func metricSuffix(name string) string {
+	suffix := sanitizeName(name)
+	if suffix == name {
+		return suffix
+	}
+	h := fnv.New32a()
+	h.Write([]byte(name))
+	return fmt.Sprintf("%s_%08x", suffix, h.Sum32())
+}
+
+// sanitizeName replaces the characters of name not allowed in a metric name suffix with underscores,
+// dropping leading and trailing slashes; "/" becomes "root".
+func sanitizeName(name string) string {
+	name = strings.Trim(name, "/")
+	if name == "" {
+		return "root"
+	}
+	return strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '_' {
+			return r
+		}
+		return '_'
+	}, name)
+}
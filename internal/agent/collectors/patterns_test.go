@@ -0,0 +1,81 @@
+package collectors
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Pro100x3mal/go-musthave-metrics/internal/agent/configs"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPatternFilter(t *testing.T) {
+	f, err := newPatternFilter([]string{"sd*", "nvme*"}, []string{"sda1"})
+	require.NoError(t, err)
+	assert.True(t, f.match("sda"))
+	assert.True(t, f.match("nvme0n1"))
+	assert.False(t, f.match("sda1"))
+	assert.False(t, f.match("loop0"))
+
+	f, err = newPatternFilter(nil, []string{"/snap/*"})
+	require.NoError(t, err)
+	assert.True(t, f.match("/"))
+	assert.False(t, f.match("/snap/core"))
+
+	_, err = newPatternFilter([]string{"[sd"}, nil)
+	assert.Error(t, err)
+
+	_, err = Build(&configs.AgentConfig{Collectors: []string{DiskIOName}, DiskExclude: []string{"[sd"}})
+	assert.Error(t, err)
+}
+
+func TestMetricSuffix(t *testing.T) {
+	assert.Equal(t, "eth0", metricSuffix("eth0"))
+	assert.Equal(t, "veth_a", metricSuffix("veth_a"))
+	assert.Equal(t, "root", metricSuffix("root"))
+
+	assert.Regexp(t, `^root_[0-9a-f]{8}$`, metricSuffix("/"))
+	assert.Regexp(t, `^root_[0-9a-f]{8}$`, metricSuffix("/root"))
+	assert.Regexp(t, `^var_lib_[0-9a-f]{8}$`, metricSuffix("/var/lib"))
+	assert.Regexp(t, `^veth_a_[0-9a-f]{8}$`, metricSuffix("veth-a"))
+	assert.Regexp(t, `^C__[0-9a-f]{8}$`, metricSuffix("C:"))
+
+	for _, pair := range [][2]string{{"/", "/root"}, {"/var/lib", "/var-lib"}, {"veth_a", "veth-a"}} {
+		assert.NotEqual(t, metricSuffix(pair[0]), metricSuffix(pair[1]), "names that sanitize alike stay apart")
+	}
+
+	suffixes := func(names ...string) map[string]string {
+		m := make(map[string]string, len(names))
+		for _, name := range names {
+			m[name] = metricSuffix(name)
+		}
+		return m
+	}
+	without, with := suffixes("/", "/home", "veth_a"), suffixes("/", "/root", "/home", "veth_a", "veth-a")
+	for name, suffix := range without {
+		assert.Equal(t, suffix, with[name], "the suffix of %q does not depend on the other names", name)
+	}
+}
+
+func TestSystemCollectors(t *testing.T) {
+	cs, err := Build(&configs.AgentConfig{
+		PollInterval: time.Second,
+		Collectors:   []string{FilesystemName, DiskIOName, NetworkName, LoadName},
+		NetInclude:   []string{"lo"},
+	})
+	require.NoError(t, err)
+
+	for _, c := range cs {
+		metrics, err := c.Collect(context.Background())
+		if err != nil {
+			t.Skipf("%s statistics are not available: %v", c.Name(), err)
+		}
+		for _, m := range metrics {
+			require.NotNil(t, m.Value, m.ID)
+			if c.Name() == NetworkName {
+				assert.Regexp(t, `_lo$`, m.ID)
+			}
+		}
+	}
+}
@@ -0,0 +1,33 @@
+package collectors
+
+import (
+	"context"
+	"math/rand"
+
+	"github.com/Pro100x3mal/go-musthave-metrics/internal/agent/configs"
+	"github.com/Pro100x3mal/go-musthave-metrics/internal/agent/models"
+)
+
+const (
+	// RandomName is the name of the collector of the RandomValue gauge.
+	RandomName = "random"
+	// PollCountName is the name of the collector of the PollCount counter.
+	PollCountName = "pollcount"
+
+	// PollCountMetric counts the polls of the pollcount collector since the last report.
+	PollCountMetric   = "PollCount"
+	randomValueMetric = "RandomValue"
+)
+
+func init() {
+	Register(RandomName, func(cfg *configs.AgentConfig) (Collector, error) {
+		return New(RandomName, cfg.CollectorInterval(RandomName), func(context.Context) ([]*models.Metrics, error) {
+			return []*models.Metrics{gauge(randomValueMetric, rand.Float64())}, nil
+		}), nil
+	})
+	Register(PollCountName, func(cfg *configs.AgentConfig) (Collector, error) {
+		return New(PollCountName, cfg.CollectorInterval(PollCountName), func(context.Context) ([]*models.Metrics, error) {
+			return []*models.Metrics{counter(PollCountMetric, 1)}, nil
+		}), nil
+	})
+}
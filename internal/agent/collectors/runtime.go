@@ -0,0 +1,59 @@
+package collectors
+
+import (
+	"context"
+	"runtime"
+
+	"github.com/Pro100x3mal/go-musthave-metrics/internal/agent/configs"
+	"github.com/Pro100x3mal/go-musthave-metrics/internal/agent/models"
+)
+
+// RuntimeName is the name of the collector of Go runtime memory statistics.
+const RuntimeName = "runtime"
+
+var runtimeMetrics = map[string]func(m *runtime.MemStats) float64{
+	"Alloc":         func(m *runtime.MemStats) float64 { return float64(m.Alloc) },
+	"BuckHashSys":   func(m *runtime.MemStats) float64 { return float64(m.BuckHashSys) },
+	"Frees":         func(m *runtime.MemStats) float64 { return float64(m.Frees) },
+	"GCCPUFraction": func(m *runtime.MemStats) float64 { return m.GCCPUFraction },
+	"GCSys":         func(m *runtime.MemStats) float64 { return float64(m.GCSys) },
+	"HeapAlloc":     func(m *runtime.MemStats) float64 { return float64(m.HeapAlloc) },
+	"HeapIdle":      func(m *runtime.MemStats) float64 { return float64(m.HeapIdle) },
+	"HeapInuse":     func(m *runtime.MemStats) float64 { return float64(m.HeapInuse) },
+	"HeapObjects":   func(m *runtime.MemStats) float64 { return float64(m.HeapObjects) },
+	"HeapReleased":  func(m *runtime.MemStats) float64 { return float64(m.HeapReleased) },
+	"HeapSys":       func(m *runtime.MemStats) float64 { return float64(m.HeapSys) },
+	"LastGC":        func(m *runtime.MemStats) float64 { return float64(m.LastGC) },
+	"Lookups":       func(m *runtime.MemStats) float64 { return float64(m.Lookups) },
+	"MCacheInuse":   func(m *runtime.MemStats) float64 { return float64(m.MCacheInuse) },
+	"MCacheSys":     func(m *runtime.MemStats) float64 { return float64(m.MCacheSys) },
+	"MSpanInuse":    func(m *runtime.MemStats) float64 { return float64(m.MSpanInuse) },
+	"MSpanSys":      func(m *runtime.MemStats) float64 { return float64(m.MSpanSys) },
+	"Mallocs":       func(m *runtime.MemStats) float64 { return float64(m.Mallocs) },
+	"NextGC":        func(m *runtime.MemStats) float64 { return float64(m.NextGC) },
+	"NumForcedGC":   func(m *runtime.MemStats) float64 { return float64(m.NumForcedGC) },
+	"NumGC":         func(m *runtime.MemStats) float64 { return float64(m.NumGC) },
+	"OtherSys":      func(m *runtime.MemStats) float64 { return float64(m.OtherSys) },
+	"PauseTotalNs":  func(m *runtime.MemStats) float64 { return float64(m.PauseTotalNs) },
+	"StackInuse":    func(m *runtime.MemStats) float64 { return float64(m.StackInuse) },
+	"StackSys":      func(m *runtime.MemStats) float64 { return float64(m.StackSys) },
+	"Sys":           func(m *runtime.MemStats) float64 { return float64(m.Sys) },
+	"TotalAlloc":    func(m *runtime.MemStats) float64 { return float64(m.TotalAlloc) },
+}
+
+func init() {
+	Register(RuntimeName, func(cfg *configs.AgentConfig) (Collector, error) {
+		return New(RuntimeName, cfg.CollectorInterval(RuntimeName), collectRuntime), nil
+	})
+}
+
+func collectRuntime(_ context.Context) ([]*models.Metrics, error) {
+	var stats runtime.MemStats
+	runtime.ReadMemStats(&stats)
+
+	metrics := make([]*models.Metrics, 0, len(runtimeMetrics))
+	for name, fn := range runtimeMetrics {
+		metrics = append(metrics, gauge(name, fn(&stats)))
+	}
+	return metrics, nil
+}
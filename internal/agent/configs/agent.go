@@ -5,8 +5,12 @@ import (
 	"flag"
 	"fmt"
 	"os"
+	"path"
 	"strconv"
+	"strings"
 	"time"
+
+	"github.com/Pro100x3mal/go-musthave-metrics/internal/agent/models"
 )
 
 type AgentConfig struct {
@@ -17,16 +21,80 @@ type AgentConfig struct {
 	Key            string
 	RateLimit      int
 	PublicKeyPath  string
+	Labels         map[string]string
+	GRPCAddr       string
+	// Collectors lists the collectors to run; all registered collectors run if it is empty.
+	Collectors []string
+	// DisabledCollectors lists collectors not to run.
+	DisabledCollectors []string
+	// CollectorIntervals overrides PollInterval for individual collectors.
+	CollectorIntervals map[string]time.Duration
+	// FilesystemInclude and FilesystemExclude select filesystems by mountpoint glob; exclusions win.
+	FilesystemInclude []string
+	FilesystemExclude []string
+	// DiskInclude and DiskExclude select block devices by name glob, e.g. sd* or nvme0n1.
+	DiskInclude []string
+	DiskExclude []string
+	// NetInclude and NetExclude select network interfaces by name glob, e.g. eth* or lo.
+	NetInclude []string
+	NetExclude []string
+	// SpoolDir is the directory batches that could not be delivered are kept in; spooling is disabled if it is empty.
+	SpoolDir string
+	// SpoolMaxSize is the size limit of the spool in bytes.
+	SpoolMaxSize int64
+	// SpoolMaxAge is how long spooled batches are kept; it may not exceed the server's idempotency key TTL of 10 minutes.
+	SpoolMaxAge time.Duration
+	// GaugeAggregation selects how gauges are aggregated over a report window; the first matching rule applies
+	// and gauges matching none report their last value.
+	GaugeAggregation []models.AggregationRule
+	// ChangeOnly reports only the gauges that changed by more than DeadbandAbs and DeadbandRel since their
+	// last report, and all metrics every FullRefreshEvery reports.
+	ChangeOnly       bool
+	DeadbandAbs      float64
+	DeadbandRel      float64
+	FullRefreshEvery int
+	// Servers lists the HTTP servers to report to, ServerAddr only if it is empty.
+	Servers []string
+	// ServerStrategy is StrategyFailover or StrategyFanout.
+	ServerStrategy string
 }
 
+const (
+	// StrategyFailover reports to the first healthy server.
+	StrategyFailover = "failover"
+	// StrategyFanout reports to every server.
+	StrategyFanout = "fanout"
+)
+
 type JSONAgentConfig struct {
-	PollInterval   string `json:"poll_interval"`
-	ReportInterval string `json:"report_interval"`
-	ServerAddr     string `json:"address"`
-	LogLevel       string `json:"log_level"`
-	Key            string `json:"signing_key"`
-	RateLimit      *int   `json:"rate_limit"`
-	PublicKeyPath  string `json:"crypto_key"`
+	PollInterval       string            `json:"poll_interval"`
+	ReportInterval     string            `json:"report_interval"`
+	ServerAddr         string            `json:"address"`
+	LogLevel           string            `json:"log_level"`
+	Key                string            `json:"signing_key"`
+	RateLimit          *int              `json:"rate_limit"`
+	PublicKeyPath      string            `json:"crypto_key"`
+	Labels             map[string]string `json:"labels"`
+	GRPCAddr           string            `json:"grpc_address"`
+	Collectors         []string          `json:"collectors"`
+	DisabledCollectors []string          `json:"disabled_collectors"`
+	CollectorIntervals map[string]string `json:"collector_intervals"`
+	FilesystemInclude  []string          `json:"fs_include"`
+	FilesystemExclude  []string          `json:"fs_exclude"`
+	DiskInclude        []string          `json:"disk_include"`
+	DiskExclude        []string          `json:"disk_exclude"`
+	NetInclude         []string          `json:"net_include"`
+	NetExclude         []string          `json:"net_exclude"`
+	SpoolDir           string            `json:"spool_dir"`
+	SpoolMaxSize       *int              `json:"spool_max_size"`
+	SpoolMaxAge        string            `json:"spool_max_age"`
+	GaugeAggregation   []string          `json:"gauge_aggregation"`
+	ChangeOnly         *bool             `json:"change_only"`
+	DeadbandAbs        *float64          `json:"deadband_abs"`
+	DeadbandRel        *float64          `json:"deadband_rel"`
+	FullRefreshEvery   *int              `json:"full_refresh_every"`
+	Servers            []string          `json:"servers"`
+	ServerStrategy     string            `json:"server_strategy"`
 }
 
 const (
@@ -35,6 +103,14 @@ const (
 	defaultRateLimit  = 5
 	defaultPollSec    = 2
 	defaultReportSec  = 10
+	// defaultSpoolMaxSizeMB bounds the disk space taken by the spool.
+	defaultSpoolMaxSizeMB = 64
+	// maxSpoolMaxAge is the server's idempotency key TTL. A spooled batch must be replayed while the server
+	// still remembers its key, or a batch the server applied before the connection failed is applied twice.
+	maxSpoolMaxAge     = 10 * time.Minute
+	defaultSpoolMaxAge = maxSpoolMaxAge
+	// defaultFullRefreshEvery keeps the server at most about 100 seconds stale with the default report interval.
+	defaultFullRefreshEvery = 10
 )
 
 func GetConfig() (*AgentConfig, error) {
@@ -47,17 +123,42 @@ func GetConfig() (*AgentConfig, error) {
 	cfg.ServerAddr = defaultServerAddr
 	cfg.LogLevel = defaultLogLevel
 	cfg.RateLimit = defaultRateLimit
+	spoolMaxSizeMB := defaultSpoolMaxSizeMB
+	cfg.SpoolMaxAge = defaultSpoolMaxAge
+	cfg.FullRefreshEvery = defaultFullRefreshEvery
+	cfg.ServerStrategy = StrategyFailover
 	pollSec = defaultPollSec
 	reportSec = defaultReportSec
 
 	var (
-		flagPollInterval   int
-		flagReportInterval int
-		flagServerAddr     string
-		flagLogLevel       string
-		flagKey            string
-		flagRateLimit      int
-		flagPublicKeyPath  string
+		flagPollInterval      int
+		flagReportInterval    int
+		flagServerAddr        string
+		flagLogLevel          string
+		flagKey               string
+		flagRateLimit         int
+		flagPublicKeyPath     string
+		flagLabels            string
+		flagGRPCAddr          string
+		flagCollectors        string
+		flagDisabled          string
+		flagIntervals         string
+		flagFilesystemInclude string
+		flagFilesystemExclude string
+		flagDiskInclude       string
+		flagDiskExclude       string
+		flagNetInclude        string
+		flagNetExclude        string
+		flagSpoolDir          string
+		flagSpoolMaxSize      int
+		flagSpoolMaxAge       time.Duration
+		flagGaugeAggregation  string
+		flagChangeOnly        bool
+		flagDeadbandAbs       float64
+		flagDeadbandRel       float64
+		flagFullRefreshEvery  int
+		flagServers           string
+		flagServerStrategy    string
 	)
 
 	flag.StringVar(&flagServerAddr, "a", "", "address of HTTP server")
@@ -67,6 +168,27 @@ func GetConfig() (*AgentConfig, error) {
 	flag.StringVar(&flagKey, "k", "", "signing key")
 	flag.IntVar(&flagRateLimit, "l", -1, "report rate limit")
 	flag.StringVar(&flagPublicKeyPath, "crypto-key", "", "path to public key file")
+	flag.StringVar(&flagLabels, "labels", "", "labels attached to every metric as name=value pairs separated by commas")
+	flag.StringVar(&flagGRPCAddr, "grpc-addr", "", "address of gRPC server; metrics are sent over gRPC instead of HTTP if set")
+	flag.StringVar(&flagCollectors, "collectors", "", "comma-separated collectors to run (all if empty)")
+	flag.StringVar(&flagDisabled, "disable-collectors", "", "comma-separated collectors not to run")
+	flag.StringVar(&flagIntervals, "collector-intervals", "", "per-collector poll intervals as name=duration pairs separated by commas")
+	flag.StringVar(&flagFilesystemInclude, "fs-include", "", "comma-separated mountpoint globs of the filesystems to report (all if empty)")
+	flag.StringVar(&flagFilesystemExclude, "fs-exclude", "", "comma-separated mountpoint globs of the filesystems not to report")
+	flag.StringVar(&flagDiskInclude, "disk-include", "", "comma-separated device globs of the disks to report IO of (all if empty)")
+	flag.StringVar(&flagDiskExclude, "disk-exclude", "", "comma-separated device globs of the disks not to report IO of")
+	flag.StringVar(&flagNetInclude, "net-include", "", "comma-separated globs of the network interfaces to report (all if empty)")
+	flag.StringVar(&flagNetExclude, "net-exclude", "", "comma-separated globs of the network interfaces not to report")
+	flag.StringVar(&flagSpoolDir, "spool-dir", "", "directory to keep undelivered metrics in until the server is reachable (disabled if empty)")
+	flag.IntVar(&flagSpoolMaxSize, "spool-max-size", -1, "maximum size of the spool in megabytes")
+	flag.DurationVar(&flagSpoolMaxAge, "spool-max-age", -1, "maximum age of spooled metrics, at most the server's idempotency key TTL of 10m")
+	flag.StringVar(&flagGaugeAggregation, "gauge-aggregation", "", "gauge aggregation over a report window as pattern=last|min|max|avg|all rules separated by commas, first match wins")
+	flag.BoolVar(&flagChangeOnly, "change-only", false, "report only gauges that changed beyond the deadbands, with periodic full refreshes")
+	flag.Float64Var(&flagDeadbandAbs, "deadband-abs", -1, "absolute change of a gauge not reported in change-only mode")
+	flag.Float64Var(&flagDeadbandRel, "deadband-rel", -1, "relative change of a gauge not reported in change-only mode, e.g. 0.01 for 1%")
+	flag.IntVar(&flagFullRefreshEvery, "full-refresh-every", -1, "report all metrics every N reports in change-only mode (0 disables)")
+	flag.StringVar(&flagServers, "servers", "", "comma-separated addresses of HTTP servers to report to instead of -a")
+	flag.StringVar(&flagServerStrategy, "server-strategy", "", "how to report to several servers: failover or fanout")
 	flag.StringVar(&configFilePath, "config", "", "path to JSON config file")
 	flag.StringVar(&configFilePath, "c", "", "path to JSON config file")
 	flag.Parse()
@@ -78,7 +200,7 @@ func GetConfig() (*AgentConfig, error) {
 	}
 
 	if configFilePath != "" {
-		if err := loadJSONConfig(configFilePath, &cfg, &pollSec, &reportSec); err != nil {
+		if err := loadJSONConfig(configFilePath, &cfg, &pollSec, &reportSec, &spoolMaxSizeMB); err != nil {
 			return nil, fmt.Errorf("failed to load JSON config: %w", err)
 		}
 	}
@@ -104,6 +226,81 @@ func GetConfig() (*AgentConfig, error) {
 	if flagPublicKeyPath != "" {
 		cfg.PublicKeyPath = flagPublicKeyPath
 	}
+	if flagLabels != "" {
+		labels, err := parseLabels(flagLabels)
+		if err != nil {
+			return nil, err
+		}
+		cfg.Labels = labels
+	}
+	if flagGRPCAddr != "" {
+		cfg.GRPCAddr = flagGRPCAddr
+	}
+	if flagCollectors != "" {
+		cfg.Collectors = parseList(flagCollectors)
+	}
+	if flagDisabled != "" {
+		cfg.DisabledCollectors = parseList(flagDisabled)
+	}
+	if flagIntervals != "" {
+		intervals, err := parseIntervals(flagIntervals)
+		if err != nil {
+			return nil, err
+		}
+		cfg.CollectorIntervals = intervals
+	}
+	if flagFilesystemInclude != "" {
+		cfg.FilesystemInclude = parseList(flagFilesystemInclude)
+	}
+	if flagFilesystemExclude != "" {
+		cfg.FilesystemExclude = parseList(flagFilesystemExclude)
+	}
+	if flagDiskInclude != "" {
+		cfg.DiskInclude = parseList(flagDiskInclude)
+	}
+	if flagDiskExclude != "" {
+		cfg.DiskExclude = parseList(flagDiskExclude)
+	}
+	if flagNetInclude != "" {
+		cfg.NetInclude = parseList(flagNetInclude)
+	}
+	if flagNetExclude != "" {
+		cfg.NetExclude = parseList(flagNetExclude)
+	}
+	if flagSpoolDir != "" {
+		cfg.SpoolDir = flagSpoolDir
+	}
+	if flagSpoolMaxSize >= 0 {
+		spoolMaxSizeMB = flagSpoolMaxSize
+	}
+	if flagSpoolMaxAge >= 0 {
+		cfg.SpoolMaxAge = flagSpoolMaxAge
+	}
+	if flagServers != "" {
+		cfg.Servers = parseList(flagServers)
+	}
+	if flagServerStrategy != "" {
+		cfg.ServerStrategy = flagServerStrategy
+	}
+	if flagChangeOnly {
+		cfg.ChangeOnly = true
+	}
+	if flagDeadbandAbs >= 0 {
+		cfg.DeadbandAbs = flagDeadbandAbs
+	}
+	if flagDeadbandRel >= 0 {
+		cfg.DeadbandRel = flagDeadbandRel
+	}
+	if flagFullRefreshEvery >= 0 {
+		cfg.FullRefreshEvery = flagFullRefreshEvery
+	}
+	if flagGaugeAggregation != "" {
+		rules, err := parseAggregationRules(parseList(flagGaugeAggregation))
+		if err != nil {
+			return nil, err
+		}
+		cfg.GaugeAggregation = rules
+	}
 
 	if envServerAddr, ok := os.LookupEnv("ADDRESS"); ok && envServerAddr != "" {
 		cfg.ServerAddr = envServerAddr
@@ -145,13 +342,146 @@ func GetConfig() (*AgentConfig, error) {
 		cfg.PublicKeyPath = envPublicKeyPath
 	}
 
+	if envLabels, ok := os.LookupEnv("LABELS"); ok && envLabels != "" {
+		labels, err := parseLabels(envLabels)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse LABELS value %q: %w", envLabels, err)
+		}
+		cfg.Labels = labels
+	}
+
+	if envGRPCAddr, ok := os.LookupEnv("GRPC_ADDRESS"); ok && envGRPCAddr != "" {
+		cfg.GRPCAddr = envGRPCAddr
+	}
+
+	if envCollectors, ok := os.LookupEnv("COLLECTORS"); ok && envCollectors != "" {
+		cfg.Collectors = parseList(envCollectors)
+	}
+
+	if envDisabled, ok := os.LookupEnv("DISABLED_COLLECTORS"); ok && envDisabled != "" {
+		cfg.DisabledCollectors = parseList(envDisabled)
+	}
+
+	if envIntervals, ok := os.LookupEnv("COLLECTOR_INTERVALS"); ok && envIntervals != "" {
+		intervals, err := parseIntervals(envIntervals)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse COLLECTOR_INTERVALS value %q: %w", envIntervals, err)
+		}
+		cfg.CollectorIntervals = intervals
+	}
+
+	if envFilesystemInclude, ok := os.LookupEnv("FS_INCLUDE"); ok && envFilesystemInclude != "" {
+		cfg.FilesystemInclude = parseList(envFilesystemInclude)
+	}
+
+	if envFilesystemExclude, ok := os.LookupEnv("FS_EXCLUDE"); ok && envFilesystemExclude != "" {
+		cfg.FilesystemExclude = parseList(envFilesystemExclude)
+	}
+
+	if envDiskInclude, ok := os.LookupEnv("DISK_INCLUDE"); ok && envDiskInclude != "" {
+		cfg.DiskInclude = parseList(envDiskInclude)
+	}
+
+	if envDiskExclude, ok := os.LookupEnv("DISK_EXCLUDE"); ok && envDiskExclude != "" {
+		cfg.DiskExclude = parseList(envDiskExclude)
+	}
+
+	if envNetInclude, ok := os.LookupEnv("NET_INCLUDE"); ok && envNetInclude != "" {
+		cfg.NetInclude = parseList(envNetInclude)
+	}
+
+	if envNetExclude, ok := os.LookupEnv("NET_EXCLUDE"); ok && envNetExclude != "" {
+		cfg.NetExclude = parseList(envNetExclude)
+	}
+
+	if envSpoolDir, ok := os.LookupEnv("SPOOL_DIR"); ok && envSpoolDir != "" {
+		cfg.SpoolDir = envSpoolDir
+	}
+
+	if envSpoolMaxSize, ok := os.LookupEnv("SPOOL_MAX_SIZE"); ok && envSpoolMaxSize != "" {
+		size, err := strconv.Atoi(envSpoolMaxSize)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse SPOOL_MAX_SIZE value %q to integer: %w", envSpoolMaxSize, err)
+		}
+		spoolMaxSizeMB = size
+	}
+
+	if envSpoolMaxAge, ok := os.LookupEnv("SPOOL_MAX_AGE"); ok && envSpoolMaxAge != "" {
+		duration, err := time.ParseDuration(envSpoolMaxAge)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse SPOOL_MAX_AGE value %q: %w", envSpoolMaxAge, err)
+		}
+		cfg.SpoolMaxAge = duration
+	}
+
+	if envGaugeAggregation, ok := os.LookupEnv("GAUGE_AGGREGATION"); ok && envGaugeAggregation != "" {
+		rules, err := parseAggregationRules(parseList(envGaugeAggregation))
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse GAUGE_AGGREGATION value %q: %w", envGaugeAggregation, err)
+		}
+		cfg.GaugeAggregation = rules
+	}
+
+	if envChangeOnly, ok := os.LookupEnv("CHANGE_ONLY"); ok && envChangeOnly != "" {
+		changeOnly, err := strconv.ParseBool(envChangeOnly)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse CHANGE_ONLY value %q to bool: %w", envChangeOnly, err)
+		}
+		cfg.ChangeOnly = changeOnly
+	}
+
+	if envDeadbandAbs, ok := os.LookupEnv("DEADBAND_ABS"); ok && envDeadbandAbs != "" {
+		deadband, err := strconv.ParseFloat(envDeadbandAbs, 64)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse DEADBAND_ABS value %q to float: %w", envDeadbandAbs, err)
+		}
+		cfg.DeadbandAbs = deadband
+	}
+
+	if envDeadbandRel, ok := os.LookupEnv("DEADBAND_REL"); ok && envDeadbandRel != "" {
+		deadband, err := strconv.ParseFloat(envDeadbandRel, 64)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse DEADBAND_REL value %q to float: %w", envDeadbandRel, err)
+		}
+		cfg.DeadbandRel = deadband
+	}
+
+	if envFullRefreshEvery, ok := os.LookupEnv("FULL_REFRESH_EVERY"); ok && envFullRefreshEvery != "" {
+		every, err := strconv.Atoi(envFullRefreshEvery)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse FULL_REFRESH_EVERY value %q to integer: %w", envFullRefreshEvery, err)
+		}
+		cfg.FullRefreshEvery = every
+	}
+
+	if envServers, ok := os.LookupEnv("SERVERS"); ok && envServers != "" {
+		cfg.Servers = parseList(envServers)
+	}
+
+	if envServerStrategy, ok := os.LookupEnv("SERVER_STRATEGY"); ok && envServerStrategy != "" {
+		cfg.ServerStrategy = envServerStrategy
+	}
+
+	if cfg.ServerStrategy != StrategyFailover && cfg.ServerStrategy != StrategyFanout {
+		return nil, fmt.Errorf("unknown server strategy %q: expected %s or %s", cfg.ServerStrategy, StrategyFailover, StrategyFanout)
+	}
+
+	if cfg.DeadbandAbs < 0 || cfg.DeadbandRel < 0 || cfg.FullRefreshEvery < 0 {
+		return nil, fmt.Errorf("deadbands and full refresh interval must not be negative")
+	}
+
+	if cfg.SpoolMaxAge <= 0 || cfg.SpoolMaxAge > maxSpoolMaxAge {
+		return nil, fmt.Errorf("spool max age %s must be greater than 0 and at most %s, the server's idempotency key TTL", cfg.SpoolMaxAge, maxSpoolMaxAge)
+	}
+
+	cfg.SpoolMaxSize = int64(spoolMaxSizeMB) << 20
 	cfg.PollInterval = time.Duration(pollSec) * time.Second
 	cfg.ReportInterval = time.Duration(reportSec) * time.Second
 
 	return &cfg, nil
 }
 
-func loadJSONConfig(path string, cfg *AgentConfig, pollSec *int, reportSec *int) error {
+func loadJSONConfig(path string, cfg *AgentConfig, pollSec *int, reportSec *int, spoolMaxSizeMB *int) error {
 	data, err := os.ReadFile(path)
 	if err != nil {
 		return fmt.Errorf("failed to read config file: %w", err)
@@ -193,6 +523,186 @@ func loadJSONConfig(path string, cfg *AgentConfig, pollSec *int, reportSec *int)
 	if jsonCfg.RateLimit != nil {
 		cfg.RateLimit = *jsonCfg.RateLimit
 	}
+	if len(jsonCfg.Labels) > 0 {
+		for name := range jsonCfg.Labels {
+			if err := validateLabelName(name); err != nil {
+				return fmt.Errorf("failed to parse labels: %w", err)
+			}
+		}
+		cfg.Labels = jsonCfg.Labels
+	}
+	if jsonCfg.GRPCAddr != "" {
+		cfg.GRPCAddr = jsonCfg.GRPCAddr
+	}
+	if len(jsonCfg.Collectors) > 0 {
+		cfg.Collectors = jsonCfg.Collectors
+	}
+	if len(jsonCfg.DisabledCollectors) > 0 {
+		cfg.DisabledCollectors = jsonCfg.DisabledCollectors
+	}
+	if len(jsonCfg.CollectorIntervals) > 0 {
+		cfg.CollectorIntervals = make(map[string]time.Duration, len(jsonCfg.CollectorIntervals))
+		for name, value := range jsonCfg.CollectorIntervals {
+			duration, err := time.ParseDuration(value)
+			if err != nil {
+				return fmt.Errorf("failed to parse collector_intervals.%s: %w", name, err)
+			}
+			cfg.CollectorIntervals[name] = duration
+		}
+	}
+	if len(jsonCfg.FilesystemInclude) > 0 {
+		cfg.FilesystemInclude = jsonCfg.FilesystemInclude
+	}
+	if len(jsonCfg.FilesystemExclude) > 0 {
+		cfg.FilesystemExclude = jsonCfg.FilesystemExclude
+	}
+	if len(jsonCfg.DiskInclude) > 0 {
+		cfg.DiskInclude = jsonCfg.DiskInclude
+	}
+	if len(jsonCfg.DiskExclude) > 0 {
+		cfg.DiskExclude = jsonCfg.DiskExclude
+	}
+	if len(jsonCfg.NetInclude) > 0 {
+		cfg.NetInclude = jsonCfg.NetInclude
+	}
+	if len(jsonCfg.NetExclude) > 0 {
+		cfg.NetExclude = jsonCfg.NetExclude
+	}
+	if jsonCfg.SpoolDir != "" {
+		cfg.SpoolDir = jsonCfg.SpoolDir
+	}
+	if jsonCfg.SpoolMaxSize != nil {
+		*spoolMaxSizeMB = *jsonCfg.SpoolMaxSize
+	}
+	if jsonCfg.SpoolMaxAge != "" {
+		duration, err := time.ParseDuration(jsonCfg.SpoolMaxAge)
+		if err != nil {
+			return fmt.Errorf("failed to parse spool_max_age: %w", err)
+		}
+		cfg.SpoolMaxAge = duration
+	}
+	if len(jsonCfg.Servers) > 0 {
+		cfg.Servers = jsonCfg.Servers
+	}
+	if jsonCfg.ServerStrategy != "" {
+		cfg.ServerStrategy = jsonCfg.ServerStrategy
+	}
+	if jsonCfg.ChangeOnly != nil {
+		cfg.ChangeOnly = *jsonCfg.ChangeOnly
+	}
+	if jsonCfg.DeadbandAbs != nil {
+		cfg.DeadbandAbs = *jsonCfg.DeadbandAbs
+	}
+	if jsonCfg.DeadbandRel != nil {
+		cfg.DeadbandRel = *jsonCfg.DeadbandRel
+	}
+	if jsonCfg.FullRefreshEvery != nil {
+		cfg.FullRefreshEvery = *jsonCfg.FullRefreshEvery
+	}
+	if len(jsonCfg.GaugeAggregation) > 0 {
+		rules, err := parseAggregationRules(jsonCfg.GaugeAggregation)
+		if err != nil {
+			return fmt.Errorf("failed to parse gauge_aggregation: %w", err)
+		}
+		cfg.GaugeAggregation = rules
+	}
+
+	return nil
+}
 
+func parseLabels(s string) (map[string]string, error) {
+	labels := make(map[string]string)
+	for _, pair := range strings.Split(s, ",") {
+		name, value, ok := strings.Cut(pair, "=")
+		name = strings.TrimSpace(name)
+		if !ok || name == "" {
+			return nil, fmt.Errorf("invalid label %q: expected name=value", pair)
+		}
+		if err := validateLabelName(name); err != nil {
+			return nil, err
+		}
+		labels[name] = value
+	}
+	return labels, nil
+}
+
+// validateLabelName applies the server's rule for label names: a letter or underscore followed by letters,
+// digits and underscores. The server rejects every metric carrying an invalid label name.
+func validateLabelName(name string) error {
+	valid := name != ""
+	for i, c := range name {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c == '_':
+		case c >= '0' && c <= '9' && i > 0:
+		default:
+			valid = false
+		}
+	}
+	if !valid {
+		return fmt.Errorf("invalid label name %q: must match [a-zA-Z_][a-zA-Z0-9_]*", name)
+	}
 	return nil
 }
+
+func parseList(s string) []string {
+	var items []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+func parseIntervals(s string) (map[string]time.Duration, error) {
+	intervals := make(map[string]time.Duration)
+	for _, pair := range strings.Split(s, ",") {
+		name, value, ok := strings.Cut(pair, "=")
+		name = strings.TrimSpace(name)
+		if !ok || name == "" {
+			return nil, fmt.Errorf("invalid collector interval %q: expected name=duration", pair)
+		}
+		duration, err := time.ParseDuration(strings.TrimSpace(value))
+		if err != nil || duration <= 0 {
+			return nil, fmt.Errorf("invalid collector interval %q: expected a positive duration", pair)
+		}
+		intervals[name] = duration
+	}
+	return intervals, nil
+}
+
+func parseAggregationRules(specs []string) ([]models.AggregationRule, error) {
+	rules := make([]models.AggregationRule, 0, len(specs))
+	for _, spec := range specs {
+		pattern, mode, ok := strings.Cut(spec, "=")
+		pattern = strings.TrimSpace(pattern)
+		aggregation := models.Aggregation(strings.TrimSpace(mode))
+		if !ok || pattern == "" {
+			return nil, fmt.Errorf("invalid gauge aggregation %q: expected pattern=aggregation", spec)
+		}
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("invalid gauge aggregation pattern %q: %w", pattern, err)
+		}
+		if !aggregation.Valid() {
+			return nil, fmt.Errorf("invalid gauge aggregation %q: expected last, min, max, avg or all", spec)
+		}
+		rules = append(rules, models.AggregationRule{Pattern: pattern, Aggregation: aggregation})
+	}
+	return rules, nil
+}
+
+// ServerAddrs returns the addresses of the HTTP servers to report to.
+func (cfg *AgentConfig) ServerAddrs() []string {
+	if len(cfg.Servers) > 0 {
+		return cfg.Servers
+	}
+	return []string{cfg.ServerAddr}
+}
+
+// CollectorInterval returns the poll interval of the named collector.
+func (cfg *AgentConfig) CollectorInterval(name string) time.Duration {
+	if d, ok := cfg.CollectorIntervals[name]; ok && d > 0 {
+		return d
+	}
+	return cfg.PollInterval
+}
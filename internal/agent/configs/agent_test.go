@@ -0,0 +1,51 @@
+package configs
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// getConfig runs GetConfig with the command line args on a fresh flag set.
+func getConfig(t *testing.T, args ...string) (*AgentConfig, error) {
+	t.Helper()
+
+	oldArgs, oldFlags := os.Args, flag.CommandLine
+	t.Cleanup(func() {
+		os.Args, flag.CommandLine = oldArgs, oldFlags
+	})
+	os.Args = append([]string{"agent"}, args...)
+	flag.CommandLine = flag.NewFlagSet("agent", flag.ContinueOnError)
+
+	return GetConfig()
+}
+
+func TestGetConfig_Labels(t *testing.T) {
+	cfg, err := getConfig(t, "-labels", "host=web1, region_2=eu")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"host": "web1", "region_2": "eu"}, cfg.Labels)
+}
+
+func TestGetConfig_InvalidLabelName(t *testing.T) {
+	t.Run("flag", func(t *testing.T) {
+		_, err := getConfig(t, "-labels", "host-name=web1")
+		assert.ErrorContains(t, err, `invalid label name "host-name"`)
+	})
+
+	t.Run("env", func(t *testing.T) {
+		t.Setenv("LABELS", "region.zone=eu")
+		_, err := getConfig(t)
+		assert.ErrorContains(t, err, `invalid label name "region.zone"`)
+	})
+
+	t.Run("json", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "agent.json")
+		require.NoError(t, os.WriteFile(path, []byte(`{"labels": {"1host": "web1"}}`), 0o600))
+		_, err := getConfig(t, "-config", path)
+		assert.ErrorContains(t, err, `invalid label name "1host"`)
+	})
+}
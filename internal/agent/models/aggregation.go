@@ -0,0 +1,31 @@
+package models
+
+// Aggregation is how the values a gauge takes between two reports are reported.
+type Aggregation string
+
+const (
+	// AggregateLast reports the last polled value.
+	AggregateLast Aggregation = "last"
+	// AggregateMin, AggregateMax and AggregateAvg report the minimum, maximum or mean value under the gauge's ID.
+	AggregateMin Aggregation = "min"
+	AggregateMax Aggregation = "max"
+	AggregateAvg Aggregation = "avg"
+	// AggregateAll reports the last value under the gauge's ID and the others as derived gauges
+	// with the _min, _max and _avg suffixes, e.g. CPUutilization0_max.
+	AggregateAll Aggregation = "all"
+)
+
+// Valid reports whether a is a known aggregation.
+func (a Aggregation) Valid() bool {
+	switch a {
+	case AggregateLast, AggregateMin, AggregateMax, AggregateAvg, AggregateAll:
+		return true
+	}
+	return false
+}
+
+// AggregationRule applies an aggregation to the gauges whose ID matches Pattern, a glob in path.Match syntax.
+type AggregationRule struct {
+	Pattern     string
+	Aggregation Aggregation
+}
@@ -0,0 +1,10 @@
+package models
+
+import "time"
+
+// Batch is one report of metrics, identified by its idempotency key across all delivery attempts.
+type Batch struct {
+	IdempotencyKey string     `json:"idempotency_key"`
+	CreatedAt      time.Time  `json:"created_at"`
+	Metrics        []*Metrics `json:"metrics"`
+}
@@ -6,8 +6,9 @@ const (
 )
 
 type Metrics struct {
-	ID    string   `json:"id"`
-	MType string   `json:"type"`
-	Delta *int64   `json:"delta,omitempty"`
-	Value *float64 `json:"value,omitempty"`
+	ID     string            `json:"id"`
+	MType  string            `json:"type"`
+	Delta  *int64            `json:"delta,omitempty"`
+	Value  *float64          `json:"value,omitempty"`
+	Labels map[string]string `json:"labels,omitempty"`
 }
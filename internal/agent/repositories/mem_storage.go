@@ -3,6 +3,7 @@ package repositories
 import (
 	"errors"
 	"fmt"
+	"path"
 	"sync"
 
 	"github.com/Pro100x3mal/go-musthave-metrics/internal/agent/models"
@@ -10,15 +11,45 @@ import (
 
 type MemStorage struct {
 	mu       *sync.RWMutex
-	gauges   map[string]float64
+	gauges   map[string]*gaugeWindow
 	counters map[string]int64
+	rules    []models.AggregationRule
 }
 
-func NewMemStorage() *MemStorage {
+// gaugeWindow aggregates the values of a gauge polled since the last acknowledged report.
+type gaugeWindow struct {
+	aggregation models.Aggregation
+	last        float64
+	// window holds the values since the last acknowledged report. pending holds those since the last snapshot;
+	// it replaces window once the snapshot is acknowledged, so that values polled during a report are kept.
+	window  gaugeStats
+	pending gaugeStats
+}
+
+type gaugeStats struct {
+	min, max, sum float64
+	count         int
+}
+
+func (s *gaugeStats) add(v float64) {
+	if s.count == 0 {
+		s.min, s.max = v, v
+	} else {
+		s.min = min(s.min, v)
+		s.max = max(s.max, v)
+	}
+	s.sum += v
+	s.count++
+}
+
+// NewMemStorage returns a storage aggregating gauges according to the first matching rule in aggregation.
+// Gauges matching no rule report their last value.
+func NewMemStorage(aggregation []models.AggregationRule) *MemStorage {
 	return &MemStorage{
 		mu:       &sync.RWMutex{},
-		gauges:   make(map[string]float64),
+		gauges:   make(map[string]*gaugeWindow),
 		counters: make(map[string]int64),
+		rules:    aggregation,
 	}
 }
 
@@ -29,7 +60,14 @@ func (m *MemStorage) UpdateMetrics(metric *models.Metrics) error {
 			return errors.New("nil gauge value")
 		}
 		m.mu.Lock()
-		m.gauges[metric.ID] = *metric.Value
+		g, ok := m.gauges[metric.ID]
+		if !ok {
+			g = &gaugeWindow{aggregation: m.aggregationFor(metric.ID)}
+			m.gauges[metric.ID] = g
+		}
+		g.last = *metric.Value
+		g.window.add(*metric.Value)
+		g.pending.add(*metric.Value)
 		m.mu.Unlock()
 	case models.Counter:
 		if metric.Delta == nil {
@@ -45,40 +83,51 @@ func (m *MemStorage) UpdateMetrics(metric *models.Metrics) error {
 	return nil
 }
 
-func (m *MemStorage) ResetMetricValue(metric *models.Metrics) error {
+func (m *MemStorage) aggregationFor(id string) models.Aggregation {
+	for _, rule := range m.rules {
+		if ok, _ := path.Match(rule.Pattern, id); ok {
+			return rule.Aggregation
+		}
+	}
+	return models.AggregateLast
+}
+
+// Drain completes a report acknowledged by the server. It subtracts the deltas of the counters in sent,
+// a snapshot taken by GetAllMetrics, and starts new gauge windows with the values polled after the snapshot.
+// Counter increments made after the snapshot are kept for the next report.
+func (m *MemStorage) Drain(sent []*models.Metrics) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
-	switch metric.MType {
-	case models.Gauge:
-		if _, ok := m.gauges[metric.ID]; !ok {
-			return errors.New("gauge metric not found")
+	for _, metric := range sent {
+		if metric.MType != models.Counter || metric.Delta == nil {
+			continue
 		}
-		m.gauges[metric.ID] = 0
-	case models.Counter:
-		if _, ok := m.counters[metric.ID]; !ok {
-			return errors.New("counter metric not found")
+		// Fully drained counters are removed so that they are not reported with a zero delta.
+		if remaining := m.counters[metric.ID] - *metric.Delta; remaining != 0 {
+			m.counters[metric.ID] = remaining
+		} else {
+			delete(m.counters, metric.ID)
 		}
-		m.counters[metric.ID] = 0
-	default:
-		return fmt.Errorf("unsupported metric type: %s", metric.MType)
 	}
-	return nil
+
+	for _, g := range m.gauges {
+		g.window = g.pending
+	}
 }
 
+// GetAllMetrics returns a snapshot of the metrics to report, with gauges aggregated over their windows.
+// Only one snapshot may be in flight at a time: taking a snapshot restarts the tracking of the values
+// that Drain carries over to the next window.
 func (m *MemStorage) GetAllMetrics() []*models.Metrics {
-	m.mu.RLock()
-	defer m.mu.RUnlock()
+	m.mu.Lock()
+	defer m.mu.Unlock()
 
 	var result []*models.Metrics
 
-	for id, value := range m.gauges {
-		v := value
-		result = append(result, &models.Metrics{
-			ID:    id,
-			MType: models.Gauge,
-			Value: &v,
-		})
+	for id, g := range m.gauges {
+		result = append(result, g.metrics(id)...)
+		g.pending = gaugeStats{}
 	}
 
 	for id, delta := range m.counters {
@@ -92,3 +141,35 @@ func (m *MemStorage) GetAllMetrics() []*models.Metrics {
 
 	return result
 }
+
+// metrics returns the gauges reported for the window. A window without values, when nothing was polled
+// since the last report, reports the last value for every aggregate.
+func (g *gaugeWindow) metrics(id string) []*models.Metrics {
+	minValue, maxValue, avgValue := g.last, g.last, g.last
+	if g.window.count > 0 {
+		minValue, maxValue = g.window.min, g.window.max
+		avgValue = g.window.sum / float64(g.window.count)
+	}
+
+	gauge := func(id string, v float64) *models.Metrics {
+		return &models.Metrics{ID: id, MType: models.Gauge, Value: &v}
+	}
+
+	switch g.aggregation {
+	case models.AggregateMin:
+		return []*models.Metrics{gauge(id, minValue)}
+	case models.AggregateMax:
+		return []*models.Metrics{gauge(id, maxValue)}
+	case models.AggregateAvg:
+		return []*models.Metrics{gauge(id, avgValue)}
+	case models.AggregateAll:
+		return []*models.Metrics{
+			gauge(id, g.last),
+			gauge(id+"_min", minValue),
+			gauge(id+"_max", maxValue),
+			gauge(id+"_avg", avgValue),
+		}
+	default:
+		return []*models.Metrics{gauge(id, g.last)}
+	}
+}
@@ -0,0 +1,78 @@
+package repositories
+
+import (
+	"testing"
+
+	"github.com/Pro100x3mal/go-musthave-metrics/internal/agent/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemStorage_Drain(t *testing.T) {
+	m := NewMemStorage(nil)
+	delta := int64(3)
+	value := 1.5
+	require.NoError(t, m.UpdateMetrics(&models.Metrics{ID: "PollCount", MType: models.Counter, Delta: &delta}))
+	require.NoError(t, m.UpdateMetrics(&models.Metrics{ID: "Errors", MType: models.Counter, Delta: &delta}))
+	require.NoError(t, m.UpdateMetrics(&models.Metrics{ID: "Alloc", MType: models.Gauge, Value: &value}))
+
+	snapshot := m.GetAllMetrics()
+
+	// Increments after the snapshot survive the drain.
+	two := int64(2)
+	require.NoError(t, m.UpdateMetrics(&models.Metrics{ID: "PollCount", MType: models.Counter, Delta: &two}))
+	m.Drain(snapshot)
+
+	got := make(map[string]*models.Metrics)
+	for _, metric := range m.GetAllMetrics() {
+		got[metric.ID] = metric
+	}
+	require.Contains(t, got, "PollCount")
+	assert.Equal(t, int64(2), *got["PollCount"].Delta)
+	assert.NotContains(t, got, "Errors")
+	require.Contains(t, got, "Alloc")
+	assert.Equal(t, 1.5, *got["Alloc"].Value)
+}
+
+func TestMemStorage_GaugeAggregation(t *testing.T) {
+	m := NewMemStorage([]models.AggregationRule{
+		{Pattern: "CPUutilization*", Aggregation: models.AggregateAll},
+		{Pattern: "Heap*", Aggregation: models.AggregateMax},
+	})
+	update := func(id string, v float64) {
+		require.NoError(t, m.UpdateMetrics(&models.Metrics{ID: id, MType: models.Gauge, Value: &v}))
+	}
+	values := func() map[string]float64 {
+		got := make(map[string]float64)
+		for _, metric := range m.GetAllMetrics() {
+			got[metric.ID] = *metric.Value
+		}
+		return got
+	}
+
+	for _, v := range []float64{10, 90, 20} {
+		update("CPUutilization0", v)
+		update("HeapAlloc", v)
+		update("Alloc", v)
+	}
+
+	assert.Equal(t, map[string]float64{
+		"CPUutilization0":     20,
+		"CPUutilization0_min": 10,
+		"CPUutilization0_max": 90,
+		"CPUutilization0_avg": 40,
+		"HeapAlloc":           90,
+		"Alloc":               20,
+	}, values())
+
+	// A failed report keeps the window; a poll during the report belongs to both windows.
+	update("HeapAlloc", 30)
+	assert.Equal(t, 90.0, values()["HeapAlloc"])
+
+	snapshot := m.GetAllMetrics()
+	update("HeapAlloc", 40)
+	m.Drain(snapshot)
+	got := values()
+	assert.Equal(t, 40.0, got["HeapAlloc"])
+	assert.Equal(t, 20.0, got["CPUutilization0_max"], "an empty window reports the last value")
+}
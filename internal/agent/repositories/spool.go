@@ -0,0 +1,216 @@
+package repositories
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Pro100x3mal/go-musthave-metrics/internal/agent/models"
+)
+
+const (
+	spoolFileExt = ".json"
+	spoolTmpExt  = ".tmp"
+)
+
+// SpoolOptions bound the size of a Spool. Zero values disable the respective limit.
+type SpoolOptions struct {
+	// MaxSize is the total size of the spooled batches in bytes; the oldest batches are dropped to stay below it.
+	MaxSize int64
+	// MaxAge is how long a batch is kept from its creation; older batches are dropped instead of being sent.
+	MaxAge time.Duration
+}
+
+// Spool keeps batches that could not be delivered in a directory, one file per batch,
+// so that they survive agent restarts and can be replayed in the order they were reported.
+type Spool struct {
+	mu    sync.Mutex
+	dir   string
+	opts  SpoolOptions
+	files []spoolFile
+	size  int64
+	// nextSeq is the lowest sequence number of the next batch. Sequence numbers are the creation times
+	// of the batches in nanoseconds, raised if needed to keep them increasing, so they order batches and tell their age.
+	nextSeq uint64
+	now     func() time.Time
+}
+
+type spoolFile struct {
+	seq  uint64
+	size int64
+}
+
+// NewSpool opens the spool in dir, creating the directory if needed. Batches left by a previous run are kept;
+// temporary files of writes interrupted by a crash are removed.
+func NewSpool(dir string, opts SpoolOptions) (*Spool, error) {
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return nil, fmt.Errorf("failed to create spool directory: %w", err)
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read spool directory: %w", err)
+	}
+
+	s := &Spool{dir: dir, opts: opts, now: time.Now}
+	for _, e := range entries {
+		if strings.HasSuffix(e.Name(), spoolFileExt+spoolTmpExt) && e.Type().IsRegular() {
+			_ = os.Remove(filepath.Join(dir, e.Name()))
+			continue
+		}
+		seq, ok := parseSpoolName(e.Name())
+		if !ok || !e.Type().IsRegular() {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil {
+			continue
+		}
+		s.files = append(s.files, spoolFile{seq: seq, size: info.Size()})
+		s.size += info.Size()
+	}
+	sort.Slice(s.files, func(i, j int) bool { return s.files[i].seq < s.files[j].seq })
+
+	if n := len(s.files); n > 0 {
+		s.nextSeq = s.files[n-1].seq + 1
+	}
+	return s, nil
+}
+
+func parseSpoolName(name string) (uint64, bool) {
+	base, ok := strings.CutSuffix(name, spoolFileExt)
+	if !ok {
+		return 0, false
+	}
+	seq, err := strconv.ParseUint(base, 10, 64)
+	return seq, err == nil
+}
+
+func (s *Spool) path(seq uint64) string {
+	return filepath.Join(s.dir, fmt.Sprintf("%020d%s", seq, spoolFileExt))
+}
+
+// Len returns the number of spooled batches.
+func (s *Spool) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.files)
+}
+
+// Put appends a batch to the spool and drops the oldest batches that exceed the size or age limits.
+// It returns the number of batches dropped.
+func (s *Spool) Put(batch *models.Batch) (int, error) {
+	data, err := json.Marshal(batch)
+	if err != nil {
+		return 0, fmt.Errorf("failed to encode batch: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.opts.MaxSize > 0 && int64(len(data)) > s.opts.MaxSize {
+		return 0, fmt.Errorf("batch of %d bytes exceeds the spool size limit of %d bytes", len(data), s.opts.MaxSize)
+	}
+
+	// The age of a batch counts from its creation, before the first delivery attempt, which may have reached the server.
+	createdAt := batch.CreatedAt
+	if createdAt.IsZero() {
+		createdAt = s.now()
+	}
+	seq := max(s.nextSeq, uint64(createdAt.UnixNano()))
+	path := s.path(seq)
+	tmp := path + spoolTmpExt
+	if err = os.WriteFile(tmp, data, 0o640); err != nil {
+		return 0, fmt.Errorf("failed to write spool file: %w", err)
+	}
+	if err = os.Rename(tmp, path); err != nil {
+		_ = os.Remove(tmp)
+		return 0, fmt.Errorf("failed to write spool file: %w", err)
+	}
+	s.nextSeq = seq + 1
+	s.files = append(s.files, spoolFile{seq: seq, size: int64(len(data))})
+	s.size += int64(len(data))
+
+	return s.enforceLimits(), nil
+}
+
+// enforceLimits drops the oldest batches while the spool is over its size limit or they are expired.
+// Expiry is judged from the sequence number, which is the time the batch was created. The caller holds s.mu.
+func (s *Spool) enforceLimits() int {
+	var dropped int
+	for len(s.files) > 0 {
+		oldest := s.files[0]
+		overSize := s.opts.MaxSize > 0 && s.size > s.opts.MaxSize
+		if !overSize && !s.expired(oldest.seq) {
+			break
+		}
+		s.removeOldest()
+		dropped++
+	}
+	return dropped
+}
+
+func (s *Spool) expired(seq uint64) bool {
+	return s.opts.MaxAge > 0 && s.now().Sub(time.Unix(0, int64(seq))) > s.opts.MaxAge
+}
+
+// removeOldest deletes the oldest batch. The caller holds s.mu.
+func (s *Spool) removeOldest() {
+	oldest := s.files[0]
+	_ = os.Remove(s.path(oldest.seq))
+	s.files = s.files[1:]
+	s.size -= oldest.size
+}
+
+// Replay sends the spooled batches oldest first and removes each one that was sent. It stops at the first
+// batch send fails for and returns that error, keeping the batch for the next replay. Expired and unreadable
+// batches are dropped; Replay returns the numbers of batches sent and dropped.
+func (s *Spool) Replay(ctx context.Context, send func(ctx context.Context, batch *models.Batch) error) (sent, dropped int, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	dropped = s.enforceLimits()
+	for len(s.files) > 0 {
+		if err = ctx.Err(); err != nil {
+			return sent, dropped, err
+		}
+
+		oldest := s.files[0]
+		batch, readErr := s.read(oldest.seq)
+		if readErr != nil {
+			s.removeOldest()
+			dropped++
+			continue
+		}
+
+		if err = send(ctx, batch); err != nil {
+			return sent, dropped, err
+		}
+		s.removeOldest()
+		sent++
+	}
+	return sent, dropped, nil
+}
+
+func (s *Spool) read(seq uint64) (*models.Batch, error) {
+	data, err := os.ReadFile(s.path(seq))
+	if err != nil {
+		return nil, err
+	}
+	var batch models.Batch
+	if err = json.Unmarshal(data, &batch); err != nil {
+		return nil, err
+	}
+	if len(batch.Metrics) == 0 {
+		return nil, errors.New("empty batch")
+	}
+	return &batch, nil
+}
@@ -0,0 +1,144 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Pro100x3mal/go-musthave-metrics/internal/agent/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testBatch(key string) *models.Batch {
+	v := 1.0
+	return &models.Batch{
+		IdempotencyKey: key,
+		Metrics:        []*models.Metrics{{ID: "Alloc", MType: models.Gauge, Value: &v}},
+	}
+}
+
+func TestSpool_ReplayInOrder(t *testing.T) {
+	dir := t.TempDir()
+	s, err := NewSpool(dir, SpoolOptions{})
+	require.NoError(t, err)
+
+	for _, key := range []string{"a", "b", "c"} {
+		_, err = s.Put(testBatch(key))
+		require.NoError(t, err)
+	}
+
+	// A failed send stops the replay and keeps the failed batch.
+	var keys []string
+	sent, dropped, err := s.Replay(context.Background(), func(_ context.Context, b *models.Batch) error {
+		if b.IdempotencyKey == "b" {
+			return errors.New("server down")
+		}
+		keys = append(keys, b.IdempotencyKey)
+		return nil
+	})
+	require.Error(t, err)
+	assert.Equal(t, 1, sent)
+	assert.Zero(t, dropped)
+	assert.Equal(t, 2, s.Len())
+
+	// The remaining batches survive a restart.
+	s, err = NewSpool(dir, SpoolOptions{})
+	require.NoError(t, err)
+	_, err = s.Put(testBatch("d"))
+	require.NoError(t, err)
+
+	sent, _, err = s.Replay(context.Background(), func(_ context.Context, b *models.Batch) error {
+		keys = append(keys, b.IdempotencyKey)
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 3, sent)
+	assert.Equal(t, []string{"a", "b", "c", "d"}, keys)
+	assert.Zero(t, s.Len())
+
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+}
+
+func TestSpool_Limits(t *testing.T) {
+	s, err := NewSpool(t.TempDir(), SpoolOptions{MaxSize: 300, MaxAge: time.Minute})
+	require.NoError(t, err)
+
+	var dropped int
+	for _, key := range []string{"a", "b", "c", "d"} {
+		n, err := s.Put(testBatch(key))
+		require.NoError(t, err)
+		dropped += n
+	}
+	assert.Positive(t, dropped)
+	assert.LessOrEqual(t, s.size, int64(300))
+
+	var keys []string
+	collect := func(_ context.Context, b *models.Batch) error {
+		keys = append(keys, b.IdempotencyKey)
+		return nil
+	}
+
+	// The oldest batches were dropped to stay within the size limit.
+	remaining := s.Len()
+	_, _, err = s.Replay(context.Background(), collect)
+	require.NoError(t, err)
+	require.Len(t, keys, remaining)
+	assert.Equal(t, "d", keys[len(keys)-1])
+
+	_, err = s.Put(testBatch("e"))
+	require.NoError(t, err)
+	s.now = func() time.Time { return time.Now().Add(2 * time.Minute) }
+	sent, dropped, err := s.Replay(context.Background(), collect)
+	require.NoError(t, err)
+	assert.Zero(t, sent)
+	assert.Equal(t, 1, dropped)
+
+	_, err = s.Put(&models.Batch{Metrics: make([]*models.Metrics, 100)})
+	assert.Error(t, err)
+}
+
+func TestSpool_RemovesInterruptedWrites(t *testing.T) {
+	dir := t.TempDir()
+	s, err := NewSpool(dir, SpoolOptions{})
+	require.NoError(t, err)
+	_, err = s.Put(testBatch("a"))
+	require.NoError(t, err)
+
+	// A crash between writing and renaming a batch leaves its temporary file behind.
+	tmp := filepath.Join(dir, "00000000000000000001.json.tmp")
+	require.NoError(t, os.WriteFile(tmp, []byte(`{"idempotency_key":`), 0o640))
+
+	s, err = NewSpool(dir, SpoolOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, 1, s.Len())
+	assert.NoFileExists(t, tmp)
+}
+
+func TestSpool_AgeFromCreation(t *testing.T) {
+	s, err := NewSpool(t.TempDir(), SpoolOptions{MaxAge: time.Minute})
+	require.NoError(t, err)
+
+	// A batch created before its delivery attempts failed is as old as its first attempt, not its spooling.
+	old := testBatch("old")
+	old.CreatedAt = time.Now().Add(-2 * time.Minute)
+	dropped, err := s.Put(old)
+	require.NoError(t, err)
+	assert.Equal(t, 1, dropped)
+
+	fresh := testBatch("fresh")
+	fresh.CreatedAt = time.Now().Add(-30 * time.Second)
+	_, err = s.Put(fresh)
+	require.NoError(t, err)
+	s.now = func() time.Time { return time.Now().Add(45 * time.Second) }
+
+	sent, dropped, err := s.Replay(context.Background(), func(context.Context, *models.Batch) error { return nil })
+	require.NoError(t, err)
+	assert.Zero(t, sent)
+	assert.Equal(t, 1, dropped)
+}
@@ -4,278 +4,93 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"math/rand"
-	"runtime"
+	"sync"
 	"time"
 
+	"github.com/Pro100x3mal/go-musthave-metrics/internal/agent/collectors"
 	"github.com/Pro100x3mal/go-musthave-metrics/internal/agent/models"
-	"github.com/shirou/gopsutil/v4/cpu"
-	"github.com/shirou/gopsutil/v4/mem"
+	"go.uber.org/zap"
 )
 
 type RepositoryWriter interface {
 	UpdateMetrics(metric *models.Metrics) error
-	ResetMetricValue(metric *models.Metrics) error
 }
 
+// MetricsCollectService polls the collectors and stores their metrics until they are reported.
 type MetricsCollectService struct {
 	writer     RepositoryWriter
-	rntMetrics *runtimeMetricsProvider
-	memMetrics *sysMemMetricsProvider
-	cpuMetrics *sysCPUMetricsProvider
+	collectors []collectors.Collector
+	logger     *zap.Logger
 }
 
-func NewMetricsCollectService(writer RepositoryWriter) *MetricsCollectService {
+func NewMetricsCollectService(writer RepositoryWriter, cs []collectors.Collector, logger *zap.Logger) *MetricsCollectService {
 	return &MetricsCollectService{
 		writer:     writer,
-		rntMetrics: newRuntimeMetricsProvider(),
-		memMetrics: newSysMemMetricsProvider(),
-		cpuMetrics: newSysCPUMetricsProvider(),
+		collectors: cs,
+		logger:     logger,
 	}
 }
 
-const (
-	pollCountMetric   = "PollCount"
-	randomValueMetric = "RandomValue"
-)
-
-type runtimeMetricsProvider struct {
-	stats          *runtime.MemStats
-	runtimeMetrics map[string]func(m *runtime.MemStats) float64
-}
-
-func newRuntimeMetricsProvider() *runtimeMetricsProvider {
-	return &runtimeMetricsProvider{
-		stats: &runtime.MemStats{},
-		runtimeMetrics: map[string]func(m *runtime.MemStats) float64{
-			"Alloc":         func(m *runtime.MemStats) float64 { return float64(m.Alloc) },
-			"BuckHashSys":   func(m *runtime.MemStats) float64 { return float64(m.BuckHashSys) },
-			"Frees":         func(m *runtime.MemStats) float64 { return float64(m.Frees) },
-			"GCCPUFraction": func(m *runtime.MemStats) float64 { return m.GCCPUFraction },
-			"GCSys":         func(m *runtime.MemStats) float64 { return float64(m.GCSys) },
-			"HeapAlloc":     func(m *runtime.MemStats) float64 { return float64(m.HeapAlloc) },
-			"HeapIdle":      func(m *runtime.MemStats) float64 { return float64(m.HeapIdle) },
-			"HeapInuse":     func(m *runtime.MemStats) float64 { return float64(m.HeapInuse) },
-			"HeapObjects":   func(m *runtime.MemStats) float64 { return float64(m.HeapObjects) },
-			"HeapReleased":  func(m *runtime.MemStats) float64 { return float64(m.HeapReleased) },
-			"HeapSys":       func(m *runtime.MemStats) float64 { return float64(m.HeapSys) },
-			"LastGC":        func(m *runtime.MemStats) float64 { return float64(m.LastGC) },
-			"Lookups":       func(m *runtime.MemStats) float64 { return float64(m.Lookups) },
-			"MCacheInuse":   func(m *runtime.MemStats) float64 { return float64(m.MCacheInuse) },
-			"MCacheSys":     func(m *runtime.MemStats) float64 { return float64(m.MCacheSys) },
-			"MSpanInuse":    func(m *runtime.MemStats) float64 { return float64(m.MSpanInuse) },
-			"MSpanSys":      func(m *runtime.MemStats) float64 { return float64(m.MSpanSys) },
-			"Mallocs":       func(m *runtime.MemStats) float64 { return float64(m.Mallocs) },
-			"NextGC":        func(m *runtime.MemStats) float64 { return float64(m.NextGC) },
-			"NumForcedGC":   func(m *runtime.MemStats) float64 { return float64(m.NumForcedGC) },
-			"NumGC":         func(m *runtime.MemStats) float64 { return float64(m.NumGC) },
-			"OtherSys":      func(m *runtime.MemStats) float64 { return float64(m.OtherSys) },
-			"PauseTotalNs":  func(m *runtime.MemStats) float64 { return float64(m.PauseTotalNs) },
-			"StackInuse":    func(m *runtime.MemStats) float64 { return float64(m.StackInuse) },
-			"StackSys":      func(m *runtime.MemStats) float64 { return float64(m.StackSys) },
-			"Sys":           func(m *runtime.MemStats) float64 { return float64(m.Sys) },
-			"TotalAlloc":    func(m *runtime.MemStats) float64 { return float64(m.TotalAlloc) },
-		},
-	}
-}
-
-func (cs *MetricsCollectService) updateRuntimeMetrics(ctx context.Context) error {
-	runtime.ReadMemStats(cs.rntMetrics.stats)
-
-	for name, fn := range cs.rntMetrics.runtimeMetrics {
-		select {
-		case <-ctx.Done():
-			return ctx.Err()
-		default:
-		}
-
-		val := fn(cs.rntMetrics.stats)
-		if err := cs.writer.UpdateMetrics(&models.Metrics{
-			ID:    name,
-			MType: models.Gauge,
-			Value: &val,
-		}); err != nil {
-			return fmt.Errorf("update %s metric error: %w", name, err)
-		}
-	}
-
-	return nil
-}
-
-func (cs *MetricsCollectService) updateRandomValue(ctx context.Context) error {
-	random := rand.Float64()
-
-	select {
-	case <-ctx.Done():
-		return ctx.Err()
-	default:
-	}
-
-	if err := cs.writer.UpdateMetrics(&models.Metrics{
-		ID:    randomValueMetric,
-		MType: models.Gauge,
-		Value: &random,
-	}); err != nil {
-		return fmt.Errorf("update %s metric error: %w", randomValueMetric, err)
+// Run polls every collector on its own interval until ctx is cancelled.
+// Collectors run independently: a collector that fails, panics or is slow does not delay the others.
+func (cs *MetricsCollectService) Run(ctx context.Context) {
+	var wg sync.WaitGroup
+	for _, c := range cs.collectors {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			cs.runCollector(ctx, c)
+		}()
 	}
-
-	return nil
+	wg.Wait()
 }
 
-func (cs *MetricsCollectService) updatePollCount(ctx context.Context) error {
-	var pollCount int64 = 1
-
-	select {
-	case <-ctx.Done():
-		return ctx.Err()
-	default:
+func (cs *MetricsCollectService) runCollector(ctx context.Context, c collectors.Collector) {
+	interval := c.Interval()
+	if interval <= 0 {
+		cs.logger.Error("collector has no poll interval, not running", zap.String("collector", c.Name()))
+		return
 	}
 
-	if err := cs.writer.UpdateMetrics(&models.Metrics{
-		ID:    pollCountMetric,
-		MType: models.Counter,
-		Delta: &pollCount,
-	}); err != nil {
-		return fmt.Errorf("update %s metric error: %w", pollCountMetric, err)
-	}
-	return nil
-}
-
-type sysMemMetricsProvider struct {
-	systemMemoryMetrics map[string]func(*mem.VirtualMemoryStat) float64
-}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
 
-func newSysMemMetricsProvider() *sysMemMetricsProvider {
-	return &sysMemMetricsProvider{
-		systemMemoryMetrics: map[string]func(vm *mem.VirtualMemoryStat) float64{
-			"TotalMemory": func(vm *mem.VirtualMemoryStat) float64 { return float64(vm.Total) },
-			"FreeMemory":  func(vm *mem.VirtualMemoryStat) float64 { return float64(vm.Free) },
-		},
-	}
-}
-
-func (cs *MetricsCollectService) updateSysMemoryMetrics(ctx context.Context) error {
-	vm, err := mem.VirtualMemory()
-	if err != nil {
-		return fmt.Errorf("failed to get virtual memory statistics: %w", err)
-	}
-
-	for name, fn := range cs.memMetrics.systemMemoryMetrics {
+	for {
 		select {
 		case <-ctx.Done():
-			return ctx.Err()
-		default:
+			return
+		case <-ticker.C:
 		}
 
-		val := fn(vm)
-		if err := cs.writer.UpdateMetrics(&models.Metrics{
-			ID:    name,
-			MType: models.Gauge,
-			Value: &val,
-		}); err != nil {
-			return fmt.Errorf("update %s metric error: %w", name, err)
+		if err := cs.collect(ctx, c); err != nil {
+			// A collector's own timeout is an ordinary failure; only the cancellation of ctx stops the loop.
+			if ctx.Err() != nil {
+				cs.logger.Debug("collection cancelled", zap.String("collector", c.Name()))
+				return
+			}
+			cs.logger.Error("failed to collect metrics", zap.String("collector", c.Name()), zap.Error(err))
 		}
 	}
-
-	return nil
-}
-
-type sysCPUMetricsProvider struct {
-	cpuCount int
 }
 
-func newSysCPUMetricsProvider() *sysCPUMetricsProvider {
-	count, err := cpu.Counts(true)
-	if err != nil {
-		count = runtime.NumCPU()
-	}
-
-	return &sysCPUMetricsProvider{
-		cpuCount: count,
-	}
-}
-
-func (cs *MetricsCollectService) updateCPUMetrics(ctx context.Context) error {
-	percentages, err := cpu.Percent(time.Second, true)
-	if err != nil {
-		return fmt.Errorf("failed to get CPU utilization: %w", err)
-	}
-
-	expectedCount := cs.cpuMetrics.cpuCount
-	if len(percentages) != expectedCount {
-		return fmt.Errorf("expected %d CPU metrics, got %d", expectedCount, len(percentages))
-	}
-
-	for i, percentage := range percentages {
-		select {
-		case <-ctx.Done():
-			return ctx.Err()
-		default:
+// collect polls one collector and stores its metrics. Metrics returned along with an error, such as those
+// of the mounts that could be read, are stored too. A panic in the collector is returned as an error.
+func (cs *MetricsCollectService) collect(ctx context.Context, c collectors.Collector) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("collector %s panicked: %v", c.Name(), r)
 		}
+	}()
 
-		metricName := fmt.Sprintf("CPUutilization%d", i)
-		val := percentage
-
-		if err := cs.writer.UpdateMetrics(&models.Metrics{
-			ID:    metricName,
-			MType: models.Gauge,
-			Value: &val,
-		}); err != nil {
-			return fmt.Errorf("update %s metric error: %w", metricName, err)
-		}
+	metrics, collectErr := c.Collect(ctx)
+	if collectErr != nil && !errors.Is(collectErr, context.Canceled) && !errors.Is(collectErr, context.DeadlineExceeded) {
+		collectErr = fmt.Errorf("collector %s: %w", c.Name(), collectErr)
 	}
 
-	return nil
-}
-
-func (cs *MetricsCollectService) UpdateAllMetrics(ctx context.Context) error {
-	if err := cs.updateRuntimeMetrics(ctx); err != nil {
-		if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
-			return err
+	for _, m := range metrics {
+		if err = cs.writer.UpdateMetrics(m); err != nil {
+			return errors.Join(collectErr, fmt.Errorf("update %s metric error: %w", m.ID, err))
 		}
-		return fmt.Errorf("failed to update metrics: %w", err)
 	}
-
-	if err := cs.updateSysMemoryMetrics(ctx); err != nil {
-		if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
-			return err
-		}
-		return fmt.Errorf("failed to update metrics: %w", err)
-	}
-
-	if err := cs.updateCPUMetrics(ctx); err != nil {
-		if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
-			return err
-		}
-		return fmt.Errorf("failed to update CPU metrics: %w", err)
-	}
-
-	if err := cs.updateRandomValue(ctx); err != nil {
-		if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
-			return err
-		}
-		return fmt.Errorf("failed to update metrics: %w", err)
-	}
-
-	if err := cs.updatePollCount(ctx); err != nil {
-		if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
-			return err
-		}
-		return fmt.Errorf("failed to update metrics: %w", err)
-	}
-
-	return nil
-}
-
-func (cs *MetricsCollectService) ResetPollCount() error {
-	err := cs.writer.ResetMetricValue(&models.Metrics{
-		ID:    pollCountMetric,
-		MType: models.Counter,
-	})
-
-	if err != nil {
-		return fmt.Errorf("reset %s metric error: %w", pollCountMetric, err)
-	}
-
-	return nil
+	return collectErr
 }
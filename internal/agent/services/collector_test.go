@@ -0,0 +1,59 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Pro100x3mal/go-musthave-metrics/internal/agent/collectors"
+	"github.com/Pro100x3mal/go-musthave-metrics/internal/agent/models"
+	"github.com/Pro100x3mal/go-musthave-metrics/internal/agent/repositories"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestMetricsCollectService_StoresPartialMetrics(t *testing.T) {
+	storage := repositories.NewMemStorage(nil)
+	partial := collectors.New("filesystem", time.Second, func(context.Context) ([]*models.Metrics, error) {
+		v := 42.0
+		return []*models.Metrics{{ID: "FilesystemTotal_root", MType: models.Gauge, Value: &v}}, errors.New("/mnt: permission denied")
+	})
+	cs := NewMetricsCollectService(storage, []collectors.Collector{partial}, zap.NewNop())
+
+	err := cs.collect(context.Background(), partial)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "permission denied")
+
+	metrics := storage.GetAllMetrics()
+	require.Len(t, metrics, 1)
+	assert.Equal(t, "FilesystemTotal_root", metrics[0].ID)
+}
+
+func TestMetricsCollectService_KeepsPollingAfterTimeout(t *testing.T) {
+	var calls atomic.Int32
+	slow := collectors.New("diskio", time.Millisecond, func(context.Context) ([]*models.Metrics, error) {
+		calls.Add(1)
+		// The collector's own timeout, not a cancellation of the service.
+		return nil, fmt.Errorf("read disk stats: %w", context.DeadlineExceeded)
+	})
+	cs := NewMetricsCollectService(repositories.NewMemStorage(nil), []collectors.Collector{slow}, zap.NewNop())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		cs.Run(ctx)
+		close(done)
+	}()
+
+	assert.Eventually(t, func() bool { return calls.Load() >= 3 }, time.Second, time.Millisecond)
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Run did not stop after cancellation")
+	}
+}
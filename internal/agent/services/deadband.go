@@ -0,0 +1,87 @@
+package services
+
+import (
+	"math"
+
+	"github.com/Pro100x3mal/go-musthave-metrics/internal/agent/models"
+)
+
+// DeadbandOptions configure change-only reporting of gauges.
+type DeadbandOptions struct {
+	// Absolute suppresses gauges that changed by at most this amount since the last acknowledged report.
+	Absolute float64
+	// Relative suppresses gauges that changed by at most this fraction of their last acknowledged value, e.g. 0.01.
+	Relative float64
+	// FullRefreshEvery forces a report of all metrics every this many reports; 0 never forces one.
+	FullRefreshEvery int
+}
+
+// Deadband selects the gauges worth reporting: those that changed by more than both deadbands since
+// they were last acknowledged by the server. Counters are always reported. It is not safe for concurrent
+// use; MetricsQueryService serializes reports.
+type Deadband struct {
+	opts DeadbandOptions
+	// acked holds the last acknowledged value of each gauge.
+	acked map[string]float64
+	// sinceRefresh counts the acknowledged reports since the last full one.
+	sinceRefresh int
+	refreshed    bool
+}
+
+// NewDeadband returns a Deadband with the given options.
+func NewDeadband(opts DeadbandOptions) *Deadband {
+	return &Deadband{
+		opts:  opts,
+		acked: make(map[string]float64),
+	}
+}
+
+// Filter returns the metrics to report and whether the report is a full refresh.
+// A nil Deadband reports everything.
+func (d *Deadband) Filter(metrics []*models.Metrics) ([]*models.Metrics, bool) {
+	if d == nil {
+		return metrics, true
+	}
+	if !d.refreshed || (d.opts.FullRefreshEvery > 0 && d.sinceRefresh >= d.opts.FullRefreshEvery-1) {
+		return metrics, true
+	}
+
+	changed := make([]*models.Metrics, 0, len(metrics))
+	for _, m := range metrics {
+		if m.MType != models.Gauge || m.Value == nil {
+			changed = append(changed, m)
+			continue
+		}
+		last, ok := d.acked[m.ID]
+		if !ok || d.exceeds(last, *m.Value) {
+			changed = append(changed, m)
+		}
+	}
+	return changed, false
+}
+
+func (d *Deadband) exceeds(last, value float64) bool {
+	diff := math.Abs(value - last)
+	if diff <= d.opts.Absolute {
+		return false
+	}
+	return diff > d.opts.Relative*math.Abs(last)
+}
+
+// Acknowledge records the gauges of a report delivered to the server, or stored for a later delivery.
+func (d *Deadband) Acknowledge(sent []*models.Metrics, full bool) {
+	if d == nil {
+		return
+	}
+	for _, m := range sent {
+		if m.MType == models.Gauge && m.Value != nil {
+			d.acked[m.ID] = *m.Value
+		}
+	}
+	if full {
+		d.refreshed = true
+		d.sinceRefresh = 0
+	} else {
+		d.sinceRefresh++
+	}
+}
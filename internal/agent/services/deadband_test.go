@@ -0,0 +1,66 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/Pro100x3mal/go-musthave-metrics/internal/agent/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func gaugeMetric(id string, v float64) *models.Metrics {
+	return &models.Metrics{ID: id, MType: models.Gauge, Value: &v}
+}
+
+func ids(metrics []*models.Metrics) []string {
+	var result []string
+	for _, m := range metrics {
+		result = append(result, m.ID)
+	}
+	return result
+}
+
+func TestDeadband_Filter(t *testing.T) {
+	d := NewDeadband(DeadbandOptions{Absolute: 1, Relative: 0.1, FullRefreshEvery: 3})
+	delta := int64(1)
+	report := func(alloc, heap float64) ([]*models.Metrics, bool) {
+		sent, full := d.Filter([]*models.Metrics{
+			gaugeMetric("Alloc", alloc),
+			gaugeMetric("HeapAlloc", heap),
+			{ID: "PollCount", MType: models.Counter, Delta: &delta},
+		})
+		d.Acknowledge(sent, full)
+		return sent, full
+	}
+
+	// The first report is full.
+	sent, full := report(100, 5)
+	assert.True(t, full)
+	assert.Len(t, sent, 3)
+
+	// Alloc changed by 5, within 10%; HeapAlloc by 2, beyond both deadbands. Counters are always sent.
+	sent, full = report(105, 7)
+	assert.False(t, full)
+	assert.Equal(t, []string{"HeapAlloc", "PollCount"}, ids(sent))
+
+	// Changes are measured against the last reported value, not the last polled one.
+	sent, full = report(111, 7)
+	assert.False(t, full)
+	assert.Equal(t, []string{"Alloc", "PollCount"}, ids(sent))
+
+	// Every third report is full.
+	sent, full = report(111, 7)
+	assert.True(t, full)
+	assert.Len(t, sent, 3)
+
+	sent, _ = report(111, 7)
+	assert.Equal(t, []string{"PollCount"}, ids(sent))
+}
+
+func TestDeadband_Nil(t *testing.T) {
+	var d *Deadband
+	metrics := []*models.Metrics{gaugeMetric("Alloc", 1)}
+	sent, full := d.Filter(metrics)
+	assert.True(t, full)
+	assert.Equal(t, metrics, sent)
+	d.Acknowledge(sent, full)
+}
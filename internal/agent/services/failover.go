@@ -0,0 +1,112 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"time"
+
+	"github.com/Pro100x3mal/go-musthave-metrics/internal/agent/models"
+	"go.uber.org/zap"
+)
+
+const (
+	// pingTimeout bounds one health probe of a server.
+	pingTimeout = 2 * time.Second
+	// healthCheckInterval is how often FailoverTransport probes its servers.
+	healthCheckInterval = 5 * time.Second
+)
+
+// Server is a transport to one server that can also probe the server's health.
+type Server interface {
+	Transport
+	Ping(ctx context.Context) error
+}
+
+type failoverServer struct {
+	name    string
+	server  Server
+	healthy atomic.Bool
+}
+
+// FailoverTransport sends each batch to the first healthy server in configuration order. A server is marked
+// unhealthy when a send to it fails and healthy again when a probe of it succeeds; if no server is healthy,
+// all of them are tried in order.
+type FailoverTransport struct {
+	servers []*failoverServer
+	logger  *zap.Logger
+}
+
+// NewFailoverTransport returns a transport failing over between servers, keyed by their names.
+// All servers start out healthy.
+func NewFailoverTransport(names []string, servers []Server, logger *zap.Logger) *FailoverTransport {
+	ft := &FailoverTransport{logger: logger}
+	for i, server := range servers {
+		fs := &failoverServer{name: names[i], server: server}
+		fs.healthy.Store(true)
+		ft.servers = append(ft.servers, fs)
+	}
+	return ft
+}
+
+func (ft *FailoverTransport) Send(ctx context.Context, metrics []*models.Metrics, idempotencyKey string) error {
+	var errs []error
+	for _, fs := range ft.ordered() {
+		err := fs.server.Send(ctx, metrics, idempotencyKey)
+		if err == nil {
+			fs.healthy.Store(true)
+			return nil
+		}
+		if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) || errors.Is(err, ErrRejected) {
+			return err
+		}
+		if fs.healthy.Swap(false) {
+			ft.logger.Warn("server unavailable, failing over", zap.String("server", fs.name), zap.Error(err))
+		}
+		errs = append(errs, err)
+	}
+	return errors.Join(errs...)
+}
+
+// ordered returns the healthy servers followed by the unhealthy ones, each in configuration order.
+func (ft *FailoverTransport) ordered() []*failoverServer {
+	healthy := make([]*failoverServer, 0, len(ft.servers))
+	var unhealthy []*failoverServer
+	for _, fs := range ft.servers {
+		if fs.healthy.Load() {
+			healthy = append(healthy, fs)
+		} else {
+			unhealthy = append(unhealthy, fs)
+		}
+	}
+	return append(healthy, unhealthy...)
+}
+
+// RunHealthChecks probes every server until ctx is cancelled, so that a recovered server is preferred again.
+func (ft *FailoverTransport) RunHealthChecks(ctx context.Context) {
+	ticker := time.NewTicker(healthCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		for _, fs := range ft.servers {
+			err := fs.server.Ping(ctx)
+			if ctx.Err() != nil {
+				return
+			}
+			healthy := err == nil
+			if fs.healthy.Swap(healthy) != healthy {
+				if healthy {
+					ft.logger.Info("server available again", zap.String("server", fs.name))
+				} else {
+					ft.logger.Warn("server unavailable", zap.String("server", fs.name), zap.Error(err))
+				}
+			}
+		}
+	}
+}
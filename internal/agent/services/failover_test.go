@@ -0,0 +1,44 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestFailoverTransport(t *testing.T) {
+	primary := &fakeTransport{}
+	secondary := &fakeTransport{}
+	ft := NewFailoverTransport([]string{"primary", "secondary"}, []Server{primary, secondary}, zap.NewNop())
+
+	require.NoError(t, ft.Send(context.Background(), nil, "1"))
+	assert.Equal(t, []string{"1"}, primary.keys)
+
+	// A failed primary is skipped until it is healthy again.
+	primary.err = errors.New("connection refused")
+	require.NoError(t, ft.Send(context.Background(), nil, "2"))
+	primary.err = nil
+	require.NoError(t, ft.Send(context.Background(), nil, "3"))
+	assert.Equal(t, []string{"1"}, primary.keys)
+	assert.Equal(t, []string{"2", "3"}, secondary.keys)
+
+	ft.servers[0].healthy.Store(true)
+	require.NoError(t, ft.Send(context.Background(), nil, "4"))
+	assert.Equal(t, []string{"1", "4"}, primary.keys)
+
+	// Rejected batches are not failed over.
+	primary.err = ErrRejected
+	assert.ErrorIs(t, ft.Send(context.Background(), nil, "5"), ErrRejected)
+	assert.Equal(t, []string{"2", "3"}, secondary.keys)
+
+	// With every server down, all are tried.
+	primary.err = errors.New("connection refused")
+	secondary.err = errors.New("connection refused")
+	assert.Error(t, ft.Send(context.Background(), nil, "6"))
+	assert.False(t, ft.servers[0].healthy.Load())
+	assert.False(t, ft.servers[1].healthy.Load())
+}
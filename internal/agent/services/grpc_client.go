@@ -0,0 +1,146 @@
+package services
+
+import (
+	"context"
+	"crypto/rsa"
+	"fmt"
+
+	"github.com/Pro100x3mal/go-musthave-metrics/internal/agent/configs"
+	"github.com/Pro100x3mal/go-musthave-metrics/internal/agent/models"
+	"github.com/Pro100x3mal/go-musthave-metrics/pkg/crypto"
+	"github.com/Pro100x3mal/go-musthave-metrics/pkg/metricspb"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+// grpcServiceConfig mirrors the HTTP client settings: a 10s timeout and 3 retries with 1s to 5s backoff.
+const grpcServiceConfig = `{
+	"methodConfig": [{
+		"name": [{"service": "metrics.Metrics"}],
+		"timeout": "10s",
+		"retryPolicy": {
+			"maxAttempts": 4,
+			"initialBackoff": "1s",
+			"maxBackoff": "5s",
+			"backoffMultiplier": 2,
+			"retryableStatusCodes": ["UNAVAILABLE"]
+		}
+	}]
+}`
+
+// GRPCClient sends metrics over the gRPC API as an alternative to the HTTP Client.
+type GRPCClient struct {
+	conn   *grpc.ClientConn
+	client metricspb.MetricsClient
+}
+
+func NewGRPCClient(cfg *configs.AgentConfig, publicKey *rsa.PublicKey) (*GRPCClient, error) {
+	var interceptors []grpc.UnaryClientInterceptor
+	if publicKey != nil {
+		interceptors = append(interceptors, encryptInterceptor(publicKey))
+	}
+	if cfg.Key != "" {
+		interceptors = append(interceptors, signInterceptor(cfg.Key))
+	}
+
+	conn, err := grpc.NewClient(cfg.GRPCAddr,
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithDefaultServiceConfig(grpcServiceConfig),
+		grpc.WithChainUnaryInterceptor(interceptors...),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create gRPC client: %w", err)
+	}
+
+	return &GRPCClient{
+		conn:   conn,
+		client: metricspb.NewMetricsClient(conn),
+	}, nil
+}
+
+func (c *GRPCClient) Send(ctx context.Context, metrics []*models.Metrics, idempotencyKey string) error {
+	req := &metricspb.UpdateBatchRequest{
+		Metrics:        make([]*metricspb.Metric, 0, len(metrics)),
+		IdempotencyKey: idempotencyKey,
+	}
+	for _, m := range metrics {
+		req.Metrics = append(req.Metrics, metricToProto(m))
+	}
+
+	_, err := c.client.UpdateBatch(ctx, req)
+	switch status.Code(err) {
+	case codes.InvalidArgument, codes.Unauthenticated, codes.PermissionDenied:
+		return fmt.Errorf("%w: %w", ErrRejected, err)
+	}
+	return err
+}
+
+func (c *GRPCClient) Close() error {
+	return c.conn.Close()
+}
+
+func metricToProto(m *models.Metrics) *metricspb.Metric {
+	pm := &metricspb.Metric{
+		Id:     m.ID,
+		Labels: m.Labels,
+	}
+	switch m.MType {
+	case models.Gauge:
+		pm.Type = metricspb.MetricType_METRIC_TYPE_GAUGE
+	case models.Counter:
+		pm.Type = metricspb.MetricType_METRIC_TYPE_COUNTER
+	}
+	if m.Delta != nil {
+		pm.Delta = *m.Delta
+	}
+	if m.Value != nil {
+		pm.Value = *m.Value
+	}
+	return pm
+}
+
+// encryptInterceptor moves the metrics of every batch into an encrypted envelope, like the HTTP client encrypts request bodies.
+func encryptInterceptor(publicKey *rsa.PublicKey) grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		batch, ok := req.(*metricspb.UpdateBatchRequest)
+		if !ok {
+			return invoker(ctx, method, req, reply, cc, opts...)
+		}
+
+		data, err := proto.Marshal(&metricspb.UpdateBatchRequest{Metrics: batch.GetMetrics()})
+		if err != nil {
+			return fmt.Errorf("failed to marshal metrics: %w", err)
+		}
+		encrypted, err := crypto.Encrypt(publicKey, data)
+		if err != nil {
+			return fmt.Errorf("failed to encrypt metrics: %w", err)
+		}
+
+		sealed := &metricspb.UpdateBatchRequest{
+			IdempotencyKey:   batch.GetIdempotencyKey(),
+			EncryptedMetrics: encrypted,
+		}
+		return invoker(ctx, method, sealed, reply, cc, opts...)
+	}
+}
+
+// signInterceptor sends the HMAC-SHA256 of every request in the hashsha256 metadata, like the HTTP client's HashSHA256 header.
+func signInterceptor(key string) grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		msg, ok := req.(proto.Message)
+		if !ok {
+			return invoker(ctx, method, req, reply, cc, opts...)
+		}
+
+		sig, err := metricspb.Sign(msg, key)
+		if err != nil {
+			return fmt.Errorf("failed to sign request: %w", err)
+		}
+		ctx = metadata.AppendToOutgoingContext(ctx, metricspb.SignatureMetadataKey, sig)
+		return invoker(ctx, method, req, reply, cc, opts...)
+	}
+}
@@ -5,12 +5,14 @@ import (
 	"compress/gzip"
 	"context"
 	"crypto/hmac"
+	"crypto/rand"
 	"crypto/rsa"
 	"crypto/sha256"
 	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"net/http"
 	"sync"
 	"time"
 
@@ -22,25 +24,64 @@ import (
 
 type RepositoryReader interface {
 	GetAllMetrics() []*models.Metrics
+	// Drain completes an acknowledged report: it subtracts the reported counter deltas and restarts the gauge windows.
+	Drain(sent []*models.Metrics)
+}
+
+// Spooler stores the batches that could not be delivered until they can be replayed.
+type Spooler interface {
+	Put(batch *models.Batch) (dropped int, err error)
+	Replay(ctx context.Context, send func(ctx context.Context, batch *models.Batch) error) (sent, dropped int, err error)
+}
+
+// ErrSpooled is returned by SendMetrics when the metrics could not be delivered but were stored in the spool
+// for a later replay. The metrics are not lost, so their counters are drained as after a successful report.
+var ErrSpooled = errors.New("metrics spooled for later delivery")
+
+// ErrRejected marks a batch the server refused as invalid; such a batch is not retried or spooled.
+var ErrRejected = errors.New("batch rejected by server")
+
+// Target is a destination of reports: a transport and an optional spool of the batches it missed.
+// Each target is retried and spooled independently of the others.
+type Target struct {
+	// Name identifies the target in errors, e.g. its server address.
+	Name      string
+	Transport Transport
+	// Spool may be nil, in which case batches the target could not receive are lost.
+	Spool Spooler
 }
 
 type MetricsQueryService struct {
-	reader RepositoryReader
+	reader  RepositoryReader
+	labels  map[string]string
+	targets []Target
+	// deadband limits reports to changed gauges; nil reports all metrics.
+	deadband *Deadband
+	// sendMu serializes reports: a snapshot is drained only after it was acknowledged, so a concurrent report
+	// would send the same counter increments again. It also keeps spooled and new batches in order.
+	sendMu sync.Mutex
 }
 
-func NewMetricsQueryService(reader RepositoryReader) *MetricsQueryService {
+// NewMetricsQueryService returns a service reporting the metrics of reader to every target.
+// deadband may be nil to report all metrics every time.
+func NewMetricsQueryService(reader RepositoryReader, labels map[string]string, deadband *Deadband, targets ...Target) *MetricsQueryService {
 	return &MetricsQueryService{
-		reader: reader,
+		reader:   reader,
+		labels:   labels,
+		targets:  targets,
+		deadband: deadband,
 	}
 }
 
 type Client struct {
 	client *resty.Client
+	addr   string
 }
 
-func NewClient(cfg *configs.AgentConfig, publicKey *rsa.PublicKey) *Client {
+// NewClient returns an HTTP client of the server at addr.
+func NewClient(cfg *configs.AgentConfig, addr string, publicKey *rsa.PublicKey) *Client {
 	c := resty.New().
-		SetBaseURL("http://" + cfg.ServerAddr).
+		SetBaseURL("http://" + addr).
 		SetTimeout(10 * time.Second).
 		SetRetryCount(3).
 		SetRetryWaitTime(1 * time.Second).
@@ -66,15 +107,124 @@ func NewClient(cfg *configs.AgentConfig, publicKey *rsa.PublicKey) *Client {
 
 	return &Client{
 		client: c,
+		addr:   addr,
 	}
 }
 
-func (qs *MetricsQueryService) SendMetrics(ctx context.Context, c *Client) error {
+// Transport delivers one batch of metrics to the server.
+// The idempotency key identifies the batch across all its retries.
+type Transport interface {
+	Send(ctx context.Context, metrics []*models.Metrics, idempotencyKey string) error
+}
+
+// SendMetrics reports a snapshot of the metrics to every target, in parallel. The reported counters and gauge
+// windows are drained once at least one target received or spooled the batch; targets that did neither miss it.
+func (qs *MetricsQueryService) SendMetrics(ctx context.Context) error {
+	qs.sendMu.Lock()
+	defer qs.sendMu.Unlock()
+
 	metrics := qs.reader.GetAllMetrics()
 	if len(metrics) == 0 {
 		return errors.New("no metrics to send")
 	}
+	if len(qs.labels) > 0 {
+		for _, m := range metrics {
+			m.Labels = qs.labels
+		}
+	}
+
+	sent, full := qs.deadband.Filter(metrics)
+	var batch *models.Batch
+	if len(sent) > 0 {
+		idempotencyKey, err := newIdempotencyKey()
+		if err != nil {
+			return err
+		}
+		batch = &models.Batch{
+			IdempotencyKey: idempotencyKey,
+			CreatedAt:      time.Now(),
+			Metrics:        sent,
+		}
+	}
 
+	errs := make([]error, len(qs.targets))
+	if len(qs.targets) == 1 {
+		errs[0] = deliver(ctx, qs.targets[0], batch)
+	} else {
+		var wg sync.WaitGroup
+		for i, target := range qs.targets {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				errs[i] = deliver(ctx, target, batch)
+			}()
+		}
+		wg.Wait()
+	}
+
+	var received bool
+	for i, err := range errs {
+		if err == nil || errors.Is(err, ErrSpooled) {
+			received = true
+		}
+		if err != nil && len(qs.targets) > 1 {
+			errs[i] = fmt.Errorf("%s: %w", qs.targets[i].Name, err)
+		}
+	}
+	if received {
+		qs.acknowledge(sent, full)
+	}
+	return errors.Join(errs...)
+}
+
+// deliver sends the batch to one target after the batches spooled for it, or spools it if that fails.
+// A nil batch, when nothing changed, only replays the spool.
+func deliver(ctx context.Context, target Target, batch *models.Batch) error {
+	var err error
+	if target.Spool != nil {
+		// Spooled batches go first so that the server receives the reports in order.
+		_, _, err = target.Spool.Replay(ctx, func(ctx context.Context, b *models.Batch) error {
+			if err := sendBatch(ctx, target.Transport, b); err != nil && !errors.Is(err, ErrRejected) {
+				return err
+			}
+			return nil
+		})
+	}
+	if batch == nil {
+		return err
+	}
+	if err == nil {
+		err = sendBatch(ctx, target.Transport, batch)
+	}
+	if err == nil || target.Spool == nil || errors.Is(err, ErrRejected) {
+		return err
+	}
+
+	// The batch is spooled on cancellation too, so that it is delivered after a restart.
+	if _, spoolErr := target.Spool.Put(batch); spoolErr != nil {
+		return errors.Join(err, fmt.Errorf("failed to spool metrics: %w", spoolErr))
+	}
+	return fmt.Errorf("%w: %w", ErrSpooled, err)
+}
+
+// acknowledge completes a report that was delivered or spooled.
+func (qs *MetricsQueryService) acknowledge(sent []*models.Metrics, full bool) {
+	qs.reader.Drain(sent)
+	qs.deadband.Acknowledge(sent, full)
+}
+
+func sendBatch(ctx context.Context, t Transport, batch *models.Batch) error {
+	err := t.Send(ctx, batch.Metrics, batch.IdempotencyKey)
+	if err != nil {
+		if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+			return err
+		}
+		return fmt.Errorf("failed to send metrics: %w", err)
+	}
+	return nil
+}
+
+func (c *Client) Send(ctx context.Context, metrics []*models.Metrics, idempotencyKey string) error {
 	buf := &bytes.Buffer{}
 	gz := gzip.NewWriter(buf)
 	err := json.NewEncoder(gz).Encode(metrics)
@@ -85,22 +235,63 @@ func (qs *MetricsQueryService) SendMetrics(ctx context.Context, c *Client) error
 		return fmt.Errorf("failed to close gzip writer: %w", err)
 	}
 
-	_, err = c.client.R().
+	resp, err := c.client.R().
 		SetContext(ctx).
+		SetHeader("Idempotency-Key", idempotencyKey).
 		SetHeader("Content-Encoding", "gzip").
 		SetHeader("Content-Type", "application/json").
 		SetBody(buf.Bytes()).
 		Post("/updates/")
-
 	if err != nil {
-		if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
-			return err
-		}
-		return fmt.Errorf("failed to send metrics: %w", err)
+		return err
+	}
+	return statusError(resp.StatusCode(), resp.Status())
+}
+
+// statusError returns nil for successful responses and ErrRejected for client errors a retry cannot fix.
+func statusError(code int, status string) error {
+	switch {
+	case code < http.StatusBadRequest:
+		return nil
+	case code == http.StatusRequestTimeout || code == http.StatusTooManyRequests || code >= http.StatusInternalServerError:
+		return fmt.Errorf("server responded with %s", status)
+	default:
+		return fmt.Errorf("%w: %s", ErrRejected, status)
 	}
+}
 
+// Ping checks that the server is up, without the retries of Send. It probes /ping, but any response
+// counts as reachable: the server answers it with an error status when it runs without a database.
+// Only the gateway errors of a proxy in front of the server mean that the server is down.
+func (c *Client) Ping(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, pingTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "http://"+c.addr+"/ping", nil)
+	if err != nil {
+		return err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return fmt.Errorf("server responded with %s", resp.Status)
+	}
 	return nil
 }
+
+// newIdempotencyKey returns a random key identifying one batch across all its retries.
+func newIdempotencyKey() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate idempotency key: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
+
 func prepareRequestData(body []byte, publicKey *rsa.PublicKey, key string) ([]byte, string, error) {
 	if publicKey != nil {
 		var err error
@@ -0,0 +1,173 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Pro100x3mal/go-musthave-metrics/internal/agent/configs"
+	"github.com/Pro100x3mal/go-musthave-metrics/internal/agent/models"
+	"github.com/Pro100x3mal/go-musthave-metrics/internal/agent/repositories"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeTransport struct {
+	err     error
+	pingErr error
+	keys    []string
+	onSend  func()
+}
+
+func (t *fakeTransport) Ping(context.Context) error {
+	return t.pingErr
+}
+
+func (t *fakeTransport) Send(_ context.Context, _ []*models.Metrics, idempotencyKey string) error {
+	if t.onSend != nil {
+		t.onSend()
+	}
+	if t.err != nil {
+		return t.err
+	}
+	t.keys = append(t.keys, idempotencyKey)
+	return nil
+}
+
+func TestSendMetrics_Spool(t *testing.T) {
+	repo := repositories.NewMemStorage(nil)
+	v := 1.0
+	require.NoError(t, repo.UpdateMetrics(&models.Metrics{ID: "Alloc", MType: models.Gauge, Value: &v}))
+
+	spool, err := repositories.NewSpool(t.TempDir(), repositories.SpoolOptions{})
+	require.NoError(t, err)
+	transport := &fakeTransport{err: errors.New("connection refused")}
+	qs := NewMetricsQueryService(repo, nil, nil, Target{Name: "primary", Transport: transport, Spool: spool})
+
+	for i := 0; i < 2; i++ {
+		err = qs.SendMetrics(context.Background())
+		assert.ErrorIs(t, err, ErrSpooled)
+	}
+	assert.Equal(t, 2, spool.Len())
+
+	// Once the server is back, the spooled batches are sent before the new one.
+	transport.err = nil
+	require.NoError(t, qs.SendMetrics(context.Background()))
+	assert.Len(t, transport.keys, 3)
+	assert.Zero(t, spool.Len())
+
+	// Rejected batches are not spooled.
+	transport.err = ErrRejected
+	err = qs.SendMetrics(context.Background())
+	assert.ErrorIs(t, err, ErrRejected)
+	assert.NotErrorIs(t, err, ErrSpooled)
+	assert.Zero(t, spool.Len())
+}
+
+func counterValue(t *testing.T, repo *repositories.MemStorage, id string) int64 {
+	t.Helper()
+	for _, m := range repo.GetAllMetrics() {
+		if m.ID == id {
+			return *m.Delta
+		}
+	}
+	return 0
+}
+
+func TestSendMetrics_DrainsAcknowledgedCounters(t *testing.T) {
+	repo := repositories.NewMemStorage(nil)
+	one := int64(1)
+	poll := func() {
+		require.NoError(t, repo.UpdateMetrics(&models.Metrics{ID: "PollCount", MType: models.Counter, Delta: &one}))
+	}
+	poll()
+	poll()
+
+	transport := &fakeTransport{err: errors.New("connection refused")}
+	qs := NewMetricsQueryService(repo, nil, nil, Target{Name: "primary", Transport: transport})
+
+	// A failed report keeps the counters.
+	require.Error(t, qs.SendMetrics(context.Background()))
+	assert.Equal(t, int64(2), counterValue(t, repo, "PollCount"))
+
+	// A poll during the report is not lost.
+	transport.err = nil
+	transport.onSend = poll
+	require.NoError(t, qs.SendMetrics(context.Background()))
+	assert.Equal(t, int64(1), counterValue(t, repo, "PollCount"))
+}
+
+func TestSendMetrics_Fanout(t *testing.T) {
+	repo := repositories.NewMemStorage(nil)
+	one := int64(1)
+	require.NoError(t, repo.UpdateMetrics(&models.Metrics{ID: "PollCount", MType: models.Counter, Delta: &one}))
+
+	up := &fakeTransport{}
+	down := &fakeTransport{err: errors.New("connection refused")}
+	downSpool, err := repositories.NewSpool(t.TempDir(), repositories.SpoolOptions{})
+	require.NoError(t, err)
+	qs := NewMetricsQueryService(repo, nil, nil,
+		Target{Name: "a:8080", Transport: up},
+		Target{Name: "b:8080", Transport: down, Spool: downSpool},
+	)
+
+	err = qs.SendMetrics(context.Background())
+	assert.ErrorIs(t, err, ErrSpooled)
+	assert.ErrorContains(t, err, "b:8080")
+	assert.Len(t, up.keys, 1)
+	assert.Equal(t, 1, downSpool.Len())
+	assert.Zero(t, counterValue(t, repo, "PollCount"))
+
+	// The recovered server catches up with the same batch.
+	down.err = nil
+	require.NoError(t, repo.UpdateMetrics(&models.Metrics{ID: "PollCount", MType: models.Counter, Delta: &one}))
+	require.NoError(t, qs.SendMetrics(context.Background()))
+	assert.Len(t, up.keys, 2)
+	require.Len(t, down.keys, 2)
+	assert.Equal(t, up.keys, down.keys)
+}
+
+func TestStatusError(t *testing.T) {
+	assert.NoError(t, statusError(200, "200 OK"))
+	assert.ErrorIs(t, statusError(400, "400 Bad Request"), ErrRejected)
+	assert.NotErrorIs(t, statusError(503, "503 Service Unavailable"), ErrRejected)
+	assert.NotErrorIs(t, statusError(429, "429 Too Many Requests"), ErrRejected)
+}
+
+func TestClient_Ping(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  int
+		wantErr bool
+	}{
+		{name: "database available", status: http.StatusOK},
+		{name: "no database", status: http.StatusNotImplemented},
+		{name: "database down", status: http.StatusInternalServerError},
+		{name: "proxy without backend", status: http.StatusBadGateway, wantErr: true},
+		{name: "unavailable", status: http.StatusServiceUnavailable, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				assert.Equal(t, "/ping", r.URL.Path)
+				w.WriteHeader(tt.status)
+			}))
+			defer srv.Close()
+
+			client := NewClient(&configs.AgentConfig{}, strings.TrimPrefix(srv.URL, "http://"), nil)
+			err := client.Ping(context.Background())
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+
+	client := NewClient(&configs.AgentConfig{}, "127.0.0.1:1", nil)
+	assert.Error(t, client.Ping(context.Background()), "unreachable server")
+}
@@ -0,0 +1,180 @@
+package alerting
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/Pro100x3mal/go-musthave-metrics/internal/server/models"
+	"github.com/Pro100x3mal/go-musthave-metrics/internal/server/repositories"
+	"go.uber.org/zap"
+)
+
+// State is the lifecycle state of an alert.
+type State string
+
+// Alert states. An alert is pending while its condition holds for less than the rule's For duration,
+// firing once it has held long enough, and resolved when the condition of a firing alert clears.
+const (
+	StatePending  State = "pending"
+	StateFiring   State = "firing"
+	StateResolved State = "resolved"
+)
+
+// Alert is the current evaluation result of a rule whose condition holds or has just cleared.
+type Alert struct {
+	Rule        string        `json:"rule"`
+	Metric      string        `json:"metric"`
+	Labels      models.Labels `json:"labels,omitempty"`
+	MType       string        `json:"type"`
+	Op          string        `json:"op"`
+	Threshold   float64       `json:"threshold"`
+	Value       float64       `json:"value"`
+	State       State         `json:"state"`
+	ActiveSince time.Time     `json:"active_since"`
+	FiredAt     *time.Time    `json:"fired_at,omitempty"`
+	ResolvedAt  *time.Time    `json:"resolved_at,omitempty"`
+}
+
+// Engine periodically evaluates alert rules against stored metrics and notifies on state changes.
+type Engine struct {
+	reader    repositories.RepositoryReader
+	rules     []Rule
+	interval  time.Duration
+	notifiers []Notifier
+	logger    *zap.Logger
+	mu        *sync.RWMutex
+	alerts    map[string]*Alert
+}
+
+// NewEngine creates a new Engine evaluating rules every interval.
+func NewEngine(reader repositories.RepositoryReader, rules []Rule, interval time.Duration, logger *zap.Logger, notifiers ...Notifier) *Engine {
+	return &Engine{
+		reader:    reader,
+		rules:     rules,
+		interval:  interval,
+		notifiers: notifiers,
+		logger:    logger,
+		mu:        &sync.RWMutex{},
+		alerts:    make(map[string]*Alert),
+	}
+}
+
+// Run evaluates the rules every interval until ctx is cancelled.
+func (e *Engine) Run(ctx context.Context) {
+	e.logger.Info("starting alerting engine", zap.Int("rules", len(e.rules)), zap.Duration("interval", e.interval))
+	ticker := time.NewTicker(e.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			e.evaluate(ctx, time.Now())
+		case <-ctx.Done():
+			e.logger.Info("alerting engine stopped")
+			return
+		}
+	}
+}
+
+// ActiveAlerts returns pending and firing alerts sorted by rule name.
+func (e *Engine) ActiveAlerts() []Alert {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	out := make([]Alert, 0, len(e.alerts))
+	for _, a := range e.alerts {
+		out = append(out, *a)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		return out[i].Rule < out[j].Rule
+	})
+	return out
+}
+
+func (e *Engine) evaluate(ctx context.Context, now time.Time) {
+	for _, rule := range e.rules {
+		if ctx.Err() != nil {
+			return
+		}
+
+		value, err := e.readValue(ctx, rule)
+		if err != nil && !errors.Is(err, models.ErrMetricNotFound) {
+			e.logger.Error("failed to read metric for alert rule", zap.String("rule", rule.Name), zap.Error(err))
+			continue
+		}
+
+		if changed := e.transition(rule, err == nil && rule.matches(value), value, now); changed != nil {
+			e.notify(ctx, *changed)
+		}
+	}
+}
+
+// transition updates the alert state of rule and returns the alert if it started firing or was resolved.
+func (e *Engine) transition(rule Rule, active bool, value float64, now time.Time) *Alert {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	alert, exists := e.alerts[rule.Name]
+
+	if !active {
+		if !exists {
+			return nil
+		}
+		delete(e.alerts, rule.Name)
+		if alert.State != StateFiring {
+			return nil
+		}
+		resolved := *alert
+		resolved.State = StateResolved
+		resolved.Value = value
+		resolved.ResolvedAt = &now
+		return &resolved
+	}
+
+	if !exists {
+		alert = &Alert{
+			Rule:        rule.Name,
+			Metric:      rule.Metric,
+			Labels:      rule.Labels,
+			MType:       rule.MType,
+			Op:          rule.Op,
+			Threshold:   rule.Threshold,
+			State:       StatePending,
+			ActiveSince: now,
+		}
+		e.alerts[rule.Name] = alert
+	}
+	alert.Value = value
+
+	if alert.State == StatePending && now.Sub(alert.ActiveSince) >= rule.For {
+		alert.State = StateFiring
+		alert.FiredAt = &now
+		fired := *alert
+		return &fired
+	}
+	return nil
+}
+
+func (e *Engine) readValue(ctx context.Context, rule Rule) (float64, error) {
+	key := models.SeriesKey(rule.Metric, rule.Labels)
+	switch rule.MType {
+	case models.Gauge:
+		return e.reader.GetGauge(ctx, key)
+	case models.Counter:
+		delta, err := e.reader.GetCounter(ctx, key)
+		return float64(delta), err
+	default:
+		return 0, models.ErrUnsupportedMetricType
+	}
+}
+
+func (e *Engine) notify(ctx context.Context, alert Alert) {
+	for _, n := range e.notifiers {
+		if err := n.Notify(ctx, alert); err != nil {
+			e.logger.Error("failed to deliver alert notification", zap.String("rule", alert.Rule), zap.Error(err))
+		}
+	}
+}
@@ -0,0 +1,153 @@
+package alerting
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Pro100x3mal/go-musthave-metrics/internal/server/models"
+	"github.com/Pro100x3mal/go-musthave-metrics/internal/server/repositories"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+type recordingNotifier struct {
+	alerts []Alert
+}
+
+func (rn *recordingNotifier) Notify(_ context.Context, alert Alert) error {
+	rn.alerts = append(rn.alerts, alert)
+	return nil
+}
+
+func setGauge(t *testing.T, repo *repositories.MemStorage, name string, value float64) {
+	require.NoError(t, repo.UpdateGauge(context.Background(), &models.Metrics{ID: name, MType: models.Gauge, Value: &value}))
+}
+
+func TestEngine_StateTransitions(t *testing.T) {
+	ctx := context.Background()
+	repo := repositories.NewMemStorage()
+	notifier := &recordingNotifier{}
+
+	rule := Rule{Name: "HighAlloc", Metric: "Alloc", MType: models.Gauge, Op: OpGreater, Threshold: 100, For: time.Minute}
+	engine := NewEngine(repo, []Rule{rule}, time.Second, zap.NewNop(), notifier)
+
+	start := time.Now()
+
+	engine.evaluate(ctx, start)
+	assert.Empty(t, engine.ActiveAlerts(), "missing metric must not trigger an alert")
+
+	setGauge(t, repo, "Alloc", 150)
+	engine.evaluate(ctx, start)
+	alerts := engine.ActiveAlerts()
+	require.Len(t, alerts, 1)
+	assert.Equal(t, StatePending, alerts[0].State)
+	assert.Empty(t, notifier.alerts)
+
+	engine.evaluate(ctx, start.Add(30*time.Second))
+	assert.Equal(t, StatePending, engine.ActiveAlerts()[0].State)
+
+	engine.evaluate(ctx, start.Add(time.Minute))
+	alerts = engine.ActiveAlerts()
+	require.Len(t, alerts, 1)
+	assert.Equal(t, StateFiring, alerts[0].State)
+	require.Len(t, notifier.alerts, 1)
+	assert.Equal(t, StateFiring, notifier.alerts[0].State)
+
+	engine.evaluate(ctx, start.Add(2*time.Minute))
+	assert.Len(t, notifier.alerts, 1, "firing alert must be notified once")
+
+	setGauge(t, repo, "Alloc", 50)
+	engine.evaluate(ctx, start.Add(3*time.Minute))
+	assert.Empty(t, engine.ActiveAlerts())
+	require.Len(t, notifier.alerts, 2)
+	assert.Equal(t, StateResolved, notifier.alerts[1].State)
+	assert.Equal(t, 50.0, notifier.alerts[1].Value)
+}
+
+func TestEngine_PendingAlertClearsSilently(t *testing.T) {
+	ctx := context.Background()
+	repo := repositories.NewMemStorage()
+	notifier := &recordingNotifier{}
+
+	rule := Rule{Name: "LowMemory", Metric: "FreeMemory", MType: models.Gauge, Op: OpLess, Threshold: 10, For: time.Minute}
+	engine := NewEngine(repo, []Rule{rule}, time.Second, zap.NewNop(), notifier)
+
+	start := time.Now()
+	setGauge(t, repo, "FreeMemory", 5)
+	engine.evaluate(ctx, start)
+	require.Len(t, engine.ActiveAlerts(), 1)
+
+	setGauge(t, repo, "FreeMemory", 20)
+	engine.evaluate(ctx, start.Add(10*time.Second))
+	assert.Empty(t, engine.ActiveAlerts())
+	assert.Empty(t, notifier.alerts)
+}
+
+func TestLoadRules(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    []Rule
+		wantErr bool
+	}{
+		{
+			name:    "valid rules",
+			content: `[{"name":"HighAlloc","metric":"Alloc","type":"gauge","op":">","threshold":1e9,"for":"1m"},{"name":"NoPolls","metric":"PollCount","type":"counter","op":"==","threshold":0}]`,
+			want: []Rule{
+				{Name: "HighAlloc", Metric: "Alloc", MType: models.Gauge, Op: OpGreater, Threshold: 1e9, For: time.Minute},
+				{Name: "NoPolls", Metric: "PollCount", MType: models.Counter, Op: OpEqual, Threshold: 0},
+			},
+		},
+		{
+			name:    "labeled rule",
+			content: `[{"name":"HostLoad","metric":"Load","labels":{"host":"h42"},"type":"gauge","op":">=","threshold":4}]`,
+			want: []Rule{
+				{Name: "HostLoad", Metric: "Load", Labels: models.Labels{"host": "h42"}, MType: models.Gauge, Op: OpGreaterEqual, Threshold: 4},
+			},
+		},
+		{
+			name:    "invalid label name",
+			content: `[{"name":"r","metric":"Load","labels":{"1host":"h42"},"type":"gauge","op":">","threshold":1}]`,
+			wantErr: true,
+		},
+		{
+			name:    "unsupported operator",
+			content: `[{"name":"r","metric":"Alloc","type":"gauge","op":"~","threshold":1}]`,
+			wantErr: true,
+		},
+		{
+			name:    "missing threshold",
+			content: `[{"name":"r","metric":"Alloc","type":"gauge","op":">"}]`,
+			wantErr: true,
+		},
+		{
+			name:    "invalid for",
+			content: `[{"name":"r","metric":"Alloc","type":"gauge","op":">","threshold":1,"for":"soon"}]`,
+			wantErr: true,
+		},
+		{
+			name:    "duplicate names",
+			content: `[{"name":"r","metric":"Alloc","type":"gauge","op":">","threshold":1},{"name":"r","metric":"Sys","type":"gauge","op":">","threshold":1}]`,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "rules.json")
+			require.NoError(t, os.WriteFile(path, []byte(tt.content), 0644))
+
+			rules, err := LoadRules(path)
+			if tt.wantErr {
+				assert.ErrorIs(t, err, ErrInvalidRule)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, rules)
+		})
+	}
+}
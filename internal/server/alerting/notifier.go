@@ -0,0 +1,92 @@
+package alerting
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Notifier delivers alert state changes to an external destination.
+type Notifier interface {
+	Notify(ctx context.Context, alert Alert) error
+}
+
+// LogNotifier writes alert state changes to the log.
+type LogNotifier struct {
+	logger *zap.Logger
+}
+
+// NewLogNotifier creates a new LogNotifier with the provided logger.
+func NewLogNotifier(logger *zap.Logger) *LogNotifier {
+	return &LogNotifier{
+		logger: logger,
+	}
+}
+
+// Notify logs the alert at warn level when firing and at info level otherwise.
+func (ln *LogNotifier) Notify(_ context.Context, alert Alert) error {
+	fields := []zap.Field{
+		zap.String("rule", alert.Rule),
+		zap.String("metric", alert.Metric),
+		zap.String("type", alert.MType),
+		zap.String("state", string(alert.State)),
+		zap.Float64("value", alert.Value),
+		zap.String("op", alert.Op),
+		zap.Float64("threshold", alert.Threshold),
+	}
+
+	if alert.State == StateFiring {
+		ln.logger.Warn("alert firing", fields...)
+		return nil
+	}
+	ln.logger.Info("alert "+string(alert.State), fields...)
+	return nil
+}
+
+// WebhookNotifier posts alert state changes as JSON to a configured URL.
+type WebhookNotifier struct {
+	url    string
+	client *http.Client
+}
+
+// NewWebhookNotifier creates a new WebhookNotifier sending alerts to url.
+func NewWebhookNotifier(url string) *WebhookNotifier {
+	return &WebhookNotifier{
+		url: url,
+		client: &http.Client{
+			Timeout: 5 * time.Second,
+		},
+	}
+}
+
+// Notify sends the alert to the webhook and fails on non-2xx responses.
+func (wn *WebhookNotifier) Notify(ctx context.Context, alert Alert) error {
+	data, err := json.Marshal(alert)
+	if err != nil {
+		return fmt.Errorf("failed to marshal alert: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, wn.url, bytes.NewBuffer(data))
+	if err != nil {
+		return fmt.Errorf("failed to create webhook request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := wn.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send alert to webhook: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook returned non-success status: %d", resp.StatusCode)
+	}
+
+	return nil
+}
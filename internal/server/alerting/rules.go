@@ -0,0 +1,141 @@
+package alerting
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/Pro100x3mal/go-musthave-metrics/internal/server/models"
+)
+
+// Comparison operators supported by alert rules.
+const (
+	OpGreater      = ">"
+	OpGreaterEqual = ">="
+	OpLess         = "<"
+	OpLessEqual    = "<="
+	OpEqual        = "=="
+	OpNotEqual     = "!="
+)
+
+// ErrInvalidRule is returned when an alert rule is incomplete or uses unsupported values.
+var ErrInvalidRule = errors.New("invalid alert rule")
+
+// Rule describes a threshold condition on a single metric series.
+// An alert fires once the condition has held continuously for the For duration.
+type Rule struct {
+	Name      string
+	Metric    string
+	Labels    models.Labels
+	MType     string
+	Op        string
+	Threshold float64
+	For       time.Duration
+}
+
+type jsonRule struct {
+	Name      string            `json:"name"`
+	Metric    string            `json:"metric"`
+	Labels    map[string]string `json:"labels,omitempty"`
+	MType     string            `json:"type"`
+	Op        string            `json:"op"`
+	Threshold *float64          `json:"threshold"`
+	For       string            `json:"for"`
+}
+
+// LoadRules reads alert rules from a JSON file containing an array of rule objects, e.g.:
+//
+//	[{"name":"HighAlloc","metric":"Alloc","type":"gauge","op":">","threshold":1e9,"for":"1m"}]
+//
+// A rule may select a labeled series with an optional "labels" object, e.g. {"host":"h42"}.
+func LoadRules(path string) ([]Rule, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read alert rules file: %w", err)
+	}
+
+	var jsonRules []jsonRule
+	if err = json.Unmarshal(data, &jsonRules); err != nil {
+		return nil, fmt.Errorf("failed to parse alert rules: %w", err)
+	}
+
+	rules := make([]Rule, 0, len(jsonRules))
+	names := make(map[string]struct{}, len(jsonRules))
+	for i, jr := range jsonRules {
+		rule, err := jr.toRule()
+		if err != nil {
+			return nil, fmt.Errorf("rule #%d: %w", i+1, err)
+		}
+		if _, ok := names[rule.Name]; ok {
+			return nil, fmt.Errorf("rule #%d: %w: duplicate name %q", i+1, ErrInvalidRule, rule.Name)
+		}
+		names[rule.Name] = struct{}{}
+		rules = append(rules, rule)
+	}
+
+	return rules, nil
+}
+
+func (jr jsonRule) toRule() (Rule, error) {
+	if jr.Name == "" || jr.Metric == "" {
+		return Rule{}, fmt.Errorf("%w: name and metric are required", ErrInvalidRule)
+	}
+	if jr.MType != models.Gauge && jr.MType != models.Counter {
+		return Rule{}, fmt.Errorf("%w: unsupported metric type %q", ErrInvalidRule, jr.MType)
+	}
+	if err := models.ValidateMetricID(jr.Metric); err != nil {
+		return Rule{}, fmt.Errorf("%w: %w", ErrInvalidRule, err)
+	}
+	if err := models.Labels(jr.Labels).Validate(); err != nil {
+		return Rule{}, fmt.Errorf("%w: %w", ErrInvalidRule, err)
+	}
+	if jr.Threshold == nil {
+		return Rule{}, fmt.Errorf("%w: threshold is required", ErrInvalidRule)
+	}
+
+	switch jr.Op {
+	case OpGreater, OpGreaterEqual, OpLess, OpLessEqual, OpEqual, OpNotEqual:
+	default:
+		return Rule{}, fmt.Errorf("%w: unsupported comparison %q", ErrInvalidRule, jr.Op)
+	}
+
+	var forDuration time.Duration
+	if jr.For != "" {
+		d, err := time.ParseDuration(jr.For)
+		if err != nil || d < 0 {
+			return Rule{}, fmt.Errorf("%w: invalid for duration %q", ErrInvalidRule, jr.For)
+		}
+		forDuration = d
+	}
+
+	return Rule{
+		Name:      jr.Name,
+		Metric:    jr.Metric,
+		Labels:    jr.Labels,
+		MType:     jr.MType,
+		Op:        jr.Op,
+		Threshold: *jr.Threshold,
+		For:       forDuration,
+	}, nil
+}
+
+func (r Rule) matches(value float64) bool {
+	switch r.Op {
+	case OpGreater:
+		return value > r.Threshold
+	case OpGreaterEqual:
+		return value >= r.Threshold
+	case OpLess:
+		return value < r.Threshold
+	case OpLessEqual:
+		return value <= r.Threshold
+	case OpEqual:
+		return value == r.Threshold
+	case OpNotEqual:
+		return value != r.Threshold
+	default:
+		return false
+	}
+}
@@ -10,37 +10,94 @@ import (
 )
 
 type ServerConfig struct {
-	ServerAddr      string
-	LogLevel        string
-	StoreInterval   time.Duration
-	FileStoragePath string
-	IsRestore       bool
-	DatabaseDSN     string
-	Key             string
-	AuditFile       string
-	AuditURL        string
-	PrivateKeyPath  string
+	ServerAddr          string
+	LogLevel            string
+	StoreInterval       time.Duration
+	FileStoragePath     string
+	IsRestore           bool
+	DatabaseDSN         string
+	Key                 string
+	AuditFile           string
+	AuditURL            string
+	AuditSyslog         string
+	AuditSyslogFacility string
+	AuditSyslogAppName  string
+	PrivateKeyPath      string
+	AlertRulesFile      string
+	AlertWebhookURL     string
+	AlertInterval       time.Duration
+	StatsDAddr          string
+	StatsDFlush         time.Duration
+	GRPCAddr            string
+	AuditQueueSize      int
+	AuditWorkers        int
+	AuditDeadLetter     string
+	AuditHMACKey        string
+	AuditMaxSize        int
+	AuditMaxAge         time.Duration
+	AuditMaxBackups     int
+	AuditCompress       bool
+	AdminToken          string
+	AuditDBRetention    time.Duration
+	AuditFileFilter     string
+	AuditURLFilter      string
+	AuditSyslogFilter   string
+	AuditDBFilter       string
+	HistoryRetention    time.Duration
 }
 
 type JSONServerConfig struct {
-	ServerAddr      string `json:"address"`
-	LogLevel        string `json:"log_level"`
-	StoreInterval   string `json:"store_interval"`
-	FileStoragePath string `json:"file_storage_path"`
-	IsRestore       *bool  `json:"restore"`
-	DatabaseDSN     string `json:"database_dsn"`
-	Key             string `json:"signing_key"`
-	AuditFile       string `json:"audit_file"`
-	AuditURL        string `json:"audit_url"`
-	PrivateKeyPath  string `json:"crypto_key"`
+	ServerAddr          string `json:"address"`
+	LogLevel            string `json:"log_level"`
+	StoreInterval       string `json:"store_interval"`
+	FileStoragePath     string `json:"file_storage_path"`
+	IsRestore           *bool  `json:"restore"`
+	DatabaseDSN         string `json:"database_dsn"`
+	Key                 string `json:"signing_key"`
+	AuditFile           string `json:"audit_file"`
+	AuditURL            string `json:"audit_url"`
+	AuditSyslog         string `json:"audit_syslog"`
+	AuditSyslogFacility string `json:"audit_syslog_facility"`
+	AuditSyslogAppName  string `json:"audit_syslog_app_name"`
+	PrivateKeyPath      string `json:"crypto_key"`
+	AlertRulesFile      string `json:"alert_rules"`
+	AlertWebhookURL     string `json:"alert_webhook_url"`
+	AlertInterval       string `json:"alert_interval"`
+	StatsDAddr          string `json:"statsd_address"`
+	StatsDFlush         string `json:"statsd_flush_interval"`
+	GRPCAddr            string `json:"grpc_address"`
+	AuditQueueSize      *int   `json:"audit_queue_size"`
+	AuditWorkers        *int   `json:"audit_workers"`
+	AuditDeadLetter     string `json:"audit_dead_letter_file"`
+	AuditHMACKey        string `json:"audit_hmac_key"`
+	AuditMaxSize        *int   `json:"audit_max_size"`
+	AuditMaxAge         string `json:"audit_max_age"`
+	AuditMaxBackups     *int   `json:"audit_max_backups"`
+	AuditCompress       *bool  `json:"audit_compress"`
+	AdminToken          string `json:"admin_token"`
+	AuditDBRetention    string `json:"audit_db_retention"`
+	AuditFileFilter     string `json:"audit_file_filter"`
+	AuditURLFilter      string `json:"audit_url_filter"`
+	AuditSyslogFilter   string `json:"audit_syslog_filter"`
+	AuditDBFilter       string `json:"audit_db_filter"`
+	HistoryRetention    string `json:"history_retention"`
 }
 
 const (
-	defaultServerAddr    = "localhost:8080"
-	defaultLogLevel      = "info"
-	defaultStoreInterval = 300
-	defaultIsRestore     = false
-	defaultAuditFile     = "audit.json"
+	defaultServerAddr          = "localhost:8080"
+	defaultLogLevel            = "info"
+	defaultStoreInterval       = 300
+	defaultIsRestore           = false
+	defaultAuditFile           = "audit.json"
+	defaultAlertInterval       = 10 * time.Second
+	defaultStatsDFlush         = 10 * time.Second
+	defaultAuditQueueSize      = 1024
+	defaultAuditWorkers        = 2
+	defaultAuditDeadLetter     = "audit_dead_letter.json"
+	defaultAuditSyslogFacility = "local0"
+	defaultAuditSyslogAppName  = "metrics-server"
+	defaultAuditDBRetention    = 30 * 24 * time.Hour
+	defaultHistoryRetention    = 7 * 24 * time.Hour
 )
 
 func GetConfig() (*ServerConfig, error) {
@@ -54,19 +111,52 @@ func GetConfig() (*ServerConfig, error) {
 	cfg.LogLevel = defaultLogLevel
 	cfg.IsRestore = defaultIsRestore
 	cfg.AuditFile = defaultAuditFile
+	cfg.AlertInterval = defaultAlertInterval
+	cfg.StatsDFlush = defaultStatsDFlush
+	cfg.AuditQueueSize = defaultAuditQueueSize
+	cfg.AuditWorkers = defaultAuditWorkers
+	cfg.AuditDeadLetter = defaultAuditDeadLetter
+	cfg.AuditSyslogFacility = defaultAuditSyslogFacility
+	cfg.AuditSyslogAppName = defaultAuditSyslogAppName
+	cfg.AuditDBRetention = defaultAuditDBRetention
+	cfg.HistoryRetention = defaultHistoryRetention
 	storeInterval = defaultStoreInterval
 
 	var (
-		flagServerAddr      string
-		flagLogLevel        string
-		flagStoreInterval   int
-		flagFileStoragePath string
-		flagIsRestore       bool
-		flagDatabaseDSN     string
-		flagKey             string
-		flagAuditFile       string
-		flagAuditURL        string
-		flagPrivateKeyPath  string
+		flagServerAddr          string
+		flagLogLevel            string
+		flagStoreInterval       int
+		flagFileStoragePath     string
+		flagIsRestore           bool
+		flagDatabaseDSN         string
+		flagKey                 string
+		flagAuditFile           string
+		flagAuditURL            string
+		flagPrivateKeyPath      string
+		flagAlertRulesFile      string
+		flagAlertWebhookURL     string
+		flagAlertInterval       time.Duration
+		flagStatsDAddr          string
+		flagStatsDFlush         time.Duration
+		flagGRPCAddr            string
+		flagAuditQueueSize      int
+		flagAuditWorkers        int
+		flagAuditDeadLetter     string
+		flagAuditHMACKey        string
+		flagAuditMaxSize        int
+		flagAuditMaxAge         time.Duration
+		flagAuditMaxBackups     int
+		flagAuditCompress       bool
+		flagAdminToken          string
+		flagAuditSyslog         string
+		flagAuditSyslogFacility string
+		flagAuditSyslogAppName  string
+		flagAuditDBRetention    time.Duration
+		flagAuditFileFilter     string
+		flagAuditURLFilter      string
+		flagAuditSyslogFilter   string
+		flagAuditDBFilter       string
+		flagHistoryRetention    time.Duration
 	)
 
 	flag.StringVar(&flagServerAddr, "a", "", "address of HTTP server")
@@ -79,6 +169,30 @@ func GetConfig() (*ServerConfig, error) {
 	flag.StringVar(&flagAuditFile, "audit-file", "", "path to audit log file")
 	flag.StringVar(&flagAuditURL, "audit-url", "", "URL for audit log server")
 	flag.StringVar(&flagPrivateKeyPath, "crypto-key", "", "path to private key file")
+	flag.StringVar(&flagAlertRulesFile, "alert-rules", "", "path to JSON alert rules file")
+	flag.StringVar(&flagAlertWebhookURL, "alert-webhook", "", "URL for alert webhook notifications")
+	flag.DurationVar(&flagAlertInterval, "alert-interval", 0, "alert rules evaluation interval")
+	flag.StringVar(&flagStatsDAddr, "statsd-addr", "", "UDP address of StatsD listener (disabled if empty)")
+	flag.DurationVar(&flagStatsDFlush, "statsd-flush-interval", 0, "StatsD aggregation flush interval")
+	flag.StringVar(&flagGRPCAddr, "grpc-addr", "", "address of gRPC server (disabled if empty)")
+	flag.IntVar(&flagAuditQueueSize, "audit-queue-size", -1, "capacity of the audit event queue")
+	flag.IntVar(&flagAuditWorkers, "audit-workers", -1, "number of audit delivery workers")
+	flag.StringVar(&flagAuditDeadLetter, "audit-dead-letter", "", "path to file for audit events that could not be delivered")
+	flag.StringVar(&flagAuditHMACKey, "audit-hmac-key", "", "key for HMAC-SHA256 audit log chaining (plain SHA-256 if empty)")
+	flag.IntVar(&flagAuditMaxSize, "audit-max-size", -1, "rotate the audit file when it exceeds this many megabytes (0 disables)")
+	flag.DurationVar(&flagAuditMaxAge, "audit-max-age", 0, "rotate the audit file when it is older than this (0 disables)")
+	flag.IntVar(&flagAuditMaxBackups, "audit-max-backups", -1, "number of rotated audit files to keep (0 keeps all)")
+	flag.BoolVar(&flagAuditCompress, "audit-compress", false, "gzip rotated audit files")
+	flag.StringVar(&flagAdminToken, "admin-token", "", "bearer token for administrative endpoints such as /audit (disabled if empty)")
+	flag.StringVar(&flagAuditSyslog, "audit-syslog", "", "syslog address for audit events: unix:///dev/log, udp://host:514 or tcp://host:514")
+	flag.StringVar(&flagAuditSyslogFacility, "audit-syslog-facility", "", "syslog facility for audit events")
+	flag.StringVar(&flagAuditSyslogAppName, "audit-syslog-app-name", "", "syslog APP-NAME for audit events")
+	flag.DurationVar(&flagAuditDBRetention, "audit-db-retention", 0, "how long audit events are kept in the database")
+	flag.StringVar(&flagAuditFileFilter, "audit-file-filter", "", "filter for the file audit observer, e.g. metric=Poll*;exclude_ip=10.0.0.0/8;type=counter;sample=0.1")
+	flag.StringVar(&flagAuditURLFilter, "audit-url-filter", "", "filter for the HTTP audit observer, e.g. metric=Poll*;exclude_ip=10.0.0.0/8;type=counter;sample=0.1")
+	flag.StringVar(&flagAuditSyslogFilter, "audit-syslog-filter", "", "filter for the syslog audit observer, e.g. metric=Poll*;exclude_ip=10.0.0.0/8;type=counter;sample=0.1")
+	flag.StringVar(&flagAuditDBFilter, "audit-db-filter", "", "filter for the database audit observer, e.g. metric=Poll*;exclude_ip=10.0.0.0/8;type=counter;sample=0.1")
+	flag.DurationVar(&flagHistoryRetention, "history-retention", 0, "how long metric history samples are kept")
 	flag.StringVar(&configFilePath, "config", "", "path to JSON config file")
 	flag.StringVar(&configFilePath, "c", "", "path to JSON config file")
 	flag.Parse()
@@ -125,6 +239,78 @@ func GetConfig() (*ServerConfig, error) {
 	if flagPrivateKeyPath != "" {
 		cfg.PrivateKeyPath = flagPrivateKeyPath
 	}
+	if flagAlertRulesFile != "" {
+		cfg.AlertRulesFile = flagAlertRulesFile
+	}
+	if flagAlertWebhookURL != "" {
+		cfg.AlertWebhookURL = flagAlertWebhookURL
+	}
+	if flagAlertInterval > 0 {
+		cfg.AlertInterval = flagAlertInterval
+	}
+	if flagStatsDAddr != "" {
+		cfg.StatsDAddr = flagStatsDAddr
+	}
+	if flagStatsDFlush > 0 {
+		cfg.StatsDFlush = flagStatsDFlush
+	}
+	if flagGRPCAddr != "" {
+		cfg.GRPCAddr = flagGRPCAddr
+	}
+	if flagAuditQueueSize >= 0 {
+		cfg.AuditQueueSize = flagAuditQueueSize
+	}
+	if flagAuditWorkers >= 0 {
+		cfg.AuditWorkers = flagAuditWorkers
+	}
+	if flagAuditDeadLetter != "" {
+		cfg.AuditDeadLetter = flagAuditDeadLetter
+	}
+	if flagAuditHMACKey != "" {
+		cfg.AuditHMACKey = flagAuditHMACKey
+	}
+	if flagAuditMaxSize >= 0 {
+		cfg.AuditMaxSize = flagAuditMaxSize
+	}
+	if flagAuditMaxAge > 0 {
+		cfg.AuditMaxAge = flagAuditMaxAge
+	}
+	if flagAuditMaxBackups >= 0 {
+		cfg.AuditMaxBackups = flagAuditMaxBackups
+	}
+	if flagAuditCompress {
+		cfg.AuditCompress = flagAuditCompress
+	}
+	if flagAdminToken != "" {
+		cfg.AdminToken = flagAdminToken
+	}
+	if flagAuditSyslog != "" {
+		cfg.AuditSyslog = flagAuditSyslog
+	}
+	if flagAuditSyslogFacility != "" {
+		cfg.AuditSyslogFacility = flagAuditSyslogFacility
+	}
+	if flagAuditSyslogAppName != "" {
+		cfg.AuditSyslogAppName = flagAuditSyslogAppName
+	}
+	if flagAuditDBRetention > 0 {
+		cfg.AuditDBRetention = flagAuditDBRetention
+	}
+	if flagAuditFileFilter != "" {
+		cfg.AuditFileFilter = flagAuditFileFilter
+	}
+	if flagAuditURLFilter != "" {
+		cfg.AuditURLFilter = flagAuditURLFilter
+	}
+	if flagAuditSyslogFilter != "" {
+		cfg.AuditSyslogFilter = flagAuditSyslogFilter
+	}
+	if flagAuditDBFilter != "" {
+		cfg.AuditDBFilter = flagAuditDBFilter
+	}
+	if flagHistoryRetention > 0 {
+		cfg.HistoryRetention = flagHistoryRetention
+	}
 
 	if envServerAddr, ok := os.LookupEnv("ADDRESS"); ok && envServerAddr != "" {
 		cfg.ServerAddr = envServerAddr
@@ -177,6 +363,157 @@ func GetConfig() (*ServerConfig, error) {
 		cfg.PrivateKeyPath = envPrivateKeyPath
 	}
 
+	if envAlertRulesFile, ok := os.LookupEnv("ALERT_RULES"); ok && envAlertRulesFile != "" {
+		cfg.AlertRulesFile = envAlertRulesFile
+	}
+
+	if envAlertWebhookURL, ok := os.LookupEnv("ALERT_WEBHOOK_URL"); ok && envAlertWebhookURL != "" {
+		cfg.AlertWebhookURL = envAlertWebhookURL
+	}
+
+	if envAlertInterval, ok := os.LookupEnv("ALERT_INTERVAL"); ok && envAlertInterval != "" {
+		duration, err := time.ParseDuration(envAlertInterval)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse ALERT_INTERVAL value %q to duration: %w", envAlertInterval, err)
+		}
+		if duration <= 0 {
+			return nil, fmt.Errorf("ALERT_INTERVAL value %q must be greater than 0", envAlertInterval)
+		}
+		cfg.AlertInterval = duration
+	}
+
+	if envStatsDAddr, ok := os.LookupEnv("STATSD_ADDRESS"); ok && envStatsDAddr != "" {
+		cfg.StatsDAddr = envStatsDAddr
+	}
+
+	if envStatsDFlush, ok := os.LookupEnv("STATSD_FLUSH_INTERVAL"); ok && envStatsDFlush != "" {
+		duration, err := time.ParseDuration(envStatsDFlush)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse STATSD_FLUSH_INTERVAL value %q to duration: %w", envStatsDFlush, err)
+		}
+		if duration <= 0 {
+			return nil, fmt.Errorf("STATSD_FLUSH_INTERVAL value %q must be greater than 0", envStatsDFlush)
+		}
+		cfg.StatsDFlush = duration
+	}
+
+	if envGRPCAddr, ok := os.LookupEnv("GRPC_ADDRESS"); ok && envGRPCAddr != "" {
+		cfg.GRPCAddr = envGRPCAddr
+	}
+
+	if envAuditQueueSize, ok := os.LookupEnv("AUDIT_QUEUE_SIZE"); ok && envAuditQueueSize != "" {
+		value, err := strconv.Atoi(envAuditQueueSize)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse AUDIT_QUEUE_SIZE value %q to integer: %w", envAuditQueueSize, err)
+		}
+		cfg.AuditQueueSize = value
+	}
+
+	if envAuditWorkers, ok := os.LookupEnv("AUDIT_WORKERS"); ok && envAuditWorkers != "" {
+		value, err := strconv.Atoi(envAuditWorkers)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse AUDIT_WORKERS value %q to integer: %w", envAuditWorkers, err)
+		}
+		cfg.AuditWorkers = value
+	}
+
+	if envAuditDeadLetter, ok := os.LookupEnv("AUDIT_DEAD_LETTER_FILE"); ok && envAuditDeadLetter != "" {
+		cfg.AuditDeadLetter = envAuditDeadLetter
+	}
+
+	if envAuditHMACKey, ok := os.LookupEnv("AUDIT_HMAC_KEY"); ok && envAuditHMACKey != "" {
+		cfg.AuditHMACKey = envAuditHMACKey
+	}
+
+	if envAuditMaxSize, ok := os.LookupEnv("AUDIT_MAX_SIZE"); ok && envAuditMaxSize != "" {
+		value, err := strconv.Atoi(envAuditMaxSize)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse AUDIT_MAX_SIZE value %q to integer: %w", envAuditMaxSize, err)
+		}
+		cfg.AuditMaxSize = value
+	}
+
+	if envAuditMaxAge, ok := os.LookupEnv("AUDIT_MAX_AGE"); ok && envAuditMaxAge != "" {
+		duration, err := time.ParseDuration(envAuditMaxAge)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse AUDIT_MAX_AGE value %q to duration: %w", envAuditMaxAge, err)
+		}
+		if duration <= 0 {
+			return nil, fmt.Errorf("AUDIT_MAX_AGE value %q must be greater than 0", envAuditMaxAge)
+		}
+		cfg.AuditMaxAge = duration
+	}
+
+	if envAuditMaxBackups, ok := os.LookupEnv("AUDIT_MAX_BACKUPS"); ok && envAuditMaxBackups != "" {
+		value, err := strconv.Atoi(envAuditMaxBackups)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse AUDIT_MAX_BACKUPS value %q to integer: %w", envAuditMaxBackups, err)
+		}
+		cfg.AuditMaxBackups = value
+	}
+
+	if envAuditCompress, ok := os.LookupEnv("AUDIT_COMPRESS"); ok && envAuditCompress != "" {
+		value, err := strconv.ParseBool(envAuditCompress)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse AUDIT_COMPRESS value %q to boolean: %w", envAuditCompress, err)
+		}
+		cfg.AuditCompress = value
+	}
+
+	if envAdminToken, ok := os.LookupEnv("ADMIN_TOKEN"); ok && envAdminToken != "" {
+		cfg.AdminToken = envAdminToken
+	}
+
+	if envAuditSyslog, ok := os.LookupEnv("AUDIT_SYSLOG"); ok && envAuditSyslog != "" {
+		cfg.AuditSyslog = envAuditSyslog
+	}
+
+	if envAuditSyslogFacility, ok := os.LookupEnv("AUDIT_SYSLOG_FACILITY"); ok && envAuditSyslogFacility != "" {
+		cfg.AuditSyslogFacility = envAuditSyslogFacility
+	}
+
+	if envAuditSyslogAppName, ok := os.LookupEnv("AUDIT_SYSLOG_APP_NAME"); ok && envAuditSyslogAppName != "" {
+		cfg.AuditSyslogAppName = envAuditSyslogAppName
+	}
+
+	if envAuditDBRetention, ok := os.LookupEnv("AUDIT_DB_RETENTION"); ok && envAuditDBRetention != "" {
+		duration, err := time.ParseDuration(envAuditDBRetention)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse AUDIT_DB_RETENTION value %q to duration: %w", envAuditDBRetention, err)
+		}
+		if duration <= 0 {
+			return nil, fmt.Errorf("AUDIT_DB_RETENTION value %q must be greater than 0", envAuditDBRetention)
+		}
+		cfg.AuditDBRetention = duration
+	}
+
+	if envAuditFileFilter, ok := os.LookupEnv("AUDIT_FILE_FILTER"); ok && envAuditFileFilter != "" {
+		cfg.AuditFileFilter = envAuditFileFilter
+	}
+
+	if envAuditURLFilter, ok := os.LookupEnv("AUDIT_URL_FILTER"); ok && envAuditURLFilter != "" {
+		cfg.AuditURLFilter = envAuditURLFilter
+	}
+
+	if envAuditSyslogFilter, ok := os.LookupEnv("AUDIT_SYSLOG_FILTER"); ok && envAuditSyslogFilter != "" {
+		cfg.AuditSyslogFilter = envAuditSyslogFilter
+	}
+
+	if envAuditDBFilter, ok := os.LookupEnv("AUDIT_DB_FILTER"); ok && envAuditDBFilter != "" {
+		cfg.AuditDBFilter = envAuditDBFilter
+	}
+
+	if envHistoryRetention, ok := os.LookupEnv("HISTORY_RETENTION"); ok && envHistoryRetention != "" {
+		duration, err := time.ParseDuration(envHistoryRetention)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse HISTORY_RETENTION value %q to duration: %w", envHistoryRetention, err)
+		}
+		if duration <= 0 {
+			return nil, fmt.Errorf("HISTORY_RETENTION value %q must be greater than 0", envHistoryRetention)
+		}
+		cfg.HistoryRetention = duration
+	}
+
 	cfg.StoreInterval = time.Duration(storeInterval) * time.Second
 
 	return &cfg, nil
@@ -228,6 +565,113 @@ func loadJSONConfig(path string, cfg *ServerConfig, storeInterval *int) error {
 	if jsonCfg.AuditURL != "" {
 		cfg.AuditURL = jsonCfg.AuditURL
 	}
+	if jsonCfg.AlertRulesFile != "" {
+		cfg.AlertRulesFile = jsonCfg.AlertRulesFile
+	}
+	if jsonCfg.AlertWebhookURL != "" {
+		cfg.AlertWebhookURL = jsonCfg.AlertWebhookURL
+	}
+	if jsonCfg.AlertInterval != "" {
+		duration, err := time.ParseDuration(jsonCfg.AlertInterval)
+		if err != nil {
+			return fmt.Errorf("failed to parse alert_interval: %w", err)
+		}
+		if duration <= 0 {
+			return fmt.Errorf("alert_interval value %q must be greater than 0", jsonCfg.AlertInterval)
+		}
+		cfg.AlertInterval = duration
+	}
+	if jsonCfg.StatsDAddr != "" {
+		cfg.StatsDAddr = jsonCfg.StatsDAddr
+	}
+	if jsonCfg.StatsDFlush != "" {
+		duration, err := time.ParseDuration(jsonCfg.StatsDFlush)
+		if err != nil {
+			return fmt.Errorf("failed to parse statsd_flush_interval: %w", err)
+		}
+		if duration <= 0 {
+			return fmt.Errorf("statsd_flush_interval value %q must be greater than 0", jsonCfg.StatsDFlush)
+		}
+		cfg.StatsDFlush = duration
+	}
+	if jsonCfg.GRPCAddr != "" {
+		cfg.GRPCAddr = jsonCfg.GRPCAddr
+	}
+	if jsonCfg.AuditQueueSize != nil {
+		cfg.AuditQueueSize = *jsonCfg.AuditQueueSize
+	}
+	if jsonCfg.AuditWorkers != nil {
+		cfg.AuditWorkers = *jsonCfg.AuditWorkers
+	}
+	if jsonCfg.AuditDeadLetter != "" {
+		cfg.AuditDeadLetter = jsonCfg.AuditDeadLetter
+	}
+	if jsonCfg.AuditHMACKey != "" {
+		cfg.AuditHMACKey = jsonCfg.AuditHMACKey
+	}
+	if jsonCfg.AuditMaxSize != nil {
+		cfg.AuditMaxSize = *jsonCfg.AuditMaxSize
+	}
+	if jsonCfg.AuditMaxAge != "" {
+		duration, err := time.ParseDuration(jsonCfg.AuditMaxAge)
+		if err != nil {
+			return fmt.Errorf("failed to parse audit_max_age: %w", err)
+		}
+		if duration <= 0 {
+			return fmt.Errorf("audit_max_age value %q must be greater than 0", jsonCfg.AuditMaxAge)
+		}
+		cfg.AuditMaxAge = duration
+	}
+	if jsonCfg.AuditMaxBackups != nil {
+		cfg.AuditMaxBackups = *jsonCfg.AuditMaxBackups
+	}
+	if jsonCfg.AuditCompress != nil {
+		cfg.AuditCompress = *jsonCfg.AuditCompress
+	}
+	if jsonCfg.AdminToken != "" {
+		cfg.AdminToken = jsonCfg.AdminToken
+	}
+	if jsonCfg.AuditSyslog != "" {
+		cfg.AuditSyslog = jsonCfg.AuditSyslog
+	}
+	if jsonCfg.AuditSyslogFacility != "" {
+		cfg.AuditSyslogFacility = jsonCfg.AuditSyslogFacility
+	}
+	if jsonCfg.AuditSyslogAppName != "" {
+		cfg.AuditSyslogAppName = jsonCfg.AuditSyslogAppName
+	}
+	if jsonCfg.AuditDBRetention != "" {
+		duration, err := time.ParseDuration(jsonCfg.AuditDBRetention)
+		if err != nil {
+			return fmt.Errorf("failed to parse audit_db_retention: %w", err)
+		}
+		if duration <= 0 {
+			return fmt.Errorf("audit_db_retention value %q must be greater than 0", jsonCfg.AuditDBRetention)
+		}
+		cfg.AuditDBRetention = duration
+	}
+	if jsonCfg.AuditFileFilter != "" {
+		cfg.AuditFileFilter = jsonCfg.AuditFileFilter
+	}
+	if jsonCfg.AuditURLFilter != "" {
+		cfg.AuditURLFilter = jsonCfg.AuditURLFilter
+	}
+	if jsonCfg.AuditSyslogFilter != "" {
+		cfg.AuditSyslogFilter = jsonCfg.AuditSyslogFilter
+	}
+	if jsonCfg.AuditDBFilter != "" {
+		cfg.AuditDBFilter = jsonCfg.AuditDBFilter
+	}
+	if jsonCfg.HistoryRetention != "" {
+		duration, err := time.ParseDuration(jsonCfg.HistoryRetention)
+		if err != nil {
+			return fmt.Errorf("failed to parse history_retention: %w", err)
+		}
+		if duration <= 0 {
+			return fmt.Errorf("history_retention value %q must be greater than 0", jsonCfg.HistoryRetention)
+		}
+		cfg.HistoryRetention = duration
+	}
 
 	return nil
 }
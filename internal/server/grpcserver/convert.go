@@ -0,0 +1,110 @@
+package grpcserver
+
+import (
+	"fmt"
+
+	"github.com/Pro100x3mal/go-musthave-metrics/internal/server/models"
+	"github.com/Pro100x3mal/go-musthave-metrics/pkg/metricspb"
+)
+
+var errMissingFields = fmt.Errorf("%w: missing required metric fields", models.ErrInvalidMetricValue)
+
+func typeFromProto(t metricspb.MetricType) (string, error) {
+	switch t {
+	case metricspb.MetricType_METRIC_TYPE_GAUGE:
+		return models.Gauge, nil
+	case metricspb.MetricType_METRIC_TYPE_COUNTER:
+		return models.Counter, nil
+	case metricspb.MetricType_METRIC_TYPE_HISTOGRAM:
+		return models.Histogram, nil
+	case metricspb.MetricType_METRIC_TYPE_UNSPECIFIED:
+		return "", errMissingFields
+	default:
+		return "", models.ErrUnsupportedMetricType
+	}
+}
+
+func typeToProto(t string) metricspb.MetricType {
+	switch t {
+	case models.Gauge:
+		return metricspb.MetricType_METRIC_TYPE_GAUGE
+	case models.Counter:
+		return metricspb.MetricType_METRIC_TYPE_COUNTER
+	case models.Histogram:
+		return metricspb.MetricType_METRIC_TYPE_HISTOGRAM
+	default:
+		return metricspb.MetricType_METRIC_TYPE_UNSPECIFIED
+	}
+}
+
+func labelsFromProto(labels map[string]string) models.Labels {
+	if len(labels) == 0 {
+		return nil
+	}
+	return models.Labels(labels)
+}
+
+func metricFromProto(m *metricspb.Metric) (models.Metrics, error) {
+	if m.GetId() == "" {
+		return models.Metrics{}, errMissingFields
+	}
+	mType, err := typeFromProto(m.GetType())
+	if err != nil {
+		return models.Metrics{}, err
+	}
+
+	metric := models.Metrics{ID: m.GetId(), MType: mType, Labels: labelsFromProto(m.GetLabels())}
+	switch mType {
+	case models.Gauge:
+		value := m.GetValue()
+		metric.Value = &value
+	case models.Counter:
+		delta := m.GetDelta()
+		metric.Delta = &delta
+	case models.Histogram:
+		if h := m.GetHistogram(); h != nil {
+			metric.Histogram = &models.HistogramValue{
+				Bounds: h.GetBounds(),
+				Counts: h.GetCounts(),
+				Sum:    h.GetSum(),
+				Count:  h.GetCount(),
+			}
+		}
+	}
+	return metric, nil
+}
+
+func metricsFromProto(ms []*metricspb.Metric) ([]models.Metrics, error) {
+	metrics := make([]models.Metrics, 0, len(ms))
+	for _, m := range ms {
+		metric, err := metricFromProto(m)
+		if err != nil {
+			return nil, err
+		}
+		metrics = append(metrics, metric)
+	}
+	return metrics, nil
+}
+
+func metricToProto(m *models.Metrics) *metricspb.Metric {
+	pm := &metricspb.Metric{
+		Id:     m.ID,
+		Type:   typeToProto(m.MType),
+		Labels: m.Labels,
+	}
+	if m.Delta != nil {
+		pm.Delta = *m.Delta
+	}
+	if m.Value != nil {
+		pm.Value = *m.Value
+	}
+	if m.Histogram != nil {
+		pm.Histogram = &metricspb.Histogram{
+			Bounds: m.Histogram.Bounds,
+			Counts: m.Histogram.Counts,
+			Sum:    m.Histogram.Sum,
+			Count:  m.Histogram.Count,
+		}
+	}
+	return pm
+}
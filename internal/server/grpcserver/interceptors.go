@@ -0,0 +1,186 @@
+package grpcserver
+
+import (
+	"context"
+	"crypto/rsa"
+
+	"github.com/Pro100x3mal/go-musthave-metrics/internal/server/infrastructure/audit"
+	"github.com/Pro100x3mal/go-musthave-metrics/pkg/crypto"
+	"github.com/Pro100x3mal/go-musthave-metrics/pkg/metricspb"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+// signInterceptor is the gRPC counterpart of middlewares.SignHandler.
+// Unary requests carrying the hashsha256 metadata and streamed batches carrying hash_sha256 are verified;
+// responses are signed with the hashsha256 header metadata.
+type signInterceptor struct {
+	logger *zap.Logger
+	key    string
+	keyID  string
+}
+
+func (si *signInterceptor) unary(ctx context.Context, req any, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if sig := md.Get(metricspb.SignatureMetadataKey); len(sig) > 0 && sig[0] != "" {
+			if err := si.verify(req, sig[0]); err != nil {
+				return nil, err
+			}
+			ctx = audit.WithSigningKeyID(ctx, si.keyID)
+		}
+	}
+
+	resp, err := handler(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	if err = si.signResponse(ctx, resp); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+func (si *signInterceptor) stream(srv any, ss grpc.ServerStream, _ *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	return handler(srv, &signServerStream{ServerStream: ss, si: si})
+}
+
+func (si *signInterceptor) verify(m any, signature string) error {
+	msg, ok := m.(proto.Message)
+	if !ok {
+		return status.Error(codes.Internal, "unexpected message type")
+	}
+
+	valid, err := metricspb.Verify(msg, si.key, signature)
+	if err != nil {
+		si.logger.Error("failed to verify signature", zap.Error(err))
+		return status.Error(codes.InvalidArgument, "invalid signature")
+	}
+	if !valid {
+		return status.Error(codes.InvalidArgument, "invalid signature")
+	}
+	return nil
+}
+
+func (si *signInterceptor) signResponse(ctx context.Context, resp any) error {
+	msg, ok := resp.(proto.Message)
+	if !ok {
+		return nil
+	}
+
+	sig, err := metricspb.Sign(msg, si.key)
+	if err != nil {
+		si.logger.Error("failed to sign response", zap.Error(err))
+		return status.Error(codes.Internal, "failed to sign response")
+	}
+	return grpc.SetHeader(ctx, metadata.Pairs(metricspb.SignatureMetadataKey, sig))
+}
+
+// signServerStream verifies streamed batches. Its context carries the signing key ID while the last received batch was signed.
+type signServerStream struct {
+	grpc.ServerStream
+	si     *signInterceptor
+	signed bool
+}
+
+func (s *signServerStream) Context() context.Context {
+	if s.signed {
+		return audit.WithSigningKeyID(s.ServerStream.Context(), s.si.keyID)
+	}
+	return s.ServerStream.Context()
+}
+
+func (s *signServerStream) RecvMsg(m any) error {
+	s.signed = false
+	if err := s.ServerStream.RecvMsg(m); err != nil {
+		return err
+	}
+
+	req, ok := m.(*metricspb.UpdateBatchRequest)
+	if !ok || req.GetHashSha256() == "" {
+		return nil
+	}
+	sig := req.GetHashSha256()
+	req.HashSha256 = ""
+	if err := s.si.verify(req, sig); err != nil {
+		return err
+	}
+	s.signed = true
+	return nil
+}
+
+func (s *signServerStream) SendMsg(m any) error {
+	if msg, ok := m.(proto.Message); ok {
+		sig, err := metricspb.Sign(msg, s.si.key)
+		if err != nil {
+			s.si.logger.Error("failed to sign response", zap.Error(err))
+			return status.Error(codes.Internal, "failed to sign response")
+		}
+		if err = s.SetHeader(metadata.Pairs(metricspb.SignatureMetadataKey, sig)); err != nil {
+			return err
+		}
+	}
+	return s.ServerStream.SendMsg(m)
+}
+
+// decryptInterceptor is the gRPC counterpart of middlewares.DecryptHandler.
+// When a private key is configured, batches must carry their metrics in encrypted_metrics.
+type decryptInterceptor struct {
+	logger     *zap.Logger
+	privateKey *rsa.PrivateKey
+}
+
+func (di *decryptInterceptor) unary(ctx context.Context, req any, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	if batch, ok := req.(*metricspb.UpdateBatchRequest); ok {
+		if err := di.decrypt(batch); err != nil {
+			return nil, err
+		}
+	}
+	return handler(ctx, req)
+}
+
+func (di *decryptInterceptor) stream(srv any, ss grpc.ServerStream, _ *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	return handler(srv, &decryptServerStream{ServerStream: ss, di: di})
+}
+
+func (di *decryptInterceptor) decrypt(req *metricspb.UpdateBatchRequest) error {
+	if len(req.GetEncryptedMetrics()) == 0 {
+		if len(req.GetMetrics()) > 0 {
+			return status.Error(codes.InvalidArgument, "metrics must be encrypted")
+		}
+		return nil
+	}
+
+	data, err := crypto.Decrypt(di.privateKey, req.GetEncryptedMetrics())
+	if err != nil {
+		di.logger.Error("failed to decrypt request", zap.Error(err))
+		return status.Error(codes.InvalidArgument, "failed to decrypt request")
+	}
+
+	var inner metricspb.UpdateBatchRequest
+	if err = proto.Unmarshal(data, &inner); err != nil {
+		di.logger.Error("failed to decode decrypted request", zap.Error(err))
+		return status.Error(codes.InvalidArgument, "failed to decrypt request")
+	}
+	req.Metrics = inner.GetMetrics()
+	req.EncryptedMetrics = nil
+	return nil
+}
+
+type decryptServerStream struct {
+	grpc.ServerStream
+	di *decryptInterceptor
+}
+
+func (s *decryptServerStream) RecvMsg(m any) error {
+	if err := s.ServerStream.RecvMsg(m); err != nil {
+		return err
+	}
+	if req, ok := m.(*metricspb.UpdateBatchRequest); ok {
+		return s.di.decrypt(req)
+	}
+	return nil
+}
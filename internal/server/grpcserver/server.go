@@ -0,0 +1,103 @@
+// Package grpcserver serves the Metrics gRPC API defined in api/metrics.proto next to the HTTP API.
+package grpcserver
+
+import (
+	"context"
+	"crypto/rsa"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/Pro100x3mal/go-musthave-metrics/internal/server/handlers"
+	"github.com/Pro100x3mal/go-musthave-metrics/internal/server/infrastructure/audit"
+	"github.com/Pro100x3mal/go-musthave-metrics/pkg/metricspb"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+)
+
+const shutdownTimeout = 5 * time.Second
+
+// Server serves the Metrics gRPC service backed by the same metrics service as the HTTP handlers.
+type Server struct {
+	srv    *grpc.Server
+	lis    net.Listener
+	logger *zap.Logger
+}
+
+// NewServer listens on addr and registers the Metrics service.
+// A non-empty key enables request verification and response signing; a non-nil privateKey requires encrypted batches.
+// The publisher may be nil to disable auditing.
+func NewServer(addr string, service handlers.MetricsServiceInterface, publisher audit.Publisher, key string, privateKey *rsa.PrivateKey, logger *zap.Logger) (*Server, error) {
+	lis, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on %q: %w", addr, err)
+	}
+
+	var (
+		unary  []grpc.UnaryServerInterceptor
+		stream []grpc.StreamServerInterceptor
+	)
+	if key != "" {
+		si := &signInterceptor{logger: logger, key: key, keyID: audit.KeyID(key)}
+		unary = append(unary, si.unary)
+		stream = append(stream, si.stream)
+	}
+	if privateKey != nil {
+		di := &decryptInterceptor{logger: logger, privateKey: privateKey}
+		unary = append(unary, di.unary)
+		stream = append(stream, di.stream)
+	}
+
+	srv := grpc.NewServer(grpc.ChainUnaryInterceptor(unary...), grpc.ChainStreamInterceptor(stream...))
+	metricspb.RegisterMetricsServer(srv, &metricsServer{
+		service:   service,
+		publisher: publisher,
+		logger:    logger,
+	})
+
+	return &Server{
+		srv:    srv,
+		lis:    lis,
+		logger: logger,
+	}, nil
+}
+
+// Addr returns the local address the server listens on.
+func (s *Server) Addr() net.Addr {
+	return s.lis.Addr()
+}
+
+// Run serves requests until ctx is cancelled or serving fails.
+// On cancellation in-flight calls are given a few seconds to finish before the server is stopped.
+func (s *Server) Run(ctx context.Context) error {
+	s.logger.Info("starting gRPC server...", zap.String("address", s.lis.Addr().String()))
+
+	serverErrCh := make(chan error, 1)
+	go func() {
+		serverErrCh <- s.srv.Serve(s.lis)
+	}()
+
+	select {
+	case <-ctx.Done():
+		s.logger.Info("gRPC server is shutting down...")
+
+		stopped := make(chan struct{})
+		go func() {
+			s.srv.GracefulStop()
+			close(stopped)
+		}()
+
+		select {
+		case <-stopped:
+		case <-time.After(shutdownTimeout):
+			s.logger.Warn("graceful shutdown timed out, stopping gRPC server")
+			s.srv.Stop()
+		}
+
+		s.logger.Info("gRPC server shutdown complete")
+		return nil
+	case err := <-serverErrCh:
+		s.logger.Error("unexpected gRPC server error", zap.Error(err))
+		return err
+	}
+}
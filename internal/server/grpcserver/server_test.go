@@ -0,0 +1,253 @@
+package grpcserver
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"testing"
+	"time"
+
+	mocksvc "github.com/Pro100x3mal/go-musthave-metrics/internal/server/handlers/mocks"
+	"github.com/Pro100x3mal/go-musthave-metrics/internal/server/models"
+	"github.com/Pro100x3mal/go-musthave-metrics/pkg/crypto"
+	"github.com/Pro100x3mal/go-musthave-metrics/pkg/metricspb"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+func startTestServer(t *testing.T, key string, privateKey *rsa.PrivateKey, setupMock func(*mocksvc.MockMetricsServiceInterface)) metricspb.MetricsClient {
+	t.Helper()
+
+	ctrl := gomock.NewController(t)
+	mockService := mocksvc.NewMockMetricsServiceInterface(ctrl)
+	if setupMock != nil {
+		setupMock(mockService)
+	}
+
+	srv, err := NewServer("127.0.0.1:0", mockService, nil, key, privateKey, zap.NewNop())
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		_ = srv.Run(ctx)
+	}()
+
+	conn, err := grpc.NewClient(srv.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+
+	t.Cleanup(func() {
+		_ = conn.Close()
+		cancel()
+		<-done
+	})
+	return metricspb.NewMetricsClient(conn)
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
+
+func TestServer_UpdateBatch(t *testing.T) {
+	tests := []struct {
+		name         string
+		req          *metricspb.UpdateBatchRequest
+		setupMock    func(*mocksvc.MockMetricsServiceInterface)
+		wantCode     codes.Code
+		wantReplayed bool
+	}{
+		{
+			name: "success",
+			req: &metricspb.UpdateBatchRequest{Metrics: []*metricspb.Metric{
+				{Id: "Alloc", Type: metricspb.MetricType_METRIC_TYPE_GAUGE, Value: 1.5, Labels: map[string]string{"host": "a"}},
+				{Id: "PollCount", Type: metricspb.MetricType_METRIC_TYPE_COUNTER, Delta: 3},
+			}},
+			setupMock: func(m *mocksvc.MockMetricsServiceInterface) {
+				m.EXPECT().UpdateJSONMetrics(gomock.Any(), []models.Metrics{
+					{ID: "Alloc", MType: models.Gauge, Value: ptr(1.5), Labels: models.Labels{"host": "a"}},
+					{ID: "PollCount", MType: models.Counter, Delta: ptr(int64(3))},
+				}).Return(nil)
+			},
+			wantCode: codes.OK,
+		},
+		{
+			name: "idempotent replay",
+			req: &metricspb.UpdateBatchRequest{
+				Metrics:        []*metricspb.Metric{{Id: "PollCount", Type: metricspb.MetricType_METRIC_TYPE_COUNTER, Delta: 1}},
+				IdempotencyKey: "key-1",
+			},
+			setupMock: func(m *mocksvc.MockMetricsServiceInterface) {
+				m.EXPECT().UpdateJSONMetricsIdempotent(gomock.Any(), "key-1", gomock.Any()).Return(models.ErrDuplicateIdempotencyKey)
+			},
+			wantCode:     codes.OK,
+			wantReplayed: true,
+		},
+		{
+			name:     "missing type",
+			req:      &metricspb.UpdateBatchRequest{Metrics: []*metricspb.Metric{{Id: "Alloc"}}},
+			wantCode: codes.InvalidArgument,
+		},
+		{
+			name: "histogram bounds mismatch",
+			req: &metricspb.UpdateBatchRequest{Metrics: []*metricspb.Metric{{
+				Id:        "latency",
+				Type:      metricspb.MetricType_METRIC_TYPE_HISTOGRAM,
+				Histogram: &metricspb.Histogram{Bounds: []float64{1}, Counts: []uint64{1, 0}, Sum: 0.5, Count: 1},
+			}}},
+			setupMock: func(m *mocksvc.MockMetricsServiceInterface) {
+				m.EXPECT().UpdateJSONMetrics(gomock.Any(), gomock.Any()).Return(models.ErrHistogramBoundsMismatch)
+			},
+			wantCode: codes.FailedPrecondition,
+		},
+		{
+			name:     "encrypted without private key",
+			req:      &metricspb.UpdateBatchRequest{EncryptedMetrics: []byte("sealed")},
+			wantCode: codes.InvalidArgument,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := startTestServer(t, "", nil, tt.setupMock)
+
+			resp, err := client.UpdateBatch(context.Background(), tt.req)
+			assert.Equal(t, tt.wantCode, status.Code(err))
+			if tt.wantCode == codes.OK {
+				assert.Equal(t, tt.wantReplayed, resp.GetReplayed())
+			}
+		})
+	}
+}
+
+func TestServer_GetValueAndList(t *testing.T) {
+	client := startTestServer(t, "", nil, func(m *mocksvc.MockMetricsServiceInterface) {
+		m.EXPECT().GetJSONMetricValue(gomock.Any(), &models.Metrics{ID: "Alloc", MType: models.Gauge}).
+			Return(&models.Metrics{ID: "Alloc", MType: models.Gauge, Value: ptr(2.5)}, nil)
+		m.EXPECT().GetJSONMetricValue(gomock.Any(), gomock.Any()).Return(nil, models.ErrMetricNotFound)
+		m.EXPECT().ListMetrics(gomock.Any(), models.Labels{"host": "a"}).Return([]models.Metrics{
+			{ID: "PollCount", MType: models.Counter, Delta: ptr(int64(7)), Labels: models.Labels{"host": "a"}},
+		}, nil)
+	})
+	ctx := context.Background()
+
+	resp, err := client.GetValue(ctx, &metricspb.GetValueRequest{Id: "Alloc", Type: metricspb.MetricType_METRIC_TYPE_GAUGE})
+	require.NoError(t, err)
+	assert.Equal(t, 2.5, resp.GetMetric().GetValue())
+
+	_, err = client.GetValue(ctx, &metricspb.GetValueRequest{Id: "missing", Type: metricspb.MetricType_METRIC_TYPE_COUNTER})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+
+	list, err := client.List(ctx, &metricspb.ListRequest{Matchers: map[string]string{"host": "a"}})
+	require.NoError(t, err)
+	require.Len(t, list.GetMetrics(), 1)
+	assert.Equal(t, "PollCount", list.GetMetrics()[0].GetId())
+	assert.Equal(t, metricspb.MetricType_METRIC_TYPE_COUNTER, list.GetMetrics()[0].GetType())
+	assert.Equal(t, int64(7), list.GetMetrics()[0].GetDelta())
+}
+
+func TestServer_StreamUpdates(t *testing.T) {
+	client := startTestServer(t, "", nil, func(m *mocksvc.MockMetricsServiceInterface) {
+		m.EXPECT().UpdateJSONMetrics(gomock.Any(), gomock.Any()).Return(nil).Times(2)
+	})
+
+	stream, err := client.StreamUpdates(context.Background())
+	require.NoError(t, err)
+	require.NoError(t, stream.Send(&metricspb.UpdateBatchRequest{Metrics: []*metricspb.Metric{
+		{Id: "a", Type: metricspb.MetricType_METRIC_TYPE_GAUGE, Value: 1},
+		{Id: "b", Type: metricspb.MetricType_METRIC_TYPE_GAUGE, Value: 2},
+	}}))
+	require.NoError(t, stream.Send(&metricspb.UpdateBatchRequest{Metrics: []*metricspb.Metric{
+		{Id: "c", Type: metricspb.MetricType_METRIC_TYPE_COUNTER, Delta: 1},
+	}}))
+
+	resp, err := stream.CloseAndRecv()
+	require.NoError(t, err)
+	assert.Equal(t, uint32(2), resp.GetBatches())
+	assert.Equal(t, uint32(3), resp.GetMetrics())
+}
+
+func TestServer_Signing(t *testing.T) {
+	const key = "secret"
+	client := startTestServer(t, key, nil, func(m *mocksvc.MockMetricsServiceInterface) {
+		m.EXPECT().UpdateJSONMetrics(gomock.Any(), gomock.Any()).Return(nil).Times(3)
+	})
+
+	req := &metricspb.UpdateBatchRequest{Metrics: []*metricspb.Metric{{Id: "a", Type: metricspb.MetricType_METRIC_TYPE_GAUGE, Value: 1}}}
+	sig, err := metricspb.Sign(req, key)
+	require.NoError(t, err)
+
+	t.Run("valid signature", func(t *testing.T) {
+		ctx := metadata.AppendToOutgoingContext(context.Background(), metricspb.SignatureMetadataKey, sig)
+		var header metadata.MD
+		resp, err := client.UpdateBatch(ctx, req, grpc.Header(&header))
+		require.NoError(t, err)
+
+		respSig, err := metricspb.Sign(resp, key)
+		require.NoError(t, err)
+		assert.Equal(t, []string{respSig}, header.Get(metricspb.SignatureMetadataKey))
+	})
+
+	t.Run("invalid signature", func(t *testing.T) {
+		ctx := metadata.AppendToOutgoingContext(context.Background(), metricspb.SignatureMetadataKey, "deadbeef")
+		_, err := client.UpdateBatch(ctx, req)
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	})
+
+	t.Run("unsigned request", func(t *testing.T) {
+		_, err := client.UpdateBatch(context.Background(), req)
+		assert.NoError(t, err)
+	})
+
+	t.Run("streamed batches", func(t *testing.T) {
+		stream, err := client.StreamUpdates(context.Background())
+		require.NoError(t, err)
+
+		signed := proto.Clone(req).(*metricspb.UpdateBatchRequest)
+		signed.HashSha256 = sig
+		require.NoError(t, stream.Send(signed))
+
+		tampered := proto.Clone(signed).(*metricspb.UpdateBatchRequest)
+		tampered.Metrics[0].Value = 2
+		require.NoError(t, stream.Send(tampered))
+
+		_, err = stream.CloseAndRecv()
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	})
+}
+
+func TestServer_Encryption(t *testing.T) {
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	client := startTestServer(t, "", privateKey, func(m *mocksvc.MockMetricsServiceInterface) {
+		m.EXPECT().UpdateJSONMetrics(gomock.Any(), []models.Metrics{
+			{ID: "a", MType: models.Gauge, Value: ptr(1.0)},
+		}).Return(nil)
+	})
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	plain := &metricspb.UpdateBatchRequest{Metrics: []*metricspb.Metric{{Id: "a", Type: metricspb.MetricType_METRIC_TYPE_GAUGE, Value: 1}}}
+	data, err := proto.Marshal(plain)
+	require.NoError(t, err)
+	sealed, err := crypto.Encrypt(&privateKey.PublicKey, data)
+	require.NoError(t, err)
+
+	_, err = client.UpdateBatch(ctx, &metricspb.UpdateBatchRequest{EncryptedMetrics: sealed})
+	require.NoError(t, err)
+
+	_, err = client.UpdateBatch(ctx, plain)
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	_, err = client.UpdateBatch(ctx, &metricspb.UpdateBatchRequest{EncryptedMetrics: []byte("garbage")})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
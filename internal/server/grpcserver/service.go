@@ -0,0 +1,200 @@
+package grpcserver
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"strings"
+
+	"github.com/Pro100x3mal/go-musthave-metrics/internal/server/handlers"
+	"github.com/Pro100x3mal/go-musthave-metrics/internal/server/infrastructure/audit"
+	"github.com/Pro100x3mal/go-musthave-metrics/internal/server/models"
+	"github.com/Pro100x3mal/go-musthave-metrics/pkg/metricspb"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// metricsServer implements the Metrics gRPC service on top of the metrics service used by the HTTP handlers.
+type metricsServer struct {
+	metricspb.UnimplementedMetricsServer
+
+	service   handlers.MetricsServiceInterface
+	publisher audit.Publisher
+	logger    *zap.Logger
+}
+
+// UpdateBatch applies a batch of metrics, honouring the idempotency key if one is set.
+func (s *metricsServer) UpdateBatch(ctx context.Context, req *metricspb.UpdateBatchRequest) (*metricspb.UpdateBatchResponse, error) {
+	replayed, _, err := s.applyBatch(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	return &metricspb.UpdateBatchResponse{Replayed: replayed}, nil
+}
+
+// GetValue returns the current value of a single series.
+func (s *metricsServer) GetValue(ctx context.Context, req *metricspb.GetValueRequest) (*metricspb.GetValueResponse, error) {
+	if req.GetId() == "" {
+		return nil, s.toStatus(errMissingFields, "failed to get metric")
+	}
+	mType, err := typeFromProto(req.GetType())
+	if err != nil {
+		return nil, s.toStatus(err, "failed to get metric")
+	}
+
+	metric, err := s.service.GetJSONMetricValue(ctx, &models.Metrics{ID: req.GetId(), MType: mType, Labels: labelsFromProto(req.GetLabels())})
+	if err != nil {
+		return nil, s.toStatus(err, "failed to get metric")
+	}
+	return &metricspb.GetValueResponse{Metric: metricToProto(metric)}, nil
+}
+
+// List returns every stored series whose labels contain the request matchers.
+func (s *metricsServer) List(ctx context.Context, req *metricspb.ListRequest) (*metricspb.ListResponse, error) {
+	metrics, err := s.service.ListMetrics(ctx, labelsFromProto(req.GetMatchers()))
+	if err != nil {
+		return nil, s.toStatus(err, "failed to list metrics")
+	}
+
+	resp := &metricspb.ListResponse{Metrics: make([]*metricspb.Metric, 0, len(metrics))}
+	for i := range metrics {
+		resp.Metrics = append(resp.Metrics, metricToProto(&metrics[i]))
+	}
+	return resp, nil
+}
+
+// StreamUpdates applies every received batch and reports the totals when the client closes the stream.
+// The first failing batch aborts the stream; batches received before it stay applied.
+func (s *metricsServer) StreamUpdates(stream metricspb.Metrics_StreamUpdatesServer) error {
+	var resp metricspb.StreamUpdatesResponse
+	for {
+		req, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			return stream.SendAndClose(&resp)
+		}
+		if err != nil {
+			return err
+		}
+
+		_, applied, err := s.applyBatch(stream.Context(), req)
+		if err != nil {
+			return err
+		}
+		resp.Batches++
+		resp.Metrics += uint32(applied)
+	}
+}
+
+// applyBatch stores one batch and audits it. It reports whether the batch was a replay and how many metrics it carried.
+// Encrypted batches only get here if the server has no private key to decrypt them.
+func (s *metricsServer) applyBatch(ctx context.Context, req *metricspb.UpdateBatchRequest) (bool, int, error) {
+	if len(req.GetEncryptedMetrics()) > 0 {
+		return false, 0, status.Error(codes.InvalidArgument, "encrypted metrics are not supported")
+	}
+
+	metrics, err := metricsFromProto(req.GetMetrics())
+	if err != nil {
+		return false, 0, s.toStatus(err, "failed to update metrics")
+	}
+
+	var changes []models.MetricChange
+	auditing := s.publisher != nil && s.publisher.HasObservers()
+	key := req.GetIdempotencyKey()
+	switch {
+	case auditing:
+		changes, err = s.service.UpdateJSONMetricsWithChanges(ctx, key, metrics)
+	case key != "":
+		err = s.service.UpdateJSONMetricsIdempotent(ctx, key, metrics)
+	default:
+		err = s.service.UpdateJSONMetrics(ctx, metrics)
+	}
+	if errors.Is(err, models.ErrDuplicateIdempotencyKey) {
+		s.logger.Debug("batch already applied, skipping", zap.String("idempotency_key", key))
+		return true, len(metrics), nil
+	}
+	if err != nil {
+		return false, 0, s.toStatus(err, "failed to update metrics")
+	}
+
+	if auditing {
+		auditEvent := audit.NewAuditEventFromChanges(changes, clientIP(ctx))
+		auditEvent.RequestID = audit.NewRequestID()
+		auditEvent.ClientRequestID = clientRequestID(ctx)
+		auditEvent.Agent = audit.NewAgentIdentity(audit.SigningKeyID(ctx), tlsCommonName(ctx))
+		s.publisher.NotifyAll(ctx, auditEvent)
+	}
+	return false, len(metrics), nil
+}
+
+// toStatus maps service errors to gRPC status codes the same way the HTTP handlers map them to status codes.
+func (s *metricsServer) toStatus(err error, internalErrorMessage string) error {
+	switch {
+	case errors.Is(err, context.Canceled):
+		return status.Error(codes.Canceled, err.Error())
+	case errors.Is(err, context.DeadlineExceeded):
+		return status.Error(codes.DeadlineExceeded, err.Error())
+	case errors.Is(err, models.ErrUnsupportedMetricType),
+		errors.Is(err, models.ErrInvalidMetricValue),
+		errors.Is(err, models.ErrInvalidIdempotencyKey),
+		errors.Is(err, models.ErrInvalidMetricID),
+		errors.Is(err, models.ErrInvalidLabels):
+		return status.Error(codes.InvalidArgument, err.Error())
+	case errors.Is(err, models.ErrHistogramBoundsMismatch):
+		return status.Error(codes.FailedPrecondition, err.Error())
+	case errors.Is(err, models.ErrMetricNotFound):
+		return status.Error(codes.NotFound, err.Error())
+	default:
+		s.logger.Error(internalErrorMessage, zap.Error(err))
+		return status.Error(codes.Internal, "internal error")
+	}
+}
+
+// clientIP returns the client address for auditing: the x-forwarded-for or x-real-ip metadata if present, the peer address otherwise.
+func clientIP(ctx context.Context) string {
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if xff := md.Get("x-forwarded-for"); len(xff) > 0 && xff[0] != "" {
+			return strings.TrimSpace(strings.Split(xff[0], ",")[0])
+		}
+		if xri := md.Get("x-real-ip"); len(xri) > 0 && xri[0] != "" {
+			return xri[0]
+		}
+	}
+
+	p, ok := peer.FromContext(ctx)
+	if !ok || p.Addr == nil {
+		return ""
+	}
+	host, _, err := net.SplitHostPort(p.Addr.String())
+	if err != nil {
+		return p.Addr.String()
+	}
+	return host
+}
+
+// clientRequestID returns the x-request-id metadata of the call, sanitized, or an empty string if the client did not send one.
+func clientRequestID(ctx context.Context) string {
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if id := md.Get("x-request-id"); len(id) > 0 {
+			return audit.SanitizeClientRequestID(id[0])
+		}
+	}
+	return ""
+}
+
+// tlsCommonName returns the common name of the verified client certificate of the call, if any.
+func tlsCommonName(ctx context.Context) string {
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return ""
+	}
+	tlsInfo, ok := p.AuthInfo.(credentials.TLSInfo)
+	if !ok || len(tlsInfo.State.VerifiedChains) == 0 || len(tlsInfo.State.VerifiedChains[0]) == 0 {
+		return ""
+	}
+	return tlsInfo.State.VerifiedChains[0][0].Subject.CommonName
+}
@@ -0,0 +1,25 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"go.uber.org/zap"
+)
+
+// ListAlertsHandler returns the currently pending and firing alerts as JSON.
+// It accepts HTTP GET requests to the "/alerts" endpoint.
+// Returns 200 OK with a JSON array of alerts on success, 501 Not Implemented if alerting is not configured, 500 Internal Server Error on failure.
+func (mh *MetricsHandler) ListAlertsHandler(w http.ResponseWriter, _ *http.Request) {
+	if mh.alerts == nil {
+		http.Error(w, "Alerting is not configured", http.StatusNotImplemented)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(mh.alerts.ActiveAlerts()); err != nil {
+		mh.logger.Error("failed to encode response", zap.Error(err))
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+}
@@ -0,0 +1,81 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Pro100x3mal/go-musthave-metrics/internal/server/alerting"
+	"github.com/Pro100x3mal/go-musthave-metrics/internal/server/configs"
+	mocksvc "github.com/Pro100x3mal/go-musthave-metrics/internal/server/handlers/mocks"
+	"github.com/go-chi/chi/v5"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+type stubAlertsProvider struct {
+	alerts []alerting.Alert
+}
+
+func (s *stubAlertsProvider) ActiveAlerts() []alerting.Alert {
+	return s.alerts
+}
+
+func TestListAlertsHandler(t *testing.T) {
+	tests := []struct {
+		name       string
+		provider   AlertsProvider
+		wantStatus int
+		wantLen    int
+	}{
+		{
+			name:       "alerting not configured",
+			provider:   nil,
+			wantStatus: http.StatusNotImplemented,
+		},
+		{
+			name:       "no active alerts",
+			provider:   &stubAlertsProvider{alerts: []alerting.Alert{}},
+			wantStatus: http.StatusOK,
+		},
+		{
+			name: "firing alert",
+			provider: &stubAlertsProvider{alerts: []alerting.Alert{
+				{Rule: "HighAlloc", Metric: "Alloc", MType: "gauge", State: alerting.StateFiring, ActiveSince: time.Now()},
+			}},
+			wantStatus: http.StatusOK,
+			wantLen:    1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			handler := NewMetricsHandler(mocksvc.NewMockMetricsServiceInterface(ctrl), zap.NewNop(), &configs.ServerConfig{}, &mockAuditManager{}, nil)
+			if tt.provider != nil {
+				handler.SetAlertsProvider(tt.provider)
+			}
+
+			r := chi.NewRouter()
+			initRoutes(r, handler)
+			ts := httptest.NewServer(r)
+			defer ts.Close()
+
+			resp, err := ts.Client().Get(ts.URL + "/alerts")
+			require.NoError(t, err)
+			defer resp.Body.Close()
+
+			assert.Equal(t, tt.wantStatus, resp.StatusCode)
+
+			if tt.wantStatus == http.StatusOK {
+				var alerts []alerting.Alert
+				require.NoError(t, json.NewDecoder(resp.Body).Decode(&alerts))
+				assert.Len(t, alerts, tt.wantLen)
+			}
+		})
+	}
+}
@@ -0,0 +1,43 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/Pro100x3mal/go-musthave-metrics/internal/server/infrastructure/audit"
+	"go.uber.org/zap"
+)
+
+// ListAuditHandler searches the audit file and its rotated segments and returns matching events as JSON, newest first.
+// It accepts HTTP GET requests with the following URL pattern:
+// GET /audit?metric=&ip=&from=&to=&limit=
+// where metric is a metric name or a glob pattern, ip is an address or a CIDR prefix,
+// from and to are RFC 3339 timestamps or Unix seconds, and limit defaults to 100.
+// The endpoint requires the admin bearer token.
+// Returns 200 OK with a JSON array of events on success, 400 Bad Request for invalid parameters,
+// 501 Not Implemented if no audit file is configured, 500 Internal Server Error on failure.
+func (mh *MetricsHandler) ListAuditHandler(w http.ResponseWriter, r *http.Request) {
+	if mh.auditLog == nil {
+		http.Error(w, "Audit file is not configured", http.StatusNotImplemented)
+		return
+	}
+
+	q, err := audit.ParseQuery(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	events, err := mh.auditLog.Query(r.Context(), q)
+	if err != nil {
+		mh.writeError(w, err, "failed to query audit log")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err = json.NewEncoder(w).Encode(events); err != nil {
+		mh.logger.Error("failed to encode response", zap.Error(err))
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+}
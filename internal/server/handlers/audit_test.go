@@ -0,0 +1,113 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/Pro100x3mal/go-musthave-metrics/internal/server/configs"
+	mocksvc "github.com/Pro100x3mal/go-musthave-metrics/internal/server/handlers/mocks"
+	"github.com/Pro100x3mal/go-musthave-metrics/internal/server/infrastructure/audit"
+	"github.com/Pro100x3mal/go-musthave-metrics/internal/server/models"
+	"github.com/go-chi/chi/v5"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestListAuditHandler(t *testing.T) {
+	auditFile := filepath.Join(t.TempDir(), "audit.json")
+	obs, err := audit.NewFileAuditObserver(auditFile, "", audit.RotationOptions{})
+	require.NoError(t, err)
+	for _, e := range []*models.AuditEvent{
+		{Timestamp: 1, Metrics: []string{"Alloc"}, IPAddress: "10.0.0.1"},
+		{Timestamp: 2, Metrics: []string{"PollCount"}, IPAddress: "10.0.0.2"},
+		{Timestamp: 3, Metrics: []string{"Alloc"}, IPAddress: "10.0.0.2"},
+	} {
+		require.NoError(t, obs.Notify(context.Background(), e))
+	}
+	require.NoError(t, obs.Close())
+
+	tests := []struct {
+		name       string
+		adminToken string
+		token      string
+		query      string
+		wantStatus int
+		wantTS     []int64
+	}{
+		{
+			name:       "admin API disabled",
+			token:      "secret",
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name:       "missing token",
+			adminToken: "secret",
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:       "wrong token",
+			adminToken: "secret",
+			token:      "guess",
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:       "invalid parameter",
+			adminToken: "secret",
+			token:      "secret",
+			query:      "?limit=-1",
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "all events",
+			adminToken: "secret",
+			token:      "secret",
+			wantStatus: http.StatusOK,
+			wantTS:     []int64{3, 2, 1},
+		},
+		{
+			name:       "filtered",
+			adminToken: "secret",
+			token:      "secret",
+			query:      "?metric=Alloc&ip=10.0.0.2",
+			wantStatus: http.StatusOK,
+			wantTS:     []int64{3},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			cfg := &configs.ServerConfig{AuditFile: auditFile, AdminToken: tt.adminToken}
+			handler := NewMetricsHandler(mocksvc.NewMockMetricsServiceInterface(ctrl), zap.NewNop(), cfg, &mockAuditManager{}, nil)
+
+			r := chi.NewRouter()
+			initRoutes(r, handler)
+
+			req := httptest.NewRequest(http.MethodGet, "/audit"+tt.query, nil)
+			if tt.token != "" {
+				req.Header.Set("Authorization", "Bearer "+tt.token)
+			}
+			rec := httptest.NewRecorder()
+			r.ServeHTTP(rec, req)
+
+			assert.Equal(t, tt.wantStatus, rec.Code)
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+
+			var events []models.AuditEvent
+			require.NoError(t, json.NewDecoder(rec.Body).Decode(&events))
+			var ts []int64
+			for _, e := range events {
+				ts = append(ts, e.Timestamp)
+			}
+			assert.Equal(t, tt.wantTS, ts)
+		})
+	}
+}
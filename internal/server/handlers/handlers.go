@@ -14,6 +14,13 @@ import (
 	"go.uber.org/zap"
 )
 
+const (
+	// IdempotencyKeyHeader is the request header carrying the batch idempotency key.
+	IdempotencyKeyHeader = "Idempotency-Key"
+	// IdempotentReplayedHeader is set on responses to batches that had already been applied.
+	IdempotentReplayedHeader = "Idempotent-Replayed"
+)
+
 const metricsTemplate = `<!DOCTYPE html>
 <html>
 <head>
@@ -40,26 +47,30 @@ func (mh *MetricsHandler) UpdateHandler(w http.ResponseWriter, r *http.Request)
 	mName := chi.URLParam(r, "mName")
 	mValue := chi.URLParam(r, "mValue")
 
-	if err := mh.writer.UpdateMetricFromParams(r.Context(), mType, mName, mValue); err != nil {
+	var err error
+	if mh.auditEnabled() {
+		var metric *models.Metrics
+		if metric, err = models.ParseMetric(mType, mName, mValue); err == nil {
+			err = mh.updateAudited(r, "", []models.Metrics{*metric})
+		}
+	} else {
+		err = mh.writer.UpdateMetricFromParams(r.Context(), mType, mName, mValue)
+	}
+	if err != nil {
 		mh.writeError(w, err, "failed to update metric")
 		return
 	}
 
-	if mh.auditManager != nil && mh.auditManager.HasObservers() {
-		ipAddress := audit.GetIPAddress(r)
-		metric := &models.Metrics{ID: mName, MType: mType}
-		auditEvent := audit.NewAuditEventFromMetric(metric, ipAddress)
-		go mh.auditManager.NotifyAll(r.Context(), auditEvent)
-	}
-
 	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
 	w.WriteHeader(http.StatusOK)
 }
 
 // UpdateJSONHandler handles metric updates via JSON payload.
 // It accepts HTTP POST requests with Content-Type: application/json and a JSON body containing a Metrics object.
-// The JSON structure should include "id" (metric name), "type" (gauge or counter), and either "value" (for gauge) or "delta" (for counter).
-// Returns 200 OK on success, 400 Bad Request for invalid data, 415 Unsupported Media Type for non-JSON content, 500 Internal Server Error on failure.
+// The JSON structure should include "id" (metric name), "type" (gauge, counter or histogram), and either "value" (for gauge), "delta" (for counter)
+// or "histogram" (for histogram, with "bounds", "counts", "sum" and "count").
+// Returns 200 OK on success, 400 Bad Request for invalid data, 409 Conflict if histogram bounds differ from the stored ones,
+// 415 Unsupported Media Type for non-JSON content, 500 Internal Server Error on failure.
 func (mh *MetricsHandler) UpdateJSONHandler(w http.ResponseWriter, r *http.Request) {
 	if !strings.Contains(r.Header.Get("Content-Type"), "application/json") {
 		http.Error(w, "Invalid Content-Type", http.StatusUnsupportedMediaType)
@@ -78,26 +89,28 @@ func (mh *MetricsHandler) UpdateJSONHandler(w http.ResponseWriter, r *http.Reque
 		return
 	}
 
-	err = mh.writer.UpdateJSONMetric(r.Context(), &metric)
+	if mh.auditEnabled() {
+		err = mh.updateAudited(r, "", []models.Metrics{metric})
+	} else {
+		err = mh.writer.UpdateJSONMetric(r.Context(), &metric)
+	}
 	if err != nil {
 		mh.writeError(w, err, "failed to update metric")
 		return
 	}
 
-	if mh.auditManager != nil && mh.auditManager.HasObservers() {
-		ipAddress := audit.GetIPAddress(r)
-		auditEvent := audit.NewAuditEventFromMetric(&metric, ipAddress)
-		go mh.auditManager.NotifyAll(r.Context(), auditEvent)
-	}
-
 	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
 	w.WriteHeader(http.StatusOK)
 }
 
 // UpdateBatchJSONHandler handles batch metric updates via JSON payload.
 // It accepts HTTP POST requests with Content-Type: application/json and a JSON array of Metrics objects.
-// Each metric in the array should include "id" (metric name), "type" (gauge or counter), and either "value" (for gauge) or "delta" (for counter).
-// Returns 200 OK on success, 400 Bad Request for invalid data, 415 Unsupported Media Type for non-JSON content, 500 Internal Server Error on failure.
+// Each metric in the array should include "id" (metric name), "type" (gauge, counter or histogram), and either "value" (for gauge), "delta" (for counter)
+// or "histogram" (for histogram). Counter deltas and histograms of the same series are summed; for gauges the last value wins.
+// If the request carries an Idempotency-Key header, a batch replayed with the same key is not applied again;
+// the replay is answered with 200 OK and the Idempotent-Replayed: true header.
+// Returns 200 OK on success, 400 Bad Request for invalid data, 409 Conflict if histogram bounds differ from the stored ones,
+// 415 Unsupported Media Type for non-JSON content, 500 Internal Server Error on failure.
 func (mh *MetricsHandler) UpdateBatchJSONHandler(w http.ResponseWriter, r *http.Request) {
 	if !strings.Contains(r.Header.Get("Content-Type"), "application/json") {
 		http.Error(w, "Invalid Content-Type", http.StatusUnsupportedMediaType)
@@ -118,32 +131,48 @@ func (mh *MetricsHandler) UpdateBatchJSONHandler(w http.ResponseWriter, r *http.
 		}
 	}
 
-	err = mh.writer.UpdateJSONMetrics(r.Context(), metrics)
+	key := r.Header.Get(IdempotencyKeyHeader)
+	switch {
+	case mh.auditEnabled():
+		err = mh.updateAudited(r, key, metrics)
+	case key != "":
+		err = mh.writer.UpdateJSONMetricsIdempotent(r.Context(), key, metrics)
+	default:
+		err = mh.writer.UpdateJSONMetrics(r.Context(), metrics)
+	}
+	if errors.Is(err, models.ErrDuplicateIdempotencyKey) {
+		mh.logger.Debug("batch already applied, skipping", zap.String("idempotency_key", key))
+		w.Header().Set(IdempotentReplayedHeader, "true")
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.WriteHeader(http.StatusOK)
+		return
+	}
 	if err != nil {
 		mh.writeError(w, err, "failed to update metrics")
 		return
 	}
 
-	if mh.auditManager != nil && mh.auditManager.HasObservers() {
-		ipAddress := audit.GetIPAddress(r)
-		auditEvent := audit.NewAuditEventFromMetrics(metrics, ipAddress)
-		go mh.auditManager.NotifyAll(r.Context(), auditEvent)
-	}
-
 	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
 	w.WriteHeader(http.StatusOK)
 }
 
 // GetMetricHandler retrieves a single metric value via URL parameters.
 // It accepts HTTP GET requests with the following URL pattern:
-// GET /value/{mType}/{mName}
-// where mType is either "gauge" or "counter", and mName is the metric name.
-// Returns the metric value as plain text on success, 400 Bad Request for invalid metric types, 404 Not Found if metric doesn't exist, 500 Internal Server Error on failure.
+// GET /value/{mType}/{mName}?labels=name=value,...
+// where mType is "gauge", "counter" or "histogram", mName is the metric name, and the optional labels select the exact series.
+// Histograms are returned as a JSON object.
+// Returns the metric value as plain text on success, 400 Bad Request for invalid metric types or labels, 404 Not Found if metric doesn't exist, 500 Internal Server Error on failure.
 func (mh *MetricsHandler) GetMetricHandler(w http.ResponseWriter, r *http.Request) {
 	mType := chi.URLParam(r, "mType")
 	mName := chi.URLParam(r, "mName")
 
-	mValue, err := mh.reader.GetMetricValue(r.Context(), mType, mName)
+	labels, err := models.ParseLabels(r.URL.Query().Get("labels"))
+	if err != nil {
+		mh.writeError(w, err, "failed to get metric")
+		return
+	}
+
+	mValue, err := mh.reader.GetMetricValue(r.Context(), mType, mName, labels)
 	if err != nil {
 		mh.writeError(w, err, "failed to get metric")
 		return
@@ -203,9 +232,16 @@ type metricItem struct {
 
 // ListAllMetricsHandler returns an HTML page with all stored metrics.
 // It accepts HTTP GET requests to the root path "/" and returns an HTML page with a list of all metrics and their values.
-// Returns 200 OK with HTML content on success, 500 Internal Server Error on failure.
+// An optional labels=name=value,... query parameter restricts the list to series carrying all given labels.
+// Returns 200 OK with HTML content on success, 400 Bad Request for invalid labels, 500 Internal Server Error on failure.
 func (mh *MetricsHandler) ListAllMetricsHandler(w http.ResponseWriter, r *http.Request) {
-	list, err := mh.reader.GetAllMetrics(r.Context())
+	matchers, err := models.ParseLabels(r.URL.Query().Get("labels"))
+	if err != nil {
+		mh.writeError(w, err, "failed to get metrics")
+		return
+	}
+
+	list, err := mh.reader.GetAllMetrics(r.Context(), matchers)
 	if err != nil {
 		mh.writeError(w, err, "failed to get metrics")
 		return
@@ -256,6 +292,29 @@ func (mh *MetricsHandler) PingDBHandler(w http.ResponseWriter, r *http.Request)
 	w.WriteHeader(http.StatusOK)
 }
 
+func (mh *MetricsHandler) auditEnabled() bool {
+	return mh.auditManager != nil && mh.auditManager.HasObservers()
+}
+
+// updateAudited applies the metrics, honouring key if it is not empty, and audits the changes they made.
+func (mh *MetricsHandler) updateAudited(r *http.Request, key string, metrics []models.Metrics) error {
+	changes, err := mh.writer.UpdateJSONMetricsWithChanges(r.Context(), key, metrics)
+	if err != nil {
+		return err
+	}
+	mh.notifyAudit(r, changes)
+	return nil
+}
+
+// notifyAudit publishes the changes made by the request together with the client address, request ID and agent identity.
+func (mh *MetricsHandler) notifyAudit(r *http.Request, changes []models.MetricChange) {
+	auditEvent := audit.NewAuditEventFromChanges(changes, audit.GetIPAddress(r))
+	auditEvent.RequestID = audit.RequestID(r.Context())
+	auditEvent.ClientRequestID = audit.ClientRequestID(r.Context())
+	auditEvent.Agent = audit.GetAgentIdentity(r)
+	mh.auditManager.NotifyAll(r.Context(), auditEvent)
+}
+
 func (mh *MetricsHandler) writeError(w http.ResponseWriter, err error, internalErrorMessage string) {
 	switch {
 	case errors.Is(err, context.Canceled):
@@ -274,6 +333,26 @@ func (mh *MetricsHandler) writeError(w http.ResponseWriter, err error, internalE
 		http.Error(w, models.ErrInvalidMetricValue.Error(), http.StatusBadRequest)
 		return
 
+	case errors.Is(err, models.ErrInvalidIdempotencyKey):
+		http.Error(w, models.ErrInvalidIdempotencyKey.Error(), http.StatusBadRequest)
+		return
+
+	case errors.Is(err, models.ErrInvalidTimeRange):
+		http.Error(w, models.ErrInvalidTimeRange.Error(), http.StatusBadRequest)
+		return
+
+	case errors.Is(err, models.ErrInvalidMetricID):
+		http.Error(w, models.ErrInvalidMetricID.Error(), http.StatusBadRequest)
+		return
+
+	case errors.Is(err, models.ErrInvalidLabels):
+		http.Error(w, models.ErrInvalidLabels.Error(), http.StatusBadRequest)
+		return
+
+	case errors.Is(err, models.ErrHistogramBoundsMismatch):
+		http.Error(w, models.ErrHistogramBoundsMismatch.Error(), http.StatusConflict)
+		return
+
 	case errors.Is(err, models.ErrMetricNotFound):
 		http.Error(w, models.ErrMetricNotFound.Error(), http.StatusNotFound)
 		return
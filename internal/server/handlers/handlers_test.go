@@ -2,6 +2,9 @@ package handlers
 
 import (
 	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"io"
 	"net/http"
@@ -170,6 +173,17 @@ func TestUpdateJSONHandler(t *testing.T) {
 					Return(models.ErrUnsupportedMetricType)
 			},
 		},
+		{
+			name:        "histogram bounds mismatch",
+			contentType: "application/json",
+			body:        `{"id":"latency","type":"histogram","histogram":{"bounds":[1],"counts":[1,0],"sum":0.5,"count":1}}`,
+			wantStatus:  http.StatusConflict,
+			setupMock: func(m *mocksvc.MockMetricsServiceInterface) {
+				m.EXPECT().
+					UpdateJSONMetric(gomock.Any(), gomock.Any()).
+					Return(models.ErrHistogramBoundsMismatch)
+			},
+		},
 		{
 			name:        "update counter JSON - success",
 			contentType: "application/json",
@@ -215,11 +229,13 @@ func TestUpdateJSONHandler(t *testing.T) {
 
 func TestUpdateBatchJSONHandler(t *testing.T) {
 	tests := []struct {
-		name        string
-		contentType string
-		body        string
-		wantStatus  int
-		setupMock   func(*mocksvc.MockMetricsServiceInterface)
+		name           string
+		contentType    string
+		body           string
+		idempotencyKey string
+		wantStatus     int
+		wantReplayed   bool
+		setupMock      func(*mocksvc.MockMetricsServiceInterface)
 	}{
 		{
 			name:        "invalid content type",
@@ -264,6 +280,43 @@ func TestUpdateBatchJSONHandler(t *testing.T) {
 					Return(nil)
 			},
 		},
+		{
+			name:           "update batch with idempotency key - success",
+			contentType:    "application/json",
+			body:           `[{"id":"counter1","type":"counter","delta":10}]`,
+			idempotencyKey: "batch-1",
+			wantStatus:     http.StatusOK,
+			setupMock: func(m *mocksvc.MockMetricsServiceInterface) {
+				m.EXPECT().
+					UpdateJSONMetricsIdempotent(gomock.Any(), "batch-1", gomock.Any()).
+					Return(nil)
+			},
+		},
+		{
+			name:           "update batch with idempotency key - replayed",
+			contentType:    "application/json",
+			body:           `[{"id":"counter1","type":"counter","delta":10}]`,
+			idempotencyKey: "batch-1",
+			wantStatus:     http.StatusOK,
+			wantReplayed:   true,
+			setupMock: func(m *mocksvc.MockMetricsServiceInterface) {
+				m.EXPECT().
+					UpdateJSONMetricsIdempotent(gomock.Any(), "batch-1", gomock.Any()).
+					Return(models.ErrDuplicateIdempotencyKey)
+			},
+		},
+		{
+			name:           "update batch with idempotency key - invalid key",
+			contentType:    "application/json",
+			body:           `[{"id":"counter1","type":"counter","delta":10}]`,
+			idempotencyKey: strings.Repeat("k", 300),
+			wantStatus:     http.StatusBadRequest,
+			setupMock: func(m *mocksvc.MockMetricsServiceInterface) {
+				m.EXPECT().
+					UpdateJSONMetricsIdempotent(gomock.Any(), gomock.Any(), gomock.Any()).
+					Return(models.ErrInvalidIdempotencyKey)
+			},
+		},
 	}
 
 	for _, tt := range tests {
@@ -275,16 +328,67 @@ func TestUpdateBatchJSONHandler(t *testing.T) {
 			req, err := http.NewRequest(http.MethodPost, ts.URL+"/updates/", strings.NewReader(tt.body))
 			require.NoError(t, err)
 			req.Header.Set("Content-Type", tt.contentType)
+			if tt.idempotencyKey != "" {
+				req.Header.Set(IdempotencyKeyHeader, tt.idempotencyKey)
+			}
 
 			resp, err := ts.Client().Do(req)
 			require.NoError(t, err)
 			defer resp.Body.Close()
 
 			assert.Equal(t, tt.wantStatus, resp.StatusCode)
+			assert.Equal(t, tt.wantReplayed, resp.Header.Get(IdempotentReplayedHeader) == "true")
 		})
 	}
 }
 
+type recordingAuditManager struct {
+	events []*models.AuditEvent
+}
+
+func (m *recordingAuditManager) Attach(_ audit.Observer) {}
+func (m *recordingAuditManager) NotifyAll(_ context.Context, event *models.AuditEvent) {
+	m.events = append(m.events, event)
+}
+func (m *recordingAuditManager) HasObservers() bool { return true }
+
+func TestUpdateBatchJSONHandler_Audit(t *testing.T) {
+	const key = "secret"
+	body := `[{"id":"PollCount","type":"counter","delta":5}]`
+
+	ctrl := gomock.NewController(t)
+	mockService := mocksvc.NewMockMetricsServiceInterface(ctrl)
+	changes := []models.MetricChange{{ID: "PollCount", MType: models.Counter, Delta: new(int64), PrevDelta: new(int64)}}
+	mockService.EXPECT().UpdateJSONMetricsWithChanges(gomock.Any(), "", gomock.Any()).Return(changes, nil)
+
+	auditManager := &recordingAuditManager{}
+	handler := NewMetricsHandler(mockService, zap.NewNop(), &configs.ServerConfig{Key: key}, auditManager, nil)
+	r := chi.NewRouter()
+	initRoutes(r, handler)
+
+	req := httptest.NewRequest(http.MethodPost, "/updates/", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Request-Id", "req-42")
+	mac := hmac.New(sha256.New, []byte(key))
+	mac.Write([]byte(body))
+	req.Header.Set("HashSHA256", hex.EncodeToString(mac.Sum(nil)))
+	req.RemoteAddr = "10.0.0.1:1234"
+
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	require.Len(t, auditManager.events, 1)
+	event := auditManager.events[0]
+	assert.Equal(t, []string{"PollCount"}, event.Metrics)
+	assert.Equal(t, "10.0.0.1", event.IPAddress)
+	assert.Equal(t, rec.Header().Get("X-Request-Id"), event.RequestID)
+	assert.NotEqual(t, "req-42", event.RequestID)
+	assert.Equal(t, "req-42", event.ClientRequestID)
+	assert.Equal(t, &models.AgentIdentity{KeyID: audit.KeyID(key)}, event.Agent)
+	assert.Equal(t, changes, event.Changes)
+}
+
 func TestGetMetricHandler(t *testing.T) {
 	tests := []struct {
 		name       string
@@ -299,7 +403,7 @@ func TestGetMetricHandler(t *testing.T) {
 			wantStatus: http.StatusNotFound,
 			setupMock: func(m *mocksvc.MockMetricsServiceInterface) {
 				m.EXPECT().
-					GetMetricValue(gomock.Any(), "counter", "unknown").
+					GetMetricValue(gomock.Any(), "counter", "unknown", models.Labels(nil)).
 					Return("", models.ErrMetricNotFound)
 			},
 		},
@@ -309,7 +413,7 @@ func TestGetMetricHandler(t *testing.T) {
 			wantStatus: http.StatusBadRequest,
 			setupMock: func(m *mocksvc.MockMetricsServiceInterface) {
 				m.EXPECT().
-					GetMetricValue(gomock.Any(), "unknown", "test").
+					GetMetricValue(gomock.Any(), "unknown", "test", models.Labels(nil)).
 					Return("", models.ErrUnsupportedMetricType)
 			},
 		},
@@ -320,7 +424,7 @@ func TestGetMetricHandler(t *testing.T) {
 			wantBody:   "42",
 			setupMock: func(m *mocksvc.MockMetricsServiceInterface) {
 				m.EXPECT().
-					GetMetricValue(gomock.Any(), "counter", "test").
+					GetMetricValue(gomock.Any(), "counter", "test", models.Labels(nil)).
 					Return("42", nil)
 			},
 		},
@@ -331,10 +435,27 @@ func TestGetMetricHandler(t *testing.T) {
 			wantBody:   "3.14",
 			setupMock: func(m *mocksvc.MockMetricsServiceInterface) {
 				m.EXPECT().
-					GetMetricValue(gomock.Any(), "gauge", "test").
+					GetMetricValue(gomock.Any(), "gauge", "test", models.Labels(nil)).
 					Return("3.14", nil)
 			},
 		},
+		{
+			name:       "get labeled gauge - success",
+			url:        "/value/gauge/load?labels=host=h42,dc=eu",
+			wantStatus: http.StatusOK,
+			wantBody:   "0.5",
+			setupMock: func(m *mocksvc.MockMetricsServiceInterface) {
+				m.EXPECT().
+					GetMetricValue(gomock.Any(), "gauge", "load", models.Labels{"host": "h42", "dc": "eu"}).
+					Return("0.5", nil)
+			},
+		},
+		{
+			name:       "get - invalid labels",
+			url:        "/value/gauge/load?labels=host",
+			wantStatus: http.StatusBadRequest,
+			setupMock:  func(m *mocksvc.MockMetricsServiceInterface) {},
+		},
 	}
 
 	for _, tt := range tests {
@@ -485,7 +606,7 @@ func TestListAllMetricsHandler(t *testing.T) {
 			name: "list all metrics - error",
 			setupMock: func(m *mocksvc.MockMetricsServiceInterface) {
 				m.EXPECT().
-					GetAllMetrics(gomock.Any()).
+					GetAllMetrics(gomock.Any(), models.Labels(nil)).
 					Return(nil, assert.AnError)
 			},
 			wantStatus: http.StatusInternalServerError,
@@ -494,7 +615,7 @@ func TestListAllMetricsHandler(t *testing.T) {
 			name: "list all metrics - empty",
 			setupMock: func(m *mocksvc.MockMetricsServiceInterface) {
 				m.EXPECT().
-					GetAllMetrics(gomock.Any()).
+					GetAllMetrics(gomock.Any(), models.Labels(nil)).
 					Return(map[string]string{}, nil)
 			},
 			wantStatus:   http.StatusOK,
@@ -504,7 +625,7 @@ func TestListAllMetricsHandler(t *testing.T) {
 			name: "list all metrics - success",
 			setupMock: func(m *mocksvc.MockMetricsServiceInterface) {
 				m.EXPECT().
-					GetAllMetrics(gomock.Any()).
+					GetAllMetrics(gomock.Any(), models.Labels(nil)).
 					Return(map[string]string{
 						"test_counter": "42",
 						"test_gauge":   "3.14",
@@ -0,0 +1,87 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Pro100x3mal/go-musthave-metrics/internal/server/models"
+	"github.com/go-chi/chi/v5"
+	"go.uber.org/zap"
+)
+
+const defaultHistoryRange = time.Hour
+
+// GetMetricHistoryHandler returns timestamped samples of a single metric as JSON.
+// It accepts HTTP GET requests with the following URL pattern:
+// GET /history/{mType}/{mName}?from=&to=&step=&labels=
+// where from and to are RFC 3339 timestamps or Unix seconds (defaults: the last hour up to now),
+// step is an optional Go duration (e.g. "30s") used to downsample the result to one sample per step,
+// and labels (name=value,...) selects the exact series of a labeled metric.
+// Returns 200 OK with a JSON array of samples on success, 400 Bad Request for invalid parameters, 500 Internal Server Error on failure.
+func (mh *MetricsHandler) GetMetricHistoryHandler(w http.ResponseWriter, r *http.Request) {
+	mType := chi.URLParam(r, "mType")
+	mName := chi.URLParam(r, "mName")
+	query := r.URL.Query()
+
+	to := time.Now()
+	if v := query.Get("to"); v != "" {
+		t, err := parseTimeParam(v)
+		if err != nil {
+			http.Error(w, "Invalid 'to' parameter", http.StatusBadRequest)
+			return
+		}
+		to = t
+	}
+
+	from := to.Add(-defaultHistoryRange)
+	if v := query.Get("from"); v != "" {
+		t, err := parseTimeParam(v)
+		if err != nil {
+			http.Error(w, "Invalid 'from' parameter", http.StatusBadRequest)
+			return
+		}
+		from = t
+	}
+
+	labels, err := models.ParseLabels(query.Get("labels"))
+	if err != nil {
+		mh.writeError(w, err, "failed to get metric history")
+		return
+	}
+
+	var step time.Duration
+	if v := query.Get("step"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			http.Error(w, "Invalid 'step' parameter", http.StatusBadRequest)
+			return
+		}
+		step = d
+	}
+
+	samples, err := mh.reader.GetMetricHistory(r.Context(), mType, mName, labels, from, to, step)
+	if err != nil {
+		mh.writeError(w, err, "failed to get metric history")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err = json.NewEncoder(w).Encode(samples); err != nil {
+		mh.logger.Error("failed to encode response", zap.Error(err))
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+}
+
+func parseTimeParam(v string) (time.Time, error) {
+	if sec, err := strconv.ParseInt(v, 10, 64); err == nil {
+		return time.Unix(sec, 0), nil
+	}
+	t, err := time.Parse(time.RFC3339, v)
+	if err != nil {
+		return time.Time{}, models.ErrInvalidTimeRange
+	}
+	return t, nil
+}
@@ -0,0 +1,99 @@
+package handlers
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	mocksvc "github.com/Pro100x3mal/go-musthave-metrics/internal/server/handlers/mocks"
+	"github.com/Pro100x3mal/go-musthave-metrics/internal/server/models"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetMetricHistoryHandler(t *testing.T) {
+	from := time.Unix(1700000000, 0)
+	to := time.Unix(1700003600, 0)
+
+	tests := []struct {
+		name       string
+		url        string
+		wantStatus int
+		wantLen    int
+		setupMock  func(*mocksvc.MockMetricsServiceInterface)
+	}{
+		{
+			name:       "invalid from",
+			url:        "/history/gauge/Alloc?from=yesterday",
+			wantStatus: http.StatusBadRequest,
+			setupMock:  func(m *mocksvc.MockMetricsServiceInterface) {},
+		},
+		{
+			name:       "invalid step",
+			url:        "/history/gauge/Alloc?step=often",
+			wantStatus: http.StatusBadRequest,
+			setupMock:  func(m *mocksvc.MockMetricsServiceInterface) {},
+		},
+		{
+			name:       "unsupported type",
+			url:        "/history/unknown/Alloc",
+			wantStatus: http.StatusBadRequest,
+			setupMock: func(m *mocksvc.MockMetricsServiceInterface) {
+				m.EXPECT().
+					GetMetricHistory(gomock.Any(), "unknown", "Alloc", models.Labels(nil), gomock.Any(), gomock.Any(), time.Duration(0)).
+					Return(nil, models.ErrUnsupportedMetricType)
+			},
+		},
+		{
+			name:       "inverted range",
+			url:        "/history/gauge/Alloc?from=1700003600&to=1700000000",
+			wantStatus: http.StatusBadRequest,
+			setupMock: func(m *mocksvc.MockMetricsServiceInterface) {
+				m.EXPECT().
+					GetMetricHistory(gomock.Any(), "gauge", "Alloc", models.Labels(nil), to, from, time.Duration(0)).
+					Return(nil, models.ErrInvalidTimeRange)
+			},
+		},
+		{
+			name:       "success",
+			url:        "/history/gauge/Alloc?from=1700000000&to=2023-11-14T23:13:20Z&step=1m",
+			wantStatus: http.StatusOK,
+			wantLen:    2,
+			setupMock: func(m *mocksvc.MockMetricsServiceInterface) {
+				m.EXPECT().
+					GetMetricHistory(gomock.Any(), "gauge", "Alloc", models.Labels(nil), from, gomock.Any(), time.Minute).
+					Return([]models.Sample{
+						{Timestamp: from, Value: 1},
+						{Timestamp: from.Add(time.Minute), Value: 2},
+					}, nil)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := setupTestHandler(t, tt.setupMock)
+			ts := httptest.NewServer(r)
+			defer ts.Close()
+
+			resp, err := ts.Client().Get(ts.URL + tt.url)
+			require.NoError(t, err)
+			defer resp.Body.Close()
+
+			assert.Equal(t, tt.wantStatus, resp.StatusCode)
+
+			if tt.wantStatus == http.StatusOK {
+				body, err := io.ReadAll(resp.Body)
+				require.NoError(t, err)
+
+				var samples []models.Sample
+				require.NoError(t, json.Unmarshal(body, &samples))
+				assert.Len(t, samples, tt.wantLen)
+			}
+		})
+	}
+}
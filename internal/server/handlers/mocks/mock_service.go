@@ -7,6 +7,7 @@ package mocks
 import (
 	context "context"
 	reflect "reflect"
+	time "time"
 
 	models "github.com/Pro100x3mal/go-musthave-metrics/internal/server/models"
 	gomock "github.com/golang/mock/gomock"
@@ -36,18 +37,18 @@ func (m *MockMetricsServiceInterface) EXPECT() *MockMetricsServiceInterfaceMockR
 }
 
 // GetAllMetrics mocks base method.
-func (m *MockMetricsServiceInterface) GetAllMetrics(arg0 context.Context) (map[string]string, error) {
+func (m *MockMetricsServiceInterface) GetAllMetrics(arg0 context.Context, arg1 models.Labels) (map[string]string, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetAllMetrics", arg0)
+	ret := m.ctrl.Call(m, "GetAllMetrics", arg0, arg1)
 	ret0, _ := ret[0].(map[string]string)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // GetAllMetrics indicates an expected call of GetAllMetrics.
-func (mr *MockMetricsServiceInterfaceMockRecorder) GetAllMetrics(arg0 interface{}) *gomock.Call {
+func (mr *MockMetricsServiceInterfaceMockRecorder) GetAllMetrics(arg0, arg1 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAllMetrics", reflect.TypeOf((*MockMetricsServiceInterface)(nil).GetAllMetrics), arg0)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAllMetrics", reflect.TypeOf((*MockMetricsServiceInterface)(nil).GetAllMetrics), arg0, arg1)
 }
 
 // GetJSONMetricValue mocks base method.
@@ -65,19 +66,49 @@ func (mr *MockMetricsServiceInterfaceMockRecorder) GetJSONMetricValue(arg0, arg1
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetJSONMetricValue", reflect.TypeOf((*MockMetricsServiceInterface)(nil).GetJSONMetricValue), arg0, arg1)
 }
 
+// GetMetricHistory mocks base method.
+func (m *MockMetricsServiceInterface) GetMetricHistory(arg0 context.Context, arg1, arg2 string, arg3 models.Labels, arg4, arg5 time.Time, arg6 time.Duration) ([]models.Sample, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetMetricHistory", arg0, arg1, arg2, arg3, arg4, arg5, arg6)
+	ret0, _ := ret[0].([]models.Sample)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetMetricHistory indicates an expected call of GetMetricHistory.
+func (mr *MockMetricsServiceInterfaceMockRecorder) GetMetricHistory(arg0, arg1, arg2, arg3, arg4, arg5, arg6 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetMetricHistory", reflect.TypeOf((*MockMetricsServiceInterface)(nil).GetMetricHistory), arg0, arg1, arg2, arg3, arg4, arg5, arg6)
+}
+
 // GetMetricValue mocks base method.
-func (m *MockMetricsServiceInterface) GetMetricValue(arg0 context.Context, arg1, arg2 string) (string, error) {
+func (m *MockMetricsServiceInterface) GetMetricValue(arg0 context.Context, arg1, arg2 string, arg3 models.Labels) (string, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetMetricValue", arg0, arg1, arg2)
+	ret := m.ctrl.Call(m, "GetMetricValue", arg0, arg1, arg2, arg3)
 	ret0, _ := ret[0].(string)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // GetMetricValue indicates an expected call of GetMetricValue.
-func (mr *MockMetricsServiceInterfaceMockRecorder) GetMetricValue(arg0, arg1, arg2 interface{}) *gomock.Call {
+func (mr *MockMetricsServiceInterfaceMockRecorder) GetMetricValue(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetMetricValue", reflect.TypeOf((*MockMetricsServiceInterface)(nil).GetMetricValue), arg0, arg1, arg2, arg3)
+}
+
+// ListMetrics mocks base method.
+func (m *MockMetricsServiceInterface) ListMetrics(arg0 context.Context, arg1 models.Labels) ([]models.Metrics, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListMetrics", arg0, arg1)
+	ret0, _ := ret[0].([]models.Metrics)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListMetrics indicates an expected call of ListMetrics.
+func (mr *MockMetricsServiceInterfaceMockRecorder) ListMetrics(arg0, arg1 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetMetricValue", reflect.TypeOf((*MockMetricsServiceInterface)(nil).GetMetricValue), arg0, arg1, arg2)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListMetrics", reflect.TypeOf((*MockMetricsServiceInterface)(nil).ListMetrics), arg0, arg1)
 }
 
 // PingCheck mocks base method.
@@ -122,6 +153,35 @@ func (mr *MockMetricsServiceInterfaceMockRecorder) UpdateJSONMetrics(arg0, arg1
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateJSONMetrics", reflect.TypeOf((*MockMetricsServiceInterface)(nil).UpdateJSONMetrics), arg0, arg1)
 }
 
+// UpdateJSONMetricsIdempotent mocks base method.
+func (m *MockMetricsServiceInterface) UpdateJSONMetricsIdempotent(arg0 context.Context, arg1 string, arg2 []models.Metrics) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateJSONMetricsIdempotent", arg0, arg1, arg2)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateJSONMetricsIdempotent indicates an expected call of UpdateJSONMetricsIdempotent.
+func (mr *MockMetricsServiceInterfaceMockRecorder) UpdateJSONMetricsIdempotent(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateJSONMetricsIdempotent", reflect.TypeOf((*MockMetricsServiceInterface)(nil).UpdateJSONMetricsIdempotent), arg0, arg1, arg2)
+}
+
+// UpdateJSONMetricsWithChanges mocks base method.
+func (m *MockMetricsServiceInterface) UpdateJSONMetricsWithChanges(arg0 context.Context, arg1 string, arg2 []models.Metrics) ([]models.MetricChange, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateJSONMetricsWithChanges", arg0, arg1, arg2)
+	ret0, _ := ret[0].([]models.MetricChange)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UpdateJSONMetricsWithChanges indicates an expected call of UpdateJSONMetricsWithChanges.
+func (mr *MockMetricsServiceInterfaceMockRecorder) UpdateJSONMetricsWithChanges(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateJSONMetricsWithChanges", reflect.TypeOf((*MockMetricsServiceInterface)(nil).UpdateJSONMetricsWithChanges), arg0, arg1, arg2)
+}
+
 // UpdateMetricFromParams mocks base method.
 func (m *MockMetricsServiceInterface) UpdateMetricFromParams(arg0 context.Context, arg1, arg2, arg3 string) error {
 	m.ctrl.T.Helper()
@@ -0,0 +1,192 @@
+package handlers
+
+import (
+	"bufio"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/Pro100x3mal/go-musthave-metrics/internal/server/models"
+	"go.uber.org/zap"
+)
+
+// PrometheusContentType is the content type of the Prometheus text exposition format 0.0.4.
+const PrometheusContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// PrometheusMetricsHandler exposes all stored metrics in the Prometheus text exposition format 0.0.4.
+// It accepts HTTP GET requests to the "/metrics" endpoint with an optional labels=name=value,... query parameter
+// restricting the output to series carrying all given labels.
+// Metric names are sanitized to match [a-zA-Z_:][a-zA-Z0-9_:]*; series of one name are grouped under a single TYPE line.
+// Histograms are exposed as cumulative name_bucket{le="..."} series followed by name_sum and name_count.
+// If metrics of different types map to the same name, or several series map to the same name and labels, only the first one is exposed.
+// Returns 200 OK with the exposition on success, 400 Bad Request for invalid labels, 500 Internal Server Error on failure.
+func (mh *MetricsHandler) PrometheusMetricsHandler(w http.ResponseWriter, r *http.Request) {
+	matchers, err := models.ParseLabels(r.URL.Query().Get("labels"))
+	if err != nil {
+		mh.writeError(w, err, "failed to get metrics")
+		return
+	}
+
+	list, err := mh.reader.ListMetrics(r.Context(), matchers)
+	if err != nil {
+		mh.writeError(w, err, "failed to get metrics")
+		return
+	}
+
+	var families []*prometheusFamily
+	byName := make(map[string]*prometheusFamily, len(list))
+	seen := make(map[string]struct{}, len(list))
+	for _, m := range list {
+		name := sanitizePrometheusName(m.ID)
+		series := name + formatPrometheusLabels(m.Labels)
+
+		var samples []string
+		switch {
+		case m.MType == models.Gauge && m.Value != nil:
+			samples = []string{series + " " + formatPrometheusFloat(*m.Value)}
+		case m.MType == models.Counter && m.Delta != nil:
+			samples = []string{series + " " + strconv.FormatInt(*m.Delta, 10)}
+		case m.MType == models.Histogram && m.Histogram != nil:
+			samples = formatPrometheusHistogram(name, m.Labels, m.Histogram)
+		default:
+			continue
+		}
+
+		family, ok := byName[name]
+		if !ok {
+			family = &prometheusFamily{name: name, mType: m.MType}
+			byName[name] = family
+			families = append(families, family)
+		}
+		if family.mType != m.MType {
+			mh.logger.Debug("skipping metric with conflicting Prometheus type", zap.String("metric", m.ID), zap.String("name", name))
+			continue
+		}
+
+		if _, ok = seen[series]; ok {
+			mh.logger.Debug("skipping metric with conflicting Prometheus name", zap.String("metric", m.ID), zap.String("name", name))
+			continue
+		}
+		seen[series] = struct{}{}
+		family.samples = append(family.samples, samples...)
+	}
+
+	w.Header().Set("Content-Type", PrometheusContentType)
+	w.WriteHeader(http.StatusOK)
+
+	bw := bufio.NewWriter(w)
+	for _, family := range families {
+		bw.WriteString("# TYPE ")
+		bw.WriteString(family.name)
+		bw.WriteByte(' ')
+		bw.WriteString(family.mType)
+		bw.WriteByte('\n')
+		for _, sample := range family.samples {
+			bw.WriteString(sample)
+			bw.WriteByte('\n')
+		}
+	}
+
+	if err = bw.Flush(); err != nil {
+		mh.logger.Error("failed to write response", zap.Error(err))
+	}
+}
+
+type prometheusFamily struct {
+	name    string
+	mType   string
+	samples []string
+}
+
+func formatPrometheusHistogram(name string, labels models.Labels, h *models.HistogramValue) []string {
+	samples := make([]string, 0, len(h.Counts)+2)
+
+	bucketLabels := make(models.Labels, len(labels)+1)
+	for k, v := range labels {
+		bucketLabels[k] = v
+	}
+
+	var cumulative uint64
+	for i, count := range h.Counts {
+		cumulative += count
+		le := "+Inf"
+		if i < len(h.Bounds) {
+			le = formatPrometheusFloat(h.Bounds[i])
+		}
+		bucketLabels["le"] = le
+		samples = append(samples, name+"_bucket"+formatPrometheusLabels(bucketLabels)+" "+strconv.FormatUint(cumulative, 10))
+	}
+
+	series := formatPrometheusLabels(labels)
+	samples = append(samples,
+		name+"_sum"+series+" "+formatPrometheusFloat(h.Sum),
+		name+"_count"+series+" "+strconv.FormatUint(h.Count, 10),
+	)
+	return samples
+}
+
+func formatPrometheusLabels(labels models.Labels) string {
+	if len(labels) == 0 {
+		return ""
+	}
+
+	names := make([]string, 0, len(labels))
+	for name := range labels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var sb strings.Builder
+	sb.WriteByte('{')
+	for i, name := range names {
+		if i > 0 {
+			sb.WriteByte(',')
+		}
+		sb.WriteString(name)
+		sb.WriteString(`="`)
+		sb.WriteString(prometheusLabelEscaper.Replace(labels[name]))
+		sb.WriteByte('"')
+	}
+	sb.WriteByte('}')
+	return sb.String()
+}
+
+var prometheusLabelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func sanitizePrometheusName(name string) string {
+	if name == "" {
+		return "_"
+	}
+
+	var sb strings.Builder
+	sb.Grow(len(name) + 1)
+	for i, c := range name {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c == '_', c == ':':
+			sb.WriteRune(c)
+		case c >= '0' && c <= '9':
+			if i == 0 {
+				sb.WriteByte('_')
+			}
+			sb.WriteRune(c)
+		default:
+			sb.WriteByte('_')
+		}
+	}
+	return sb.String()
+}
+
+func formatPrometheusFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	default:
+		return strconv.FormatFloat(v, 'g', -1, 64)
+	}
+}
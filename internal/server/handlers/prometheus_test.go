@@ -0,0 +1,149 @@
+package handlers
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	mocksvc "github.com/Pro100x3mal/go-musthave-metrics/internal/server/handlers/mocks"
+	"github.com/Pro100x3mal/go-musthave-metrics/internal/server/models"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPrometheusMetricsHandler(t *testing.T) {
+	value := 3.5
+	delta := int64(42)
+	conflicting := 1.0
+
+	tests := []struct {
+		name       string
+		query      string
+		gzip       bool
+		setupMock  func(*mocksvc.MockMetricsServiceInterface)
+		wantStatus int
+		wantBody   string
+	}{
+		{
+			name: "error",
+			setupMock: func(m *mocksvc.MockMetricsServiceInterface) {
+				m.EXPECT().ListMetrics(gomock.Any(), models.Labels(nil)).Return(nil, assert.AnError)
+			},
+			wantStatus: http.StatusInternalServerError,
+		},
+		{
+			name: "empty",
+			setupMock: func(m *mocksvc.MockMetricsServiceInterface) {
+				m.EXPECT().ListMetrics(gomock.Any(), models.Labels(nil)).Return([]models.Metrics{}, nil)
+			},
+			wantStatus: http.StatusOK,
+			wantBody:   "",
+		},
+		{
+			name: "gauges and counters with sanitized names",
+			gzip: true,
+			setupMock: func(m *mocksvc.MockMetricsServiceInterface) {
+				m.EXPECT().ListMetrics(gomock.Any(), models.Labels(nil)).Return([]models.Metrics{
+					{ID: "PollCount", MType: models.Counter, Delta: &delta},
+					{ID: "1cpu.load-avg", MType: models.Gauge, Value: &value},
+					{ID: "1cpu_load_avg", MType: models.Gauge, Value: &conflicting},
+				}, nil)
+			},
+			wantStatus: http.StatusOK,
+			wantBody: "# TYPE PollCount counter\n" +
+				"PollCount 42\n" +
+				"# TYPE _1cpu_load_avg gauge\n" +
+				"_1cpu_load_avg 3.5\n",
+		},
+		{
+			name:  "labeled series grouped by name",
+			query: "?labels=dc=eu",
+			setupMock: func(m *mocksvc.MockMetricsServiceInterface) {
+				m.EXPECT().ListMetrics(gomock.Any(), models.Labels{"dc": "eu"}).Return([]models.Metrics{
+					{ID: "Load", MType: models.Counter, Delta: &delta, Labels: models.Labels{"dc": "eu"}},
+					{ID: "Load", MType: models.Gauge, Value: &value, Labels: models.Labels{"dc": "eu", "host": "h1"}},
+					{ID: "Load", MType: models.Gauge, Value: &conflicting, Labels: models.Labels{"dc": "eu", "path": "a\"b\\"}},
+				}, nil)
+			},
+			wantStatus: http.StatusOK,
+			wantBody: "# TYPE Load counter\n" +
+				"Load{dc=\"eu\"} 42\n",
+		},
+		{
+			name: "histogram",
+			setupMock: func(m *mocksvc.MockMetricsServiceInterface) {
+				m.EXPECT().ListMetrics(gomock.Any(), models.Labels(nil)).Return([]models.Metrics{
+					{ID: "latency", MType: models.Histogram, Labels: models.Labels{"host": "h1"}, Histogram: &models.HistogramValue{
+						Bounds: []float64{0.1, 1},
+						Counts: []uint64{2, 1, 1},
+						Sum:    3.25,
+						Count:  4,
+					}},
+				}, nil)
+			},
+			wantStatus: http.StatusOK,
+			wantBody: "# TYPE latency histogram\n" +
+				"latency_bucket{host=\"h1\",le=\"0.1\"} 2\n" +
+				"latency_bucket{host=\"h1\",le=\"1\"} 3\n" +
+				"latency_bucket{host=\"h1\",le=\"+Inf\"} 4\n" +
+				"latency_sum{host=\"h1\"} 3.25\n" +
+				"latency_count{host=\"h1\"} 4\n",
+		},
+		{
+			name:       "invalid labels",
+			query:      "?labels=host",
+			setupMock:  func(m *mocksvc.MockMetricsServiceInterface) {},
+			wantStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := setupTestHandler(t, tt.setupMock)
+			ts := httptest.NewServer(r)
+			defer ts.Close()
+
+			req, err := http.NewRequest(http.MethodGet, ts.URL+"/metrics"+tt.query, nil)
+			require.NoError(t, err)
+			if tt.gzip {
+				req.Header.Set("Accept-Encoding", "gzip")
+			}
+
+			resp, err := ts.Client().Do(req)
+			require.NoError(t, err)
+			defer resp.Body.Close()
+
+			assert.Equal(t, tt.wantStatus, resp.StatusCode)
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+			assert.Equal(t, PrometheusContentType, resp.Header.Get("Content-Type"))
+
+			var body io.Reader = resp.Body
+			if tt.gzip {
+				require.Equal(t, "gzip", resp.Header.Get("Content-Encoding"))
+				gzr, err := gzip.NewReader(resp.Body)
+				require.NoError(t, err)
+				defer gzr.Close()
+				body = gzr
+			}
+
+			data, err := io.ReadAll(body)
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantBody, string(data))
+		})
+	}
+}
+
+func TestFormatPrometheusLabels(t *testing.T) {
+	assert.Equal(t, "", formatPrometheusLabels(nil))
+	assert.Equal(t, `{dc="eu",path="a\\b\"c\nd"}`, formatPrometheusLabels(models.Labels{"path": "a\\b\"c\nd", "dc": "eu"}))
+}
+
+func TestFormatPrometheusFloat(t *testing.T) {
+	assert.Equal(t, "0.1", formatPrometheusFloat(0.1))
+	assert.Equal(t, "1e+21", formatPrometheusFloat(1e21))
+}
@@ -8,6 +8,7 @@ import (
 )
 
 func initRoutes(r *chi.Mux, mh *MetricsHandler) {
+	r.Use(middlewares.RequestID)
 	r.Use(middlewares.NewLoggerHandler(mh.logger.With(zap.String("component", "http_logger"))).Middleware)
 	r.Use(middlewares.NewSignHandler(mh.logger.With(zap.String("component", "http_sign")), mh.cfg.Key).Middleware)
 	r.Use(middlewares.NewDecryptHandler(mh.logger.With(zap.String("component", "http_decrypt")), mh.privateKey).Middleware)
@@ -15,11 +16,16 @@ func initRoutes(r *chi.Mux, mh *MetricsHandler) {
 
 	r.Get("/", mh.ListAllMetricsHandler)
 	r.Get("/ping", mh.PingDBHandler)
+	r.Get("/alerts", mh.ListAlertsHandler)
+	r.With(middlewares.NewAdminHandler(mh.logger.With(zap.String("component", "http_admin")), mh.cfg.AdminToken).Middleware).
+		Get("/audit", mh.ListAuditHandler)
+	r.Get("/metrics", mh.PrometheusMetricsHandler)
 	r.Post("/updates/", mh.UpdateBatchJSONHandler)
 	r.Route("/value", func(r chi.Router) {
 		r.Post("/", mh.GetJSONMetricHandler)
 		r.Get("/{mType}/{mName}", mh.GetMetricHandler)
 	})
+	r.Get("/history/{mType}/{mName}", mh.GetMetricHistoryHandler)
 	r.Route("/update", func(r chi.Router) {
 		r.Post("/", mh.UpdateJSONHandler)
 		r.Post("/{mType}/{mName}/{mValue}", mh.UpdateHandler)
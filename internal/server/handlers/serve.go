@@ -8,6 +8,7 @@ import (
 	"net/http"
 	"time"
 
+	"github.com/Pro100x3mal/go-musthave-metrics/internal/server/alerting"
 	"github.com/Pro100x3mal/go-musthave-metrics/internal/server/configs"
 	"github.com/Pro100x3mal/go-musthave-metrics/internal/server/infrastructure/audit"
 	"github.com/Pro100x3mal/go-musthave-metrics/internal/server/models"
@@ -17,9 +18,11 @@ import (
 
 // MetricsServiceReader provides read-only operations for metrics retrieval.
 type MetricsServiceReader interface {
-	GetMetricValue(ctx context.Context, mType, mName string) (string, error)
+	GetMetricValue(ctx context.Context, mType, mName string, labels models.Labels) (string, error)
 	GetJSONMetricValue(ctx context.Context, metric *models.Metrics) (*models.Metrics, error)
-	GetAllMetrics(ctx context.Context) (map[string]string, error)
+	GetAllMetrics(ctx context.Context, matchers models.Labels) (map[string]string, error)
+	ListMetrics(ctx context.Context, matchers models.Labels) ([]models.Metrics, error)
+	GetMetricHistory(ctx context.Context, mType, mName string, labels models.Labels, from, to time.Time, step time.Duration) ([]models.Sample, error)
 }
 
 // MetricsServiceWriter provides write operations for metrics updates.
@@ -27,6 +30,10 @@ type MetricsServiceWriter interface {
 	UpdateMetricFromParams(ctx context.Context, mType, mName, mValue string) error
 	UpdateJSONMetric(ctx context.Context, metric *models.Metrics) error
 	UpdateJSONMetrics(ctx context.Context, metrics []models.Metrics) error
+	UpdateJSONMetricsIdempotent(ctx context.Context, key string, metrics []models.Metrics) error
+	// UpdateJSONMetricsWithChanges applies the metrics, honouring key if it is not empty, and returns the changes
+	// they made with the previous values of their series, for auditing.
+	UpdateJSONMetricsWithChanges(ctx context.Context, key string, metrics []models.Metrics) ([]models.MetricChange, error)
 }
 
 // MetricsServicePinger provides health check functionality for the underlying storage.
@@ -41,6 +48,16 @@ type MetricsServiceInterface interface {
 	MetricsServicePinger
 }
 
+// AlertsProvider provides the alerts that are currently pending or firing.
+type AlertsProvider interface {
+	ActiveAlerts() []alerting.Alert
+}
+
+// AuditLogQuerier searches the audit log.
+type AuditLogQuerier interface {
+	Query(ctx context.Context, q audit.Query) ([]models.AuditEvent, error)
+}
+
 // MetricsHandler handles HTTP requests for metrics operations.
 type MetricsHandler struct {
 	reader       MetricsServiceReader
@@ -51,6 +68,8 @@ type MetricsHandler struct {
 	auditManager audit.Publisher
 	tmpl         *template.Template
 	privateKey   *rsa.PrivateKey
+	alerts       AlertsProvider
+	auditLog     AuditLogQuerier
 }
 
 // NewMetricsHandler creates a new MetricsHandler with the provided service, logger, configuration and audit manager.
@@ -65,12 +84,21 @@ func NewMetricsHandler(service MetricsServiceInterface, logger *zap.Logger, cfg
 		privateKey:   privateKey,
 	}
 
+	if cfg.AuditFile != "" {
+		mh.auditLog = audit.NewLogReader(cfg.AuditFile)
+	}
+
 	if p, ok := service.(MetricsServicePinger); ok {
 		mh.pinger = p
 	}
 	return mh
 }
 
+// SetAlertsProvider enables the /alerts endpoint backed by the provided alerting engine.
+func (mh *MetricsHandler) SetAlertsProvider(alerts AlertsProvider) {
+	mh.alerts = alerts
+}
+
 // StartServer starts the HTTP server and blocks until the context is cancelled or an error occurs.
 // It gracefully shuts down the server when the context is cancelled.
 func (mh *MetricsHandler) StartServer(ctx context.Context) error {
@@ -14,67 +14,24 @@ import (
 	"time"
 
 	"github.com/Pro100x3mal/go-musthave-metrics/internal/server/models"
-	"go.uber.org/zap"
 )
 
 type Observer interface {
 	Notify(ctx context.Context, event *models.AuditEvent) error
 }
 
+// BatchObserver is implemented by observers that deliver several events at once more efficiently than one by one.
+type BatchObserver interface {
+	Observer
+	NotifyBatch(ctx context.Context, events []*models.AuditEvent) error
+}
+
 type Publisher interface {
 	Attach(observer Observer)
 	NotifyAll(ctx context.Context, event *models.AuditEvent)
 	HasObservers() bool
 }
 
-type AuditManager struct {
-	observers []Observer
-	mu        *sync.RWMutex
-	logger    *zap.Logger
-}
-
-func NewAuditManager(logger *zap.Logger) *AuditManager {
-	return &AuditManager{
-		observers: make([]Observer, 0),
-		mu:        &sync.RWMutex{},
-		logger:    logger,
-	}
-}
-
-func (am *AuditManager) Attach(observer Observer) {
-	am.mu.Lock()
-	defer am.mu.Unlock()
-	am.observers = append(am.observers, observer)
-}
-
-func (am *AuditManager) NotifyAll(ctx context.Context, event *models.AuditEvent) {
-	notifyCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
-	defer cancel()
-
-	am.mu.RLock()
-	observers := make([]Observer, len(am.observers))
-	copy(observers, am.observers)
-	am.mu.RUnlock()
-
-	var wg sync.WaitGroup
-	for _, observer := range observers {
-		wg.Add(1)
-		go func(obs Observer) {
-			defer wg.Done()
-			if err := obs.Notify(notifyCtx, event); err != nil {
-				am.logger.Error("failed to notify audit observer", zap.Error(err))
-			}
-		}(observer)
-	}
-	wg.Wait()
-}
-
-func (am *AuditManager) HasObservers() bool {
-	am.mu.RLock()
-	defer am.mu.RUnlock()
-	return len(am.observers) > 0
-}
-
 type lockedWriter struct {
 	mu *sync.Mutex
 	w  io.Writer
@@ -86,41 +43,144 @@ func (l *lockedWriter) Write(p []byte) (int, error) {
 	return l.w.Write(p)
 }
 
+// FileAuditObserver appends events to a file as a hash chain: every record carries its sequence number
+// and the hash of the previous record, so removed or edited records can be detected with VerifyChain.
+// The file can be rotated by size and age; the chain continues across rotated segments.
 type FileAuditObserver struct {
 	writer *lockedWriter
 	file   *os.File
+	path   string
+	key    string
+	// mu serializes linking, rotating and writing so that records are written in sequence order.
+	mu    sync.Mutex
+	chain chainState
+	// torn is set after a partial write that may have left an incomplete line at the end of the file.
+	torn     bool
+	rotation RotationOptions
+	size     int64
+	openedAt time.Time
+	// cleanup tracks background compression and removal of rotated segments, serialized by cleanupMu.
+	cleanup   sync.WaitGroup
+	cleanupMu sync.Mutex
 }
 
-func NewFileAuditObserver(filePath string) (*FileAuditObserver, error) {
-	f, err := os.OpenFile(filePath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+// NewFileAuditObserver opens the audit file for appending and continues the chain from its last record.
+// A non-empty key links records with HMAC-SHA256 instead of plain SHA-256.
+// Unparsable records at the end of the file, e.g. one torn by a crash mid-write, do not prevent the start:
+// the chain continues from the last parsable record and the skipped records are reported to rotation.OnError.
+// VerifyChain still reports them as a break.
+func NewFileAuditObserver(filePath, key string, rotation RotationOptions) (*FileAuditObserver, error) {
+	chain, skipped, err := restoreChainState(filePath, key)
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := os.OpenFile(filePath, os.O_APPEND|os.O_CREATE|os.O_RDWR, 0644)
 	if err != nil {
 		return nil, fmt.Errorf("failed to open audit file: %w", err)
 	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("failed to stat audit file: %w", err)
+	}
+	size, err := terminateLastLine(f, info.Size())
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
 
-	return &FileAuditObserver{
+	fao := &FileAuditObserver{
 		writer: &lockedWriter{
 			mu: &sync.Mutex{},
-			w:  f,
 		},
-		file: f,
-	}, nil
+		path:     filePath,
+		key:      key,
+		chain:    chain,
+		rotation: rotation,
+	}
+	fao.setFile(f, size, segmentStart(info))
+	if skipped > 0 {
+		fao.reportError(fmt.Errorf("skipped %d unparsable audit records at the end of the log, the chain continues from seq %d", skipped, chain.seq))
+	}
+	return fao, nil
 }
 
+// terminateLastLine appends a newline to f if its last line is incomplete, e.g. torn by a crash mid-write,
+// so that the next record starts on a line of its own. It returns the new size of f.
+func terminateLastLine(f *os.File, size int64) (int64, error) {
+	if size == 0 {
+		return 0, nil
+	}
+	last := make([]byte, 1)
+	if _, err := f.ReadAt(last, size-1); err != nil {
+		return size, fmt.Errorf("failed to read audit file: %w", err)
+	}
+	if last[0] == '\n' {
+		return size, nil
+	}
+	if _, err := f.Write([]byte{'\n'}); err != nil {
+		return size, fmt.Errorf("failed to write audit file: %w", err)
+	}
+	return size + 1, nil
+}
+
+// Close closes the audit file and waits for compression of rotated segments to finish.
 func (fao *FileAuditObserver) Close() error {
 	fao.writer.mu.Lock()
-	defer fao.writer.mu.Unlock()
-	return fao.file.Close()
+	err := fao.file.Close()
+	fao.writer.mu.Unlock()
+
+	fao.cleanup.Wait()
+	return err
 }
 
-func (fao *FileAuditObserver) Notify(_ context.Context, event *models.AuditEvent) error {
-	data, err := json.Marshal(event)
-	if err != nil {
-		return fmt.Errorf("failed to marshal audit event: %w", err)
+func (fao *FileAuditObserver) Notify(ctx context.Context, event *models.AuditEvent) error {
+	return fao.NotifyBatch(ctx, []*models.AuditEvent{event})
+}
+
+// NotifyBatch links the events to the chain and appends them with a single write,
+// so batches from concurrent workers never interleave. A batch is never split across segments:
+// the file is rotated before the write if needed.
+// The events themselves are not modified, as they are shared with other observers.
+func (fao *FileAuditObserver) NotifyBatch(_ context.Context, events []*models.AuditEvent) error {
+	fao.mu.Lock()
+	defer fao.mu.Unlock()
+
+	var buf bytes.Buffer
+	if fao.torn {
+		buf.WriteByte('\n')
+	}
+	chain := fao.chain
+	for _, event := range events {
+		linked, data, err := chain.link(event)
+		if err != nil {
+			return err
+		}
+		chain.advance(linked.Seq, data, fao.key)
+		buf.Write(data)
+		buf.WriteByte('\n')
+	}
+
+	if fao.shouldRotate(int64(buf.Len())) {
+		if err := fao.rotate(); err != nil {
+			return err
+		}
 	}
 
-	if _, err = fao.writer.Write(append(data, '\n')); err != nil {
+	n, err := fao.writer.Write(buf.Bytes())
+	fao.size += int64(n)
+	if err != nil {
+		// A partial write may have extended the chain; continue from whatever reached the file.
+		if state, _, syncErr := readChainState(fao.path, fao.key); syncErr == nil {
+			fao.chain = state
+		}
+		// Start the next write on a new line in case this one stopped mid-record.
+		fao.torn = n > 0
 		return fmt.Errorf("failed to write audit event: %w", err)
 	}
+	fao.chain = chain
+	fao.torn = false
 
 	return nil
 }
@@ -166,23 +226,29 @@ func (hao *HTTPAuditObserver) Notify(ctx context.Context, event *models.AuditEve
 }
 
 func NewAuditEventFromMetrics(metrics []models.Metrics, ipAddress string) *models.AuditEvent {
-	metricNames := make([]string, 0, len(metrics))
-	for _, m := range metrics {
-		metricNames = append(metricNames, m.ID)
-	}
-
-	return &models.AuditEvent{
-		Timestamp: time.Now().Unix(),
-		Metrics:   metricNames,
-		IPAddress: ipAddress,
+	changes := make([]models.MetricChange, 0, len(metrics))
+	for i := range metrics {
+		changes = append(changes, models.NewMetricChange(&metrics[i]))
 	}
+	return NewAuditEventFromChanges(changes, ipAddress)
 }
 
 func NewAuditEventFromMetric(metric *models.Metrics, ipAddress string) *models.AuditEvent {
+	return NewAuditEventFromChanges([]models.MetricChange{models.NewMetricChange(metric)}, ipAddress)
+}
+
+// NewAuditEventFromChanges returns an event for the changes, listing their metric names in the original Metrics field.
+func NewAuditEventFromChanges(changes []models.MetricChange, ipAddress string) *models.AuditEvent {
+	metricNames := make([]string, 0, len(changes))
+	for _, c := range changes {
+		metricNames = append(metricNames, c.ID)
+	}
+
 	return &models.AuditEvent{
 		Timestamp: time.Now().Unix(),
-		Metrics:   []string{metric.ID},
+		Metrics:   metricNames,
 		IPAddress: ipAddress,
+		Changes:   changes,
 	}
 }
 
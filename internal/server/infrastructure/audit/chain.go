@@ -0,0 +1,237 @@
+package audit
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/Pro100x3mal/go-musthave-metrics/internal/server/models"
+)
+
+// maxRecordSize limits the length of a single audit log line.
+const maxRecordSize = 1 << 20
+
+// ChainHash returns the link to a record of the audit log: the hex SHA-256 of the record line without the trailing newline,
+// or its HMAC-SHA256 if key is not empty. The next record stores it in prev_hash.
+func ChainHash(record []byte, key string) string {
+	if key == "" {
+		sum := sha256.Sum256(record)
+		return hex.EncodeToString(sum[:])
+	}
+
+	h := hmac.New(sha256.New, []byte(key))
+	h.Write(record)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// chainState is the position of the last record of a chained log.
+type chainState struct {
+	seq      uint64
+	lastHash string
+}
+
+// link assigns the next sequence number and the previous record hash to a copy of event and returns the record line.
+// The state is not advanced; call advance once the record is written.
+func (cs chainState) link(event *models.AuditEvent) (models.AuditEvent, []byte, error) {
+	linked := *event
+	linked.Seq = cs.seq + 1
+	linked.PrevHash = cs.lastHash
+
+	data, err := json.Marshal(&linked)
+	if err != nil {
+		return linked, nil, fmt.Errorf("failed to marshal audit event: %w", err)
+	}
+	return linked, data, nil
+}
+
+func (cs *chainState) advance(seq uint64, record []byte, key string) {
+	cs.seq = seq
+	cs.lastHash = ChainHash(record, key)
+}
+
+// readChainState restores the chain position from the last parsable record of the file at path.
+// A missing or empty file starts a new chain. A last record written before chaining was enabled
+// is linked to, and the chain continues from sequence number 1.
+// Unparsable records at the end of the file, e.g. one torn by a crash mid-write, are skipped and counted in skipped;
+// the chain continues from the record before them.
+func readChainState(path, key string) (state chainState, skipped int, err error) {
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return chainState{}, 0, nil
+	}
+	if err != nil {
+		return chainState{}, 0, fmt.Errorf("failed to open audit file: %w", err)
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return chainState{}, 0, fmt.Errorf("failed to stat audit file: %w", err)
+	}
+	end := info.Size()
+	for {
+		record, start, err := lastLine(f, end)
+		if err != nil {
+			return chainState{}, skipped, fmt.Errorf("failed to read last audit record: %w", err)
+		}
+		if len(record) == 0 {
+			return chainState{}, skipped, nil
+		}
+		if state, err = parseChainState(record, key); err == nil {
+			return state, skipped, nil
+		}
+		skipped++
+		end = start
+	}
+}
+
+func parseChainState(record []byte, key string) (chainState, error) {
+	var event models.AuditEvent
+	if err := json.Unmarshal(record, &event); err != nil {
+		return chainState{}, fmt.Errorf("failed to parse last audit record: %w", err)
+	}
+	return chainState{seq: event.Seq, lastHash: ChainHash(record, key)}, nil
+}
+
+// lastLine returns the last non-empty line of f before offset end, without the trailing newline,
+// and the offset the line starts at.
+func lastLine(f *os.File, end int64) ([]byte, int64, error) {
+	const chunkSize = 4096
+	var tail []byte
+	for end > 0 {
+		start := max(end-chunkSize, 0)
+		chunk := make([]byte, end-start)
+		if _, err := f.ReadAt(chunk, start); err != nil && !errors.Is(err, io.EOF) {
+			return nil, 0, err
+		}
+		tail = append(chunk, tail...)
+		end = start
+
+		trimmed := bytes.TrimRight(tail, "\n")
+		if i := bytes.LastIndexByte(trimmed, '\n'); i >= 0 {
+			return trimmed[i+1:], end + int64(i) + 1, nil
+		}
+		if len(tail) > maxRecordSize {
+			return nil, 0, fmt.Errorf("audit record exceeds %d bytes", maxRecordSize)
+		}
+	}
+	return bytes.TrimRight(tail, "\n"), 0, nil
+}
+
+// ChainReport is the result of walking a chained audit log.
+type ChainReport struct {
+	// Records is the number of records checked, including the unchained ones.
+	Records int
+	// Unchained is the number of leading records written before chaining was enabled.
+	Unchained int
+	// FirstSeq and LastSeq are the sequence numbers of the first and last chained records.
+	FirstSeq uint64
+	LastSeq  uint64
+	// LastHash is the hash of the last record checked.
+	LastHash string
+	// Broken describes the first broken or missing link; it is nil if the chain is intact.
+	Broken *ChainBreak
+}
+
+// ChainStart is the position a verified log is expected to continue the chain from.
+// The zero value expects the log to begin the chain, with sequence number 1.
+type ChainStart struct {
+	// Seq is the sequence number of the last record before the log.
+	Seq uint64
+	// PrevHash is the hash of that record. If it is empty, only the sequence number of the first record is checked,
+	// e.g. when the segments holding the earlier records were removed.
+	PrevHash string
+}
+
+// Next returns the position the log following the checked one, e.g. the next rotated segment, continues from.
+// If no records were checked, that is start.
+func (r *ChainReport) Next(start ChainStart) ChainStart {
+	if r.Records == 0 {
+		return start
+	}
+	return ChainStart{Seq: r.LastSeq, PrevHash: r.LastHash}
+}
+
+// ChainBreak describes the first record whose link to the previous record does not hold.
+type ChainBreak struct {
+	Line   int
+	Seq    uint64
+	Reason string
+}
+
+func (b *ChainBreak) String() string {
+	return fmt.Sprintf("line %d (seq %d): %s", b.Line, b.Seq, b.Reason)
+}
+
+// VerifyChain walks an audit log and reports the first record that is unparsable, out of sequence
+// or whose prev_hash does not match the hash of the previous record.
+// The chain must continue from start: a log whose chain starts later than expected, e.g. because its head
+// was removed, is reported as broken. Errors are returned only for read failures.
+func VerifyChain(r io.Reader, key string, start ChainStart) (*ChainReport, error) {
+	report := &ChainReport{}
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxRecordSize)
+
+	var (
+		prevHash = start.PrevHash
+		prevSeq  = start.Seq
+		chained  = start.Seq > 0
+		line     int
+	)
+	for scanner.Scan() {
+		line++
+		record := scanner.Bytes()
+		if len(bytes.TrimSpace(record)) == 0 {
+			continue
+		}
+		report.Records++
+
+		var event models.AuditEvent
+		if err := json.Unmarshal(record, &event); err != nil {
+			report.Broken = &ChainBreak{Line: line, Reason: "unparsable record: " + err.Error()}
+			return report, nil
+		}
+
+		// The link of the first record is not checked if the hash of the record before the log is unknown.
+		checkHash := prevHash != "" || report.FirstSeq > 0 || start.Seq == 0
+		switch {
+		case event.Seq == 0 && !chained:
+			report.Unchained++
+		case event.Seq == 0:
+			report.Broken = &ChainBreak{Line: line, Reason: "record without sequence number inside the chain"}
+			return report, nil
+		case event.Seq != prevSeq+1:
+			reason := fmt.Sprintf("missing link: expected seq %d", prevSeq+1)
+			if report.FirstSeq == 0 {
+				reason = fmt.Sprintf("chain starts at seq %d, expected seq %d: earlier records are missing", event.Seq, prevSeq+1)
+			}
+			report.Broken = &ChainBreak{Line: line, Seq: event.Seq, Reason: reason}
+			return report, nil
+		case checkHash && event.PrevHash != prevHash:
+			report.Broken = &ChainBreak{Line: line, Seq: event.Seq, Reason: "prev_hash does not match the previous record"}
+			return report, nil
+		}
+
+		if event.Seq > 0 {
+			chained = true
+			if report.FirstSeq == 0 {
+				report.FirstSeq = event.Seq
+			}
+			prevSeq = event.Seq
+			report.LastSeq = event.Seq
+		}
+		prevHash = ChainHash(record, key)
+		report.LastHash = prevHash
+	}
+	if err := scanner.Err(); err != nil {
+		return report, fmt.Errorf("failed to read audit log: %w", err)
+	}
+	return report, nil
+}
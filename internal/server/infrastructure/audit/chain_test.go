@@ -0,0 +1,249 @@
+package audit
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Pro100x3mal/go-musthave-metrics/internal/server/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeChain(t *testing.T, path, key string, n int) {
+	t.Helper()
+
+	obs, err := NewFileAuditObserver(path, key, RotationOptions{})
+	require.NoError(t, err)
+	for i := 0; i < n; i++ {
+		require.NoError(t, obs.Notify(context.Background(), &models.AuditEvent{
+			Timestamp: int64(i),
+			Metrics:   []string{"Alloc"},
+			IPAddress: "10.0.0.1",
+		}))
+	}
+	require.NoError(t, obs.Close())
+}
+
+func verifyFile(t *testing.T, path, key string) *ChainReport {
+	t.Helper()
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	report, err := VerifyChain(bytes.NewReader(data), key, ChainStart{})
+	require.NoError(t, err)
+	return report
+}
+
+func TestFileAuditObserver_Chain(t *testing.T) {
+	for _, key := range []string{"", "secret"} {
+		t.Run("key="+key, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "audit.json")
+
+			writeChain(t, path, key, 3)
+			// A reopened observer continues the chain of the existing file.
+			writeChain(t, path, key, 2)
+
+			report := verifyFile(t, path, key)
+			assert.Nil(t, report.Broken)
+			assert.Equal(t, 5, report.Records)
+			assert.Equal(t, uint64(1), report.FirstSeq)
+			assert.Equal(t, uint64(5), report.LastSeq)
+
+			if key != "" {
+				report = verifyFile(t, path, "wrong")
+				require.NotNil(t, report.Broken)
+				assert.Equal(t, 2, report.Broken.Line)
+			}
+		})
+	}
+}
+
+func TestFileAuditObserver_DoesNotModifySharedEvent(t *testing.T) {
+	obs, err := NewFileAuditObserver(filepath.Join(t.TempDir(), "audit.json"), "", RotationOptions{})
+	require.NoError(t, err)
+	defer obs.Close()
+
+	event := &models.AuditEvent{Timestamp: 1, Metrics: []string{"Alloc"}}
+	require.NoError(t, obs.Notify(context.Background(), event))
+	assert.Zero(t, event.Seq)
+	assert.Empty(t, event.PrevHash)
+}
+
+func TestVerifyChain(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{"ts":0,"metrics":["legacy"],"ip_address":""}`+"\n"), 0644))
+	writeChain(t, path, "", 4)
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	lines := strings.SplitAfter(strings.TrimSuffix(string(data), "\n"), "\n")
+	require.Len(t, lines, 5)
+
+	tests := []struct {
+		name       string
+		lines      []string
+		wantLine   int
+		wantReason string
+	}{
+		{
+			name:  "intact with unchained prefix",
+			lines: lines,
+		},
+		{
+			name:       "edited record",
+			lines:      []string{lines[0], lines[1], strings.Replace(lines[2], `"10.0.0.1"`, `"10.0.0.2"`, 1), lines[3], lines[4]},
+			wantLine:   4,
+			wantReason: "prev_hash does not match the previous record",
+		},
+		{
+			name:       "removed record",
+			lines:      []string{lines[0], lines[1], lines[3], lines[4]},
+			wantLine:   3,
+			wantReason: "missing link: expected seq 2",
+		},
+		{
+			name:       "edited unchained prefix",
+			lines:      []string{strings.Replace(lines[0], "legacy", "forged", 1), lines[1], lines[2]},
+			wantLine:   2,
+			wantReason: "prev_hash does not match the previous record",
+		},
+		{
+			name:       "removed head",
+			lines:      []string{lines[2], lines[3], lines[4]},
+			wantLine:   1,
+			wantReason: "chain starts at seq 2, expected seq 1",
+		},
+		{
+			name:       "removed unchained prefix",
+			lines:      []string{lines[1], lines[2]},
+			wantLine:   1,
+			wantReason: "prev_hash does not match the previous record",
+		},
+		{
+			name:       "unparsable record",
+			lines:      []string{lines[0], lines[1], "garbage\n"},
+			wantLine:   3,
+			wantReason: "unparsable record",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			report, err := VerifyChain(strings.NewReader(strings.Join(tt.lines, "")), "", ChainStart{})
+			require.NoError(t, err)
+
+			if tt.wantLine == 0 {
+				assert.Nil(t, report.Broken)
+				assert.Equal(t, 1, report.Unchained)
+				assert.Equal(t, uint64(4), report.LastSeq)
+				return
+			}
+			require.NotNil(t, report.Broken)
+			assert.Equal(t, tt.wantLine, report.Broken.Line)
+			assert.Contains(t, report.Broken.Reason, tt.wantReason)
+		})
+	}
+}
+
+func TestVerifyChain_Start(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.json")
+	writeChain(t, path, "", 4)
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	lines := strings.SplitAfter(strings.TrimSuffix(string(data), "\n"), "\n")
+	require.Len(t, lines, 4)
+	tail := strings.Join(lines[2:], "")
+	hash := ChainHash([]byte(strings.TrimSuffix(lines[1], "\n")), "")
+
+	tests := []struct {
+		name       string
+		start      ChainStart
+		wantReason string
+	}{
+		{
+			name:  "matching start",
+			start: ChainStart{Seq: 2, PrevHash: hash},
+		},
+		{
+			name:  "start without hash",
+			start: ChainStart{Seq: 2},
+		},
+		{
+			name:       "wrong hash",
+			start:      ChainStart{Seq: 2, PrevHash: ChainHash([]byte("forged"), "")},
+			wantReason: "prev_hash does not match the previous record",
+		},
+		{
+			name:       "wrong seq",
+			start:      ChainStart{Seq: 1, PrevHash: hash},
+			wantReason: "chain starts at seq 3, expected seq 2",
+		},
+		{
+			name:       "no start",
+			wantReason: "chain starts at seq 3, expected seq 1",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			report, err := VerifyChain(strings.NewReader(tail), "", tt.start)
+			require.NoError(t, err)
+
+			if tt.wantReason == "" {
+				assert.Nil(t, report.Broken)
+				assert.Equal(t, uint64(3), report.FirstSeq)
+				assert.Equal(t, uint64(4), report.LastSeq)
+				return
+			}
+			require.NotNil(t, report.Broken)
+			assert.Equal(t, 1, report.Broken.Line)
+			assert.Contains(t, report.Broken.Reason, tt.wantReason)
+		})
+	}
+}
+
+func TestFileAuditObserver_TornLastRecord(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.json")
+	writeChain(t, path, "", 3)
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	lines := strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+
+	// Simulate a crash in the middle of writing the fourth record.
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0644)
+	require.NoError(t, err)
+	_, err = f.WriteString(`{"ts":3,"metrics":["Al`)
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	var reported []error
+	obs, err := NewFileAuditObserver(path, "", RotationOptions{OnError: func(err error) { reported = append(reported, err) }})
+	require.NoError(t, err)
+	require.Len(t, reported, 1)
+	assert.Contains(t, reported[0].Error(), "skipped 1 unparsable audit records")
+
+	require.NoError(t, obs.Notify(context.Background(), &models.AuditEvent{Timestamp: 4}))
+	require.NoError(t, obs.Close())
+
+	// The torn record stays in the log as a break; the new record is written on its own line
+	// and continues the chain from the last complete record.
+	report := verifyFile(t, path, "")
+	require.NotNil(t, report.Broken)
+	assert.Equal(t, 4, report.Broken.Line)
+	assert.Contains(t, report.Broken.Reason, "unparsable record")
+
+	data, err = os.ReadFile(path)
+	require.NoError(t, err)
+	after := strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+	require.Len(t, after, 5)
+	report, err = VerifyChain(strings.NewReader(after[4]+"\n"), "", ChainStart{Seq: 3, PrevHash: ChainHash([]byte(lines[2]), "")})
+	require.NoError(t, err)
+	assert.Nil(t, report.Broken)
+	assert.Equal(t, uint64(4), report.LastSeq)
+}
@@ -0,0 +1,76 @@
+package audit
+
+import (
+	"context"
+	"time"
+
+	"github.com/Pro100x3mal/go-musthave-metrics/internal/server/models"
+	"go.uber.org/zap"
+)
+
+// maxRetentionInterval is the longest pause between two retention runs.
+const maxRetentionInterval = time.Hour
+
+// EventStore persists audit events, e.g. in the audit_events table of the metrics database.
+type EventStore interface {
+	InsertAuditEvents(ctx context.Context, events []*models.AuditEvent) error
+	DeleteAuditEventsBefore(ctx context.Context, t time.Time) (int64, error)
+}
+
+// DBAuditObserver writes audit events to a database, one batch per delivery from the AuditManager.
+type DBAuditObserver struct {
+	store  EventStore
+	logger *zap.Logger
+}
+
+// NewDBAuditObserver returns an observer writing to store.
+func NewDBAuditObserver(store EventStore, logger *zap.Logger) *DBAuditObserver {
+	return &DBAuditObserver{
+		store:  store,
+		logger: logger,
+	}
+}
+
+func (dao *DBAuditObserver) Notify(ctx context.Context, event *models.AuditEvent) error {
+	return dao.NotifyBatch(ctx, []*models.AuditEvent{event})
+}
+
+// NotifyBatch inserts the events in a single transaction.
+func (dao *DBAuditObserver) NotifyBatch(ctx context.Context, events []*models.AuditEvent) error {
+	return dao.store.InsertAuditEvents(ctx, events)
+}
+
+// RunRetention deletes events older than maxAge right away and then periodically, every maxAge but at least
+// once an hour, until ctx is cancelled. Failures are logged and retried on the next run.
+func (dao *DBAuditObserver) RunRetention(ctx context.Context, maxAge time.Duration) {
+	if maxAge <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(min(maxAge, maxRetentionInterval))
+	defer ticker.Stop()
+
+	for {
+		dao.deleteExpired(ctx, maxAge)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (dao *DBAuditObserver) deleteExpired(ctx context.Context, maxAge time.Duration) {
+	cutoff := time.Now().Add(-maxAge)
+	deleted, err := dao.store.DeleteAuditEventsBefore(ctx, cutoff)
+	if err != nil {
+		if ctx.Err() == nil {
+			dao.logger.Error("failed to delete expired audit events", zap.Error(err))
+		}
+		return
+	}
+	if deleted > 0 {
+		dao.logger.Info("deleted expired audit events", zap.Int64("count", deleted), zap.Time("before", cutoff))
+	}
+}
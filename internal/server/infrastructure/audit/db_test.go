@@ -0,0 +1,76 @@
+package audit
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Pro100x3mal/go-musthave-metrics/internal/server/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+type fakeEventStore struct {
+	mu      sync.Mutex
+	batches [][]*models.AuditEvent
+	cutoffs []time.Time
+}
+
+func (s *fakeEventStore) InsertAuditEvents(_ context.Context, events []*models.AuditEvent) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.batches = append(s.batches, events)
+	return nil
+}
+
+func (s *fakeEventStore) DeleteAuditEventsBefore(_ context.Context, t time.Time) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.cutoffs = append(s.cutoffs, t)
+	return 1, nil
+}
+
+func (s *fakeEventStore) retentionRuns() []time.Time {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]time.Time(nil), s.cutoffs...)
+}
+
+func TestDBAuditObserver_WritesBatches(t *testing.T) {
+	store := &fakeEventStore{}
+	opts := testOptions(t)
+	opts.BatchSize = 3
+	opts.BatchWait = time.Second
+	am := NewAuditManager(zap.NewNop(), opts)
+	am.Attach(NewDBAuditObserver(store, zap.NewNop()))
+
+	for i := 0; i < 6; i++ {
+		am.NotifyAll(context.Background(), &models.AuditEvent{Timestamp: int64(i)})
+	}
+	require.NoError(t, am.Shutdown(context.Background()))
+
+	require.Len(t, store.batches, 2)
+	assert.Len(t, store.batches[0], 3)
+	assert.Len(t, store.batches[1], 3)
+}
+
+func TestDBAuditObserver_RunRetention(t *testing.T) {
+	store := &fakeEventStore{}
+	dao := NewDBAuditObserver(store, zap.NewNop())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		dao.RunRetention(ctx, 20*time.Millisecond)
+	}()
+
+	require.Eventually(t, func() bool { return len(store.retentionRuns()) >= 2 }, time.Second, 5*time.Millisecond)
+	cancel()
+	<-done
+
+	cutoff := store.retentionRuns()[0]
+	assert.WithinDuration(t, time.Now().Add(-20*time.Millisecond), cutoff, time.Second)
+}
@@ -0,0 +1,290 @@
+package audit
+
+import (
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"net/netip"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/Pro100x3mal/go-musthave-metrics/internal/server/models"
+)
+
+// ErrInvalidFilter is returned for malformed filter rules.
+var ErrInvalidFilter = errors.New("invalid audit filter")
+
+// FilterRules select the events delivered to one observer. Empty include lists match everything;
+// exclude lists take precedence over include lists.
+type FilterRules struct {
+	// IncludeMetrics and ExcludeMetrics are metric name globs in path.Match syntax.
+	IncludeMetrics []string
+	ExcludeMetrics []string
+	// IncludeIPs and ExcludeIPs are client addresses or CIDR prefixes.
+	IncludeIPs []string
+	ExcludeIPs []string
+	// IncludeTypes and ExcludeTypes are metric types: gauge, counter or histogram.
+	IncludeTypes []string
+	ExcludeTypes []string
+	// SampleRate is the fraction of the remaining events delivered, in (0, 1]; 0 delivers all of them.
+	SampleRate float64
+}
+
+// ParseFilterRules reads rules from a spec of semicolon-separated key=value pairs with comma-separated values, e.g.
+//
+//	metric=Poll*,Random*;exclude_ip=10.0.0.0/8;type=counter;sample=0.1
+//
+// Keys are metric, exclude_metric, ip, exclude_ip, type, exclude_type and sample. An empty spec matches everything.
+func ParseFilterRules(spec string) (FilterRules, error) {
+	var rules FilterRules
+	for _, part := range strings.Split(spec, ";") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		key, value, ok := strings.Cut(part, "=")
+		if !ok {
+			return FilterRules{}, fmt.Errorf("%w: %q is not key=value", ErrInvalidFilter, part)
+		}
+
+		var values []string
+		for _, v := range strings.Split(value, ",") {
+			if v = strings.TrimSpace(v); v != "" {
+				values = append(values, v)
+			}
+		}
+
+		switch strings.TrimSpace(key) {
+		case "metric":
+			rules.IncludeMetrics = append(rules.IncludeMetrics, values...)
+		case "exclude_metric":
+			rules.ExcludeMetrics = append(rules.ExcludeMetrics, values...)
+		case "ip":
+			rules.IncludeIPs = append(rules.IncludeIPs, values...)
+		case "exclude_ip":
+			rules.ExcludeIPs = append(rules.ExcludeIPs, values...)
+		case "type":
+			rules.IncludeTypes = append(rules.IncludeTypes, values...)
+		case "exclude_type":
+			rules.ExcludeTypes = append(rules.ExcludeTypes, values...)
+		case "sample":
+			rate, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+			if err != nil {
+				return FilterRules{}, fmt.Errorf("%w: invalid sample rate %q", ErrInvalidFilter, value)
+			}
+			rules.SampleRate = rate
+		default:
+			return FilterRules{}, fmt.Errorf("%w: unknown key %q", ErrInvalidFilter, key)
+		}
+	}
+	return rules, nil
+}
+
+// Filter is a compiled set of FilterRules.
+type Filter struct {
+	includeMetrics []string
+	excludeMetrics []string
+	includeIPs     []netip.Prefix
+	excludeIPs     []netip.Prefix
+	includeTypes   map[string]struct{}
+	excludeTypes   map[string]struct{}
+	sampleRate     float64
+	// sample returns a number in [0, 1); it is replaceable in tests.
+	sample func() float64
+}
+
+// NewFilter validates and compiles the rules.
+func NewFilter(rules FilterRules) (*Filter, error) {
+	f := &Filter{
+		includeMetrics: rules.IncludeMetrics,
+		excludeMetrics: rules.ExcludeMetrics,
+		sampleRate:     rules.SampleRate,
+		sample:         rand.Float64,
+	}
+
+	for _, pattern := range append(append([]string(nil), rules.IncludeMetrics...), rules.ExcludeMetrics...) {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("%w: invalid metric pattern %q", ErrInvalidFilter, pattern)
+		}
+	}
+
+	var err error
+	if f.includeIPs, err = parsePrefixes(rules.IncludeIPs); err != nil {
+		return nil, err
+	}
+	if f.excludeIPs, err = parsePrefixes(rules.ExcludeIPs); err != nil {
+		return nil, err
+	}
+	if f.includeTypes, err = typeSet(rules.IncludeTypes); err != nil {
+		return nil, err
+	}
+	if f.excludeTypes, err = typeSet(rules.ExcludeTypes); err != nil {
+		return nil, err
+	}
+
+	if rules.SampleRate < 0 || rules.SampleRate > 1 {
+		return nil, fmt.Errorf("%w: sample rate %v is not in [0, 1]", ErrInvalidFilter, rules.SampleRate)
+	}
+	return f, nil
+}
+
+func parsePrefixes(values []string) ([]netip.Prefix, error) {
+	prefixes := make([]netip.Prefix, 0, len(values))
+	for _, v := range values {
+		if !strings.Contains(v, "/") {
+			addr, err := netip.ParseAddr(v)
+			if err != nil {
+				return nil, fmt.Errorf("%w: invalid address %q", ErrInvalidFilter, v)
+			}
+			prefixes = append(prefixes, netip.PrefixFrom(addr.Unmap(), addr.Unmap().BitLen()))
+			continue
+		}
+		prefix, err := netip.ParsePrefix(v)
+		if err != nil {
+			return nil, fmt.Errorf("%w: invalid prefix %q", ErrInvalidFilter, v)
+		}
+		prefixes = append(prefixes, prefix.Masked())
+	}
+	return prefixes, nil
+}
+
+func typeSet(values []string) (map[string]struct{}, error) {
+	set := make(map[string]struct{}, len(values))
+	for _, v := range values {
+		switch v {
+		case models.Gauge, models.Counter, models.Histogram:
+			set[v] = struct{}{}
+		default:
+			return nil, fmt.Errorf("%w: unsupported metric type %q", ErrInvalidFilter, v)
+		}
+	}
+	return set, nil
+}
+
+// Apply returns the event to deliver, or false if the event is filtered out.
+// If only some of the metrics of the event match, a copy limited to them is returned; event itself is not modified.
+func (f *Filter) Apply(event *models.AuditEvent) (*models.AuditEvent, bool) {
+	if f == nil {
+		return event, true
+	}
+	if !f.matchIP(event.IPAddress) {
+		return nil, false
+	}
+
+	filtered, ok := f.narrow(event)
+	if !ok {
+		return nil, false
+	}
+
+	if f.sampleRate > 0 && f.sample() >= f.sampleRate {
+		return nil, false
+	}
+	return filtered, true
+}
+
+// narrow limits the event to its metrics that pass the name and type rules.
+// Types are known from the recorded changes; events without changes are matched by name only
+// and do not pass type include rules.
+func (f *Filter) narrow(event *models.AuditEvent) (*models.AuditEvent, bool) {
+	if len(f.includeMetrics) == 0 && len(f.excludeMetrics) == 0 && len(f.includeTypes) == 0 && len(f.excludeTypes) == 0 {
+		return event, true
+	}
+
+	if len(event.Changes) == 0 {
+		if len(f.includeTypes) > 0 {
+			return nil, false
+		}
+		var metrics []string
+		for _, name := range event.Metrics {
+			if f.matchMetric(name) {
+				metrics = append(metrics, name)
+			}
+		}
+		if len(metrics) == 0 {
+			return nil, false
+		}
+		if len(metrics) == len(event.Metrics) {
+			return event, true
+		}
+		narrowed := *event
+		narrowed.Metrics = metrics
+		return &narrowed, true
+	}
+
+	var changes []models.MetricChange
+	for _, c := range event.Changes {
+		if f.matchMetric(c.ID) && f.matchType(c.MType) {
+			changes = append(changes, c)
+		}
+	}
+	if len(changes) == 0 {
+		return nil, false
+	}
+	if len(changes) == len(event.Changes) {
+		return event, true
+	}
+
+	narrowed := *event
+	narrowed.Changes = changes
+	narrowed.Metrics = make([]string, 0, len(changes))
+	for _, c := range changes {
+		narrowed.Metrics = append(narrowed.Metrics, c.ID)
+	}
+	return &narrowed, true
+}
+
+func (f *Filter) matchMetric(name string) bool {
+	if matchAnyGlob(f.excludeMetrics, name) {
+		return false
+	}
+	return len(f.includeMetrics) == 0 || matchAnyGlob(f.includeMetrics, name)
+}
+
+func matchAnyGlob(patterns []string, name string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func (f *Filter) matchType(mType string) bool {
+	if _, ok := f.excludeTypes[mType]; ok {
+		return false
+	}
+	if len(f.includeTypes) == 0 {
+		return true
+	}
+	_, ok := f.includeTypes[mType]
+	return ok
+}
+
+func (f *Filter) matchIP(ip string) bool {
+	if len(f.includeIPs) == 0 && len(f.excludeIPs) == 0 {
+		return true
+	}
+
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		// An unknown address only passes if no address is required.
+		return len(f.includeIPs) == 0
+	}
+	addr = addr.Unmap()
+
+	for _, prefix := range f.excludeIPs {
+		if prefix.Contains(addr) {
+			return false
+		}
+	}
+	if len(f.includeIPs) == 0 {
+		return true
+	}
+	for _, prefix := range f.includeIPs {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,127 @@
+package audit
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Pro100x3mal/go-musthave-metrics/internal/server/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestParseFilterRules(t *testing.T) {
+	rules, err := ParseFilterRules("metric=Poll*, Random*; exclude_metric=PollCount;ip=10.0.0.0/8;exclude_ip=10.0.0.1;type=counter;exclude_type=histogram;sample=0.5")
+	require.NoError(t, err)
+	assert.Equal(t, FilterRules{
+		IncludeMetrics: []string{"Poll*", "Random*"},
+		ExcludeMetrics: []string{"PollCount"},
+		IncludeIPs:     []string{"10.0.0.0/8"},
+		ExcludeIPs:     []string{"10.0.0.1"},
+		IncludeTypes:   []string{"counter"},
+		ExcludeTypes:   []string{"histogram"},
+		SampleRate:     0.5,
+	}, rules)
+
+	for _, spec := range []string{"metric", "color=red", "sample=often"} {
+		_, err = ParseFilterRules(spec)
+		assert.ErrorIs(t, err, ErrInvalidFilter, spec)
+	}
+	for _, rules := range []FilterRules{
+		{IncludeMetrics: []string{"[Poll"}},
+		{IncludeIPs: []string{"10.0.0"}},
+		{ExcludeTypes: []string{"summary"}},
+		{SampleRate: 2},
+	} {
+		_, err = NewFilter(rules)
+		assert.ErrorIs(t, err, ErrInvalidFilter)
+	}
+}
+
+func TestFilter_Apply(t *testing.T) {
+	event := &models.AuditEvent{
+		Metrics:   []string{"Alloc", "PollCount", "RandomValue"},
+		IPAddress: "10.0.0.1",
+		Changes: []models.MetricChange{
+			{ID: "Alloc", MType: models.Gauge},
+			{ID: "PollCount", MType: models.Counter},
+			{ID: "RandomValue", MType: models.Gauge},
+		},
+	}
+
+	tests := []struct {
+		name        string
+		rules       FilterRules
+		wantMetrics []string
+	}{
+		{name: "no rules", wantMetrics: []string{"Alloc", "PollCount", "RandomValue"}},
+		{name: "include glob", rules: FilterRules{IncludeMetrics: []string{"Poll*", "Random*"}}, wantMetrics: []string{"PollCount", "RandomValue"}},
+		{name: "exclude wins", rules: FilterRules{IncludeMetrics: []string{"*"}, ExcludeMetrics: []string{"Alloc"}}, wantMetrics: []string{"PollCount", "RandomValue"}},
+		{name: "type", rules: FilterRules{IncludeTypes: []string{models.Counter}}, wantMetrics: []string{"PollCount"}},
+		{name: "exclude type", rules: FilterRules{ExcludeTypes: []string{models.Gauge, models.Counter}}},
+		{name: "ip prefix", rules: FilterRules{IncludeIPs: []string{"10.0.0.0/8"}}, wantMetrics: []string{"Alloc", "PollCount", "RandomValue"}},
+		{name: "excluded ip", rules: FilterRules{IncludeIPs: []string{"10.0.0.0/8"}, ExcludeIPs: []string{"10.0.0.1"}}},
+		{name: "other ip", rules: FilterRules{IncludeIPs: []string{"192.168.0.0/16"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := NewFilter(tt.rules)
+			require.NoError(t, err)
+
+			filtered, ok := f.Apply(event)
+			if tt.wantMetrics == nil {
+				assert.False(t, ok)
+				return
+			}
+			require.True(t, ok)
+			assert.Equal(t, tt.wantMetrics, filtered.Metrics)
+			require.Len(t, filtered.Changes, len(tt.wantMetrics))
+			for i, c := range filtered.Changes {
+				assert.Equal(t, tt.wantMetrics[i], c.ID)
+			}
+		})
+	}
+
+	// The shared event is never modified.
+	assert.Len(t, event.Metrics, 3)
+	assert.Len(t, event.Changes, 3)
+}
+
+func TestFilter_Sampling(t *testing.T) {
+	f, err := NewFilter(FilterRules{SampleRate: 0.25})
+	require.NoError(t, err)
+
+	values := []float64{0.1, 0.3, 0.24, 0.9}
+	f.sample = func() float64 {
+		v := values[0]
+		values = values[1:]
+		return v
+	}
+
+	var delivered int
+	for i := 0; i < 4; i++ {
+		if _, ok := f.Apply(&models.AuditEvent{Metrics: []string{"Alloc"}}); ok {
+			delivered++
+		}
+	}
+	assert.Equal(t, 2, delivered)
+}
+
+func TestAuditManager_AttachFiltered(t *testing.T) {
+	am := NewAuditManager(zap.NewNop(), testOptions(t))
+	all := &recordingObserver{}
+	counters := &recordingObserver{}
+	filter, err := NewFilter(FilterRules{IncludeTypes: []string{models.Counter}})
+	require.NoError(t, err)
+	am.Attach(all)
+	am.AttachFiltered(counters, filter)
+
+	am.NotifyAll(context.Background(), NewAuditEventFromMetric(&models.Metrics{ID: "Alloc", MType: models.Gauge, Value: new(float64)}, "10.0.0.1"))
+	am.NotifyAll(context.Background(), NewAuditEventFromMetric(&models.Metrics{ID: "PollCount", MType: models.Counter, Delta: new(int64)}, "10.0.0.1"))
+	require.NoError(t, am.Shutdown(context.Background()))
+
+	assert.Len(t, all.received(), 2)
+	require.Len(t, counters.received(), 1)
+	assert.Equal(t, []string{"PollCount"}, counters.received()[0].Metrics)
+}
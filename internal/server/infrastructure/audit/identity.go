@@ -0,0 +1,54 @@
+package audit
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+
+	"github.com/Pro100x3mal/go-musthave-metrics/internal/server/models"
+)
+
+type signingKeyIDKey struct{}
+
+// keyIDLabel is the message authenticated with a signing key to derive its identifier.
+const keyIDLabel = "go-musthave-metrics signing key id"
+
+// KeyID returns a short identifier of a signing key that can be logged without revealing the key.
+// It is the truncated HMAC-SHA256 of a constant label under the key, so it is unrelated to the hashes
+// of the key or the signatures made with it, and cannot be looked up in tables of precomputed hashes.
+func KeyID(key string) string {
+	h := hmac.New(sha256.New, []byte(key))
+	h.Write([]byte(keyIDLabel))
+	return hex.EncodeToString(h.Sum(nil)[:8])
+}
+
+// WithSigningKeyID records in ctx that the request carried a valid signature made with the identified key.
+func WithSigningKeyID(ctx context.Context, keyID string) context.Context {
+	return context.WithValue(ctx, signingKeyIDKey{}, keyID)
+}
+
+// SigningKeyID returns the key identifier recorded by WithSigningKeyID, or an empty string for unsigned requests.
+func SigningKeyID(ctx context.Context) string {
+	keyID, _ := ctx.Value(signingKeyIDKey{}).(string)
+	return keyID
+}
+
+// NewAgentIdentity returns the identity of the client of a request, or nil if nothing is known about it.
+func NewAgentIdentity(keyID, tlsCommonName string) *models.AgentIdentity {
+	if keyID == "" && tlsCommonName == "" {
+		return nil
+	}
+	return &models.AgentIdentity{KeyID: keyID, TLSCommonName: tlsCommonName}
+}
+
+// GetAgentIdentity returns the identity of the client of an HTTP request: the key of a verified signature
+// and the common name of a verified TLS client certificate.
+func GetAgentIdentity(r *http.Request) *models.AgentIdentity {
+	var cn string
+	if r.TLS != nil && len(r.TLS.VerifiedChains) > 0 && len(r.TLS.VerifiedChains[0]) > 0 {
+		cn = r.TLS.VerifiedChains[0][0].Subject.CommonName
+	}
+	return NewAgentIdentity(SigningKeyID(r.Context()), cn)
+}
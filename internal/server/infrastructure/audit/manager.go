@@ -0,0 +1,351 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/Pro100x3mal/go-musthave-metrics/internal/server/models"
+	"go.uber.org/zap"
+)
+
+var (
+	errQueueFull      = errors.New("audit queue is full")
+	errManagerStopped = errors.New("audit manager is shut down")
+)
+
+// Options configure the delivery pipeline of an AuditManager. Zero fields take the values of DefaultOptions.
+type Options struct {
+	// QueueSize is the capacity of the queue of every observer.
+	QueueSize int
+	// Workers is the number of delivery goroutines per observer.
+	Workers int
+	// BatchSize is the maximum number of events delivered to an observer at once.
+	BatchSize int
+	// BatchWait is how long a worker waits to fill a batch before delivering it.
+	BatchWait time.Duration
+	// MaxRetries is the number of retries after a failed delivery.
+	MaxRetries int
+	// RetryBackoff is the delay before the first retry; it doubles up to MaxBackoff.
+	RetryBackoff time.Duration
+	MaxBackoff   time.Duration
+	// NotifyTimeout bounds a single delivery attempt.
+	NotifyTimeout time.Duration
+	// DeadLetterPath is the file receiving events that could not be delivered. Such events are only logged if it is empty.
+	DeadLetterPath string
+}
+
+// DefaultOptions returns the pipeline settings used for zero Options fields.
+func DefaultOptions() Options {
+	return Options{
+		QueueSize:     1024,
+		Workers:       2,
+		BatchSize:     64,
+		BatchWait:     200 * time.Millisecond,
+		MaxRetries:    3,
+		RetryBackoff:  500 * time.Millisecond,
+		MaxBackoff:    5 * time.Second,
+		NotifyTimeout: 5 * time.Second,
+	}
+}
+
+func (o Options) withDefaults() Options {
+	d := DefaultOptions()
+	if o.QueueSize <= 0 {
+		o.QueueSize = d.QueueSize
+	}
+	if o.Workers <= 0 {
+		o.Workers = d.Workers
+	}
+	if o.BatchSize <= 0 {
+		o.BatchSize = d.BatchSize
+	}
+	if o.BatchWait <= 0 {
+		o.BatchWait = d.BatchWait
+	}
+	if o.MaxRetries < 0 {
+		o.MaxRetries = 0
+	}
+	if o.RetryBackoff <= 0 {
+		o.RetryBackoff = d.RetryBackoff
+	}
+	if o.MaxBackoff < o.RetryBackoff {
+		o.MaxBackoff = max(d.MaxBackoff, o.RetryBackoff)
+	}
+	if o.NotifyTimeout <= 0 {
+		o.NotifyTimeout = d.NotifyTimeout
+	}
+	return o
+}
+
+// sink is the queue, name and filter of one attached observer.
+type sink struct {
+	name     string
+	observer Observer
+	filter   *Filter
+	queue    chan *models.AuditEvent
+}
+
+// AuditManager delivers audit events to the attached observers independently of the request that produced them.
+// Every observer has its own bounded queue drained by worker goroutines, so a slow observer does not hold back the others.
+// Failed deliveries are retried with exponential backoff; events that still cannot be delivered, or do not fit
+// into a full queue, are written to the dead-letter file.
+type AuditManager struct {
+	sinks      []*sink
+	mu         *sync.RWMutex
+	logger     *zap.Logger
+	opts       Options
+	closed     bool
+	wg         sync.WaitGroup
+	ctx        context.Context
+	cancel     context.CancelFunc
+	deadLetter *deadLetterWriter
+}
+
+func NewAuditManager(logger *zap.Logger, opts Options) *AuditManager {
+	opts = opts.withDefaults()
+	ctx, cancel := context.WithCancel(context.Background())
+
+	return &AuditManager{
+		sinks:      make([]*sink, 0),
+		mu:         &sync.RWMutex{},
+		logger:     logger,
+		opts:       opts,
+		ctx:        ctx,
+		cancel:     cancel,
+		deadLetter: &deadLetterWriter{path: opts.DeadLetterPath, logger: logger},
+	}
+}
+
+// Attach registers the observer and starts its delivery workers.
+func (am *AuditManager) Attach(observer Observer) {
+	am.AttachFiltered(observer, nil)
+}
+
+// AttachFiltered registers the observer with a filter selecting the events it receives.
+// A nil filter delivers all events.
+func (am *AuditManager) AttachFiltered(observer Observer, filter *Filter) {
+	am.mu.Lock()
+	defer am.mu.Unlock()
+
+	if am.closed {
+		am.logger.Warn("audit manager is shut down, observer not attached")
+		return
+	}
+
+	s := &sink{
+		name:     fmt.Sprintf("%T", observer),
+		observer: observer,
+		filter:   filter,
+		queue:    make(chan *models.AuditEvent, am.opts.QueueSize),
+	}
+	am.sinks = append(am.sinks, s)
+
+	for i := 0; i < am.opts.Workers; i++ {
+		am.wg.Add(1)
+		go am.worker(s)
+	}
+}
+
+// NotifyAll queues the event for every observer whose filter it passes and returns without waiting for delivery.
+// The context is not used for delivery, so the event outlives the request it was produced by.
+func (am *AuditManager) NotifyAll(_ context.Context, event *models.AuditEvent) {
+	am.mu.RLock()
+	defer am.mu.RUnlock()
+
+	if am.closed {
+		am.deadLetter.write("", event, errManagerStopped)
+		return
+	}
+
+	for _, s := range am.sinks {
+		filtered, ok := s.filter.Apply(event)
+		if !ok {
+			continue
+		}
+		select {
+		case s.queue <- filtered:
+		default:
+			am.logger.Warn("audit queue is full", zap.String("observer", s.name))
+			am.deadLetter.write(s.name, filtered, errQueueFull)
+		}
+	}
+}
+
+func (am *AuditManager) HasObservers() bool {
+	am.mu.RLock()
+	defer am.mu.RUnlock()
+	return len(am.sinks) > 0
+}
+
+// Shutdown stops accepting events and waits until the queued ones are delivered.
+// If ctx expires first, pending retries are abandoned and the remaining events are written to the dead-letter file.
+func (am *AuditManager) Shutdown(ctx context.Context) error {
+	am.mu.Lock()
+	if am.closed {
+		am.mu.Unlock()
+		return nil
+	}
+	am.closed = true
+	for _, s := range am.sinks {
+		close(s.queue)
+	}
+	am.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		am.wg.Wait()
+		close(done)
+	}()
+
+	var err error
+	select {
+	case <-done:
+	case <-ctx.Done():
+		err = fmt.Errorf("audit queue not drained: %w", ctx.Err())
+		am.cancel()
+		<-done
+	}
+	am.cancel()
+
+	if closeErr := am.deadLetter.Close(); closeErr != nil {
+		err = errors.Join(err, closeErr)
+	}
+	return err
+}
+
+func (am *AuditManager) worker(s *sink) {
+	defer am.wg.Done()
+
+	batch := make([]*models.AuditEvent, 0, am.opts.BatchSize)
+	for {
+		event, ok := <-s.queue
+		if !ok {
+			return
+		}
+		batch = append(batch[:0], event)
+
+		timer := time.NewTimer(am.opts.BatchWait)
+	collect:
+		for len(batch) < am.opts.BatchSize {
+			select {
+			case event, ok := <-s.queue:
+				if !ok {
+					break collect
+				}
+				batch = append(batch, event)
+			case <-timer.C:
+				break collect
+			}
+		}
+		timer.Stop()
+
+		am.deliver(s, batch)
+	}
+}
+
+func (am *AuditManager) deliver(s *sink, batch []*models.AuditEvent) {
+	if bo, ok := s.observer.(BatchObserver); ok {
+		err := am.retry(func(ctx context.Context) error {
+			return bo.NotifyBatch(ctx, batch)
+		})
+		if err != nil {
+			am.logger.Error("failed to notify audit observer", zap.String("observer", s.name), zap.Int("events", len(batch)), zap.Error(err))
+			for _, event := range batch {
+				am.deadLetter.write(s.name, event, err)
+			}
+		}
+		return
+	}
+
+	for _, event := range batch {
+		err := am.retry(func(ctx context.Context) error {
+			return s.observer.Notify(ctx, event)
+		})
+		if err != nil {
+			am.logger.Error("failed to notify audit observer", zap.String("observer", s.name), zap.Error(err))
+			am.deadLetter.write(s.name, event, err)
+		}
+	}
+}
+
+// retry calls notify until it succeeds, the retries are exhausted or the manager gives up on shutdown.
+func (am *AuditManager) retry(notify func(ctx context.Context) error) error {
+	backoff := am.opts.RetryBackoff
+	for attempt := 0; ; attempt++ {
+		if err := am.ctx.Err(); err != nil {
+			return errManagerStopped
+		}
+
+		ctx, cancel := context.WithTimeout(am.ctx, am.opts.NotifyTimeout)
+		err := notify(ctx)
+		cancel()
+		if err == nil || attempt >= am.opts.MaxRetries {
+			return err
+		}
+
+		select {
+		case <-time.After(backoff):
+		case <-am.ctx.Done():
+			return err
+		}
+		backoff = min(backoff*2, am.opts.MaxBackoff)
+	}
+}
+
+// deadLetterRecord is a line of the dead-letter file.
+type deadLetterRecord struct {
+	Observer string             `json:"observer,omitempty"`
+	Error    string             `json:"error"`
+	Event    *models.AuditEvent `json:"event"`
+}
+
+// deadLetterWriter appends undeliverable events as JSON lines. The file is created on the first write.
+type deadLetterWriter struct {
+	mu     sync.Mutex
+	path   string
+	file   *os.File
+	logger *zap.Logger
+}
+
+func (d *deadLetterWriter) write(observer string, event *models.AuditEvent, cause error) {
+	data, err := json.Marshal(deadLetterRecord{Observer: observer, Error: cause.Error(), Event: event})
+	if err != nil {
+		d.logger.Error("failed to marshal dead-letter audit event", zap.Error(err))
+		return
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.path == "" {
+		d.logger.Error("audit event dropped", zap.String("observer", observer), zap.ByteString("event", data))
+		return
+	}
+	if d.file == nil {
+		d.file, err = os.OpenFile(d.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+		if err != nil {
+			d.logger.Error("failed to open dead-letter file", zap.Error(err), zap.ByteString("event", data))
+			return
+		}
+	}
+	if _, err = d.file.Write(append(data, '\n')); err != nil {
+		d.logger.Error("failed to write dead-letter audit event", zap.Error(err), zap.ByteString("event", data))
+	}
+}
+
+func (d *deadLetterWriter) Close() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.file == nil {
+		return nil
+	}
+	err := d.file.Close()
+	d.file = nil
+	return err
+}
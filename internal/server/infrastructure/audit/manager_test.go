@@ -0,0 +1,191 @@
+package audit
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Pro100x3mal/go-musthave-metrics/internal/server/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+type recordingObserver struct {
+	mu       sync.Mutex
+	events   []*models.AuditEvent
+	failures int
+	block    chan struct{}
+}
+
+func (o *recordingObserver) Notify(ctx context.Context, event *models.AuditEvent) error {
+	if o.block != nil {
+		select {
+		case <-o.block:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.failures > 0 {
+		o.failures--
+		return errors.New("temporary failure")
+	}
+	o.events = append(o.events, event)
+	return nil
+}
+
+func (o *recordingObserver) received() []*models.AuditEvent {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return append([]*models.AuditEvent(nil), o.events...)
+}
+
+type batchObserver struct {
+	recordingObserver
+	batches int
+}
+
+func (o *batchObserver) NotifyBatch(_ context.Context, events []*models.AuditEvent) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.batches++
+	o.events = append(o.events, events...)
+	return nil
+}
+
+func testOptions(t *testing.T) Options {
+	return Options{
+		Workers:        1,
+		BatchWait:      10 * time.Millisecond,
+		RetryBackoff:   time.Millisecond,
+		MaxBackoff:     time.Millisecond,
+		DeadLetterPath: filepath.Join(t.TempDir(), "dead.json"),
+	}
+}
+
+func readDeadLetters(t *testing.T, path string) []deadLetterRecord {
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	require.NoError(t, err)
+	defer f.Close()
+
+	var records []deadLetterRecord
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var r deadLetterRecord
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &r))
+		records = append(records, r)
+	}
+	require.NoError(t, scanner.Err())
+	return records
+}
+
+func TestAuditManager_DeliversAfterRequestContextIsCancelled(t *testing.T) {
+	opts := testOptions(t)
+	am := NewAuditManager(zap.NewNop(), opts)
+	obs := &recordingObserver{}
+	am.Attach(obs)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	for i := 0; i < 5; i++ {
+		am.NotifyAll(ctx, &models.AuditEvent{Timestamp: int64(i)})
+	}
+
+	require.NoError(t, am.Shutdown(context.Background()))
+	assert.Len(t, obs.received(), 5)
+	assert.Empty(t, readDeadLetters(t, opts.DeadLetterPath))
+}
+
+func TestAuditManager_Batches(t *testing.T) {
+	opts := testOptions(t)
+	opts.BatchWait = time.Second
+	opts.BatchSize = 4
+	am := NewAuditManager(zap.NewNop(), opts)
+	obs := &batchObserver{}
+	am.Attach(obs)
+
+	for i := 0; i < 8; i++ {
+		am.NotifyAll(context.Background(), &models.AuditEvent{Timestamp: int64(i)})
+	}
+
+	require.NoError(t, am.Shutdown(context.Background()))
+	assert.Len(t, obs.received(), 8)
+	assert.Equal(t, 2, obs.batches)
+}
+
+func TestAuditManager_Retries(t *testing.T) {
+	opts := testOptions(t)
+	opts.MaxRetries = 2
+	am := NewAuditManager(zap.NewNop(), opts)
+	flaky := &recordingObserver{failures: 2}
+	broken := &recordingObserver{failures: 100}
+	am.Attach(flaky)
+	am.Attach(broken)
+
+	event := &models.AuditEvent{Timestamp: 1, Metrics: []string{"Alloc"}, IPAddress: "10.0.0.1"}
+	am.NotifyAll(context.Background(), event)
+
+	require.NoError(t, am.Shutdown(context.Background()))
+	assert.Equal(t, []*models.AuditEvent{event}, flaky.received())
+	assert.Empty(t, broken.received())
+
+	records := readDeadLetters(t, opts.DeadLetterPath)
+	require.Len(t, records, 1)
+	assert.Equal(t, "*audit.recordingObserver", records[0].Observer)
+	assert.Equal(t, "temporary failure", records[0].Error)
+	assert.Equal(t, event, records[0].Event)
+}
+
+func TestAuditManager_QueueFull(t *testing.T) {
+	opts := testOptions(t)
+	opts.QueueSize = 1
+	opts.BatchSize = 1
+	am := NewAuditManager(zap.NewNop(), opts)
+	obs := &recordingObserver{block: make(chan struct{})}
+	am.Attach(obs)
+
+	for i := 0; i < 10; i++ {
+		am.NotifyAll(context.Background(), &models.AuditEvent{Timestamp: int64(i)})
+	}
+	close(obs.block)
+
+	require.NoError(t, am.Shutdown(context.Background()))
+	records := readDeadLetters(t, opts.DeadLetterPath)
+	assert.NotEmpty(t, records)
+	assert.Equal(t, 10, len(obs.received())+len(records))
+	for _, r := range records {
+		assert.Equal(t, errQueueFull.Error(), r.Error)
+	}
+}
+
+func TestAuditManager_ShutdownTimeout(t *testing.T) {
+	opts := testOptions(t)
+	am := NewAuditManager(zap.NewNop(), opts)
+	obs := &recordingObserver{block: make(chan struct{})}
+	am.Attach(obs)
+
+	am.NotifyAll(context.Background(), &models.AuditEvent{Timestamp: 1})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	err := am.Shutdown(ctx)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+
+	assert.Empty(t, obs.received())
+	assert.Len(t, readDeadLetters(t, opts.DeadLetterPath), 1)
+
+	am.NotifyAll(context.Background(), &models.AuditEvent{Timestamp: 2})
+	assert.Len(t, readDeadLetters(t, opts.DeadLetterPath), 2)
+}
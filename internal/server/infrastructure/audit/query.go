@@ -0,0 +1,358 @@
+package audit
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/netip"
+	"net/url"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Pro100x3mal/go-musthave-metrics/internal/server/models"
+)
+
+const (
+	// DefaultQueryLimit is the number of events returned when a query sets no limit.
+	DefaultQueryLimit = 100
+	// MaxQueryLimit is the largest accepted limit.
+	MaxQueryLimit = 10000
+)
+
+// ErrInvalidQuery is returned for malformed audit query parameters.
+var ErrInvalidQuery = errors.New("invalid audit query")
+
+// Query selects audit events. Zero fields match every event.
+type Query struct {
+	// Metric is a metric name or a path.Match pattern such as "Alloc*"; an event matches if any of its metrics does.
+	Metric string
+	// IP is an address or a CIDR prefix such as "10.0.0.0/8".
+	IP string
+	// From and To bound the event timestamp, both inclusive.
+	From time.Time
+	To   time.Time
+	// Limit is the maximum number of events returned, the newest ones first.
+	Limit int
+}
+
+// ParseQuery reads a query from the metric, ip, from, to and limit parameters.
+// Times are RFC 3339 timestamps or Unix seconds. The limit defaults to DefaultQueryLimit.
+func ParseQuery(values url.Values) (Query, error) {
+	q := Query{
+		Metric: values.Get("metric"),
+		IP:     values.Get("ip"),
+		Limit:  DefaultQueryLimit,
+	}
+
+	var err error
+	if v := values.Get("from"); v != "" {
+		if q.From, err = parseQueryTime(v); err != nil {
+			return Query{}, fmt.Errorf("%w: invalid 'from' parameter", ErrInvalidQuery)
+		}
+	}
+	if v := values.Get("to"); v != "" {
+		if q.To, err = parseQueryTime(v); err != nil {
+			return Query{}, fmt.Errorf("%w: invalid 'to' parameter", ErrInvalidQuery)
+		}
+	}
+	if v := values.Get("limit"); v != "" {
+		if q.Limit, err = strconv.Atoi(v); err != nil || q.Limit <= 0 || q.Limit > MaxQueryLimit {
+			return Query{}, fmt.Errorf("%w: 'limit' must be between 1 and %d", ErrInvalidQuery, MaxQueryLimit)
+		}
+	}
+
+	if _, err = q.matcher(); err != nil {
+		return Query{}, err
+	}
+	return q, nil
+}
+
+// Values returns the query parameters ParseQuery reads q from.
+func (q Query) Values() url.Values {
+	values := url.Values{}
+	if q.Metric != "" {
+		values.Set("metric", q.Metric)
+	}
+	if q.IP != "" {
+		values.Set("ip", q.IP)
+	}
+	if !q.From.IsZero() {
+		values.Set("from", q.From.Format(time.RFC3339))
+	}
+	if !q.To.IsZero() {
+		values.Set("to", q.To.Format(time.RFC3339))
+	}
+	if q.Limit > 0 {
+		values.Set("limit", strconv.Itoa(q.Limit))
+	}
+	return values
+}
+
+func parseQueryTime(v string) (time.Time, error) {
+	if sec, err := strconv.ParseInt(v, 10, 64); err == nil {
+		return time.Unix(sec, 0), nil
+	}
+	return time.Parse(time.RFC3339, v)
+}
+
+// queryMatcher is a Query prepared for matching.
+type queryMatcher struct {
+	metric   string
+	ip       string
+	prefix   netip.Prefix
+	from, to int64
+}
+
+func (q Query) matcher() (*queryMatcher, error) {
+	m := &queryMatcher{metric: q.Metric, from: math.MinInt64, to: math.MaxInt64}
+	if _, err := path.Match(q.Metric, ""); err != nil {
+		return nil, fmt.Errorf("%w: invalid 'metric' pattern", ErrInvalidQuery)
+	}
+
+	if strings.Contains(q.IP, "/") {
+		prefix, err := netip.ParsePrefix(q.IP)
+		if err != nil {
+			return nil, fmt.Errorf("%w: invalid 'ip' prefix", ErrInvalidQuery)
+		}
+		m.prefix = prefix.Masked()
+	} else {
+		m.ip = q.IP
+	}
+
+	if !q.From.IsZero() {
+		m.from = q.From.Unix()
+	}
+	if !q.To.IsZero() {
+		m.to = q.To.Unix()
+	}
+	if m.from > m.to {
+		return nil, fmt.Errorf("%w: 'from' is after 'to'", ErrInvalidQuery)
+	}
+	return m, nil
+}
+
+func (m *queryMatcher) matchMetric(name string) bool {
+	if m.metric == "" {
+		return true
+	}
+	ok, _ := path.Match(m.metric, name)
+	return ok
+}
+
+func (m *queryMatcher) matchIP(ip string) bool {
+	switch {
+	case m.prefix.IsValid():
+		addr, err := netip.ParseAddr(ip)
+		return err == nil && m.prefix.Contains(addr.Unmap())
+	case m.ip != "":
+		return ip == m.ip
+	default:
+		return true
+	}
+}
+
+func (m *queryMatcher) match(event *models.AuditEvent) bool {
+	if event.Timestamp < m.from || event.Timestamp > m.to || !m.matchIP(event.IPAddress) {
+		return false
+	}
+	for _, name := range event.Metrics {
+		if m.matchMetric(name) {
+			return true
+		}
+	}
+	return m.metric == ""
+}
+
+// segmentIndex summarizes a rotated segment, so that queries can skip segments without matching events.
+type segmentIndex struct {
+	size     int64
+	modTime  time.Time
+	from, to int64
+	metrics  map[string]struct{}
+	ips      map[string]struct{}
+}
+
+func newSegmentIndex(info os.FileInfo) *segmentIndex {
+	return &segmentIndex{
+		size:    info.Size(),
+		modTime: info.ModTime(),
+		from:    math.MaxInt64,
+		to:      math.MinInt64,
+		metrics: make(map[string]struct{}),
+		ips:     make(map[string]struct{}),
+	}
+}
+
+func (idx *segmentIndex) add(event *models.AuditEvent) {
+	idx.from = min(idx.from, event.Timestamp)
+	idx.to = max(idx.to, event.Timestamp)
+	for _, name := range event.Metrics {
+		idx.metrics[name] = struct{}{}
+	}
+	idx.ips[event.IPAddress] = struct{}{}
+}
+
+func (idx *segmentIndex) current(info os.FileInfo) bool {
+	return idx.size == info.Size() && idx.modTime.Equal(info.ModTime())
+}
+
+// mayMatch reports whether the segment can contain events matching m.
+func (idx *segmentIndex) mayMatch(m *queryMatcher) bool {
+	if idx.to < m.from || idx.from > m.to {
+		return false
+	}
+	return anyKey(idx.metrics, m.metric == "", m.matchMetric) && anyKey(idx.ips, m.ip == "" && !m.prefix.IsValid(), m.matchIP)
+}
+
+func anyKey(set map[string]struct{}, matchAll bool, match func(string) bool) bool {
+	if matchAll {
+		return true
+	}
+	for k := range set {
+		if match(k) {
+			return true
+		}
+	}
+	return false
+}
+
+// LogReader queries the audit log written by FileAuditObserver, including its rotated and compressed segments.
+// Rotated segments do not change, so they are indexed by the time range, metrics and addresses they contain
+// on the first scan, and later queries skip the segments that cannot match. The active file is always scanned.
+type LogReader struct {
+	path  string
+	mu    sync.Mutex
+	index map[string]*segmentIndex
+}
+
+// NewLogReader returns a reader of the audit log at path.
+func NewLogReader(path string) *LogReader {
+	return &LogReader{
+		path:  path,
+		index: make(map[string]*segmentIndex),
+	}
+}
+
+// Query returns the newest events matching q, newest first.
+// Records that cannot be parsed, such as a partially written last line, are skipped.
+func (lr *LogReader) Query(ctx context.Context, q Query) ([]models.AuditEvent, error) {
+	m, err := q.matcher()
+	if err != nil {
+		return nil, err
+	}
+
+	lr.mu.Lock()
+	defer lr.mu.Unlock()
+
+	events, err := lr.query(ctx, m, q.Limit)
+	if errors.Is(err, os.ErrNotExist) {
+		// A segment was rotated or removed while the log was scanned; list the segments again.
+		events, err = lr.query(ctx, m, q.Limit)
+	}
+	return events, err
+}
+
+func (lr *LogReader) query(ctx context.Context, m *queryMatcher, limit int) ([]models.AuditEvent, error) {
+	segments, err := Segments(lr.path)
+	if err != nil {
+		return nil, err
+	}
+	lr.pruneIndex(segments)
+
+	var events []models.AuditEvent
+	for i := len(segments) - 1; i >= 0; i-- {
+		if limit > 0 && len(events) >= limit {
+			break
+		}
+		if err = ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		name := segments[i]
+		info, err := os.Stat(name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to stat audit segment: %w", err)
+		}
+
+		var idx *segmentIndex
+		if name != lr.path {
+			if cached, ok := lr.index[name]; ok && cached.current(info) {
+				if !cached.mayMatch(m) {
+					continue
+				}
+			} else {
+				idx = newSegmentIndex(info)
+			}
+		}
+
+		remaining := 0
+		if limit > 0 {
+			remaining = limit - len(events)
+		}
+		matched, err := scanSegment(name, m, remaining, idx)
+		if err != nil {
+			return nil, err
+		}
+		if idx != nil {
+			lr.index[name] = idx
+		}
+		for j := len(matched) - 1; j >= 0; j-- {
+			events = append(events, matched[j])
+		}
+	}
+	return events, nil
+}
+
+// pruneIndex drops index entries of segments that no longer exist.
+func (lr *LogReader) pruneIndex(segments []string) {
+	existing := make(map[string]struct{}, len(segments))
+	for _, name := range segments {
+		existing[name] = struct{}{}
+	}
+	for name := range lr.index {
+		if _, ok := existing[name]; !ok {
+			delete(lr.index, name)
+		}
+	}
+}
+
+// scanSegment returns the last limit events of a segment matching m in file order, all of them if limit is 0.
+// If idx is not nil, every parsed event is added to it.
+func scanSegment(name string, m *queryMatcher, limit int, idx *segmentIndex) ([]models.AuditEvent, error) {
+	r, err := OpenSegment(name)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	var matched []models.AuditEvent
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxRecordSize)
+	for scanner.Scan() {
+		var event models.AuditEvent
+		if err = json.Unmarshal(scanner.Bytes(), &event); err != nil {
+			continue
+		}
+		if idx != nil {
+			idx.add(&event)
+		}
+		if !m.match(&event) {
+			continue
+		}
+		matched = append(matched, event)
+		if limit > 0 && len(matched) > limit {
+			matched = matched[1:]
+		}
+	}
+	if err = scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read audit segment %q: %w", name, err)
+	}
+	return matched, nil
+}
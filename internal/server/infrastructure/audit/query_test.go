@@ -0,0 +1,100 @@
+package audit
+
+import (
+	"context"
+	"net/url"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Pro100x3mal/go-musthave-metrics/internal/server/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseQuery(t *testing.T) {
+	q, err := ParseQuery(url.Values{
+		"metric": {"Alloc*"},
+		"ip":     {"10.0.0.0/8"},
+		"from":   {"100"},
+		"to":     {"1970-01-01T00:03:20Z"},
+		"limit":  {"5"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, Query{Metric: "Alloc*", IP: "10.0.0.0/8", From: time.Unix(100, 0), To: time.Unix(200, 0).UTC(), Limit: 5}, q)
+
+	q, err = ParseQuery(url.Values{})
+	require.NoError(t, err)
+	assert.Equal(t, DefaultQueryLimit, q.Limit)
+
+	for _, values := range []url.Values{
+		{"from": {"yesterday"}},
+		{"limit": {"0"}},
+		{"metric": {"[Alloc"}},
+		{"ip": {"10.0.0.0/99"}},
+		{"from": {"200"}, "to": {"100"}},
+	} {
+		_, err = ParseQuery(values)
+		assert.ErrorIs(t, err, ErrInvalidQuery, values.Encode())
+	}
+}
+
+func TestLogReader_Query(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.json")
+	obs, err := NewFileAuditObserver(path, "", RotationOptions{MaxSize: 200, Compress: true})
+	require.NoError(t, err)
+	for i := 0; i < 20; i++ {
+		ip := "10.0.0.1"
+		if i%2 == 1 {
+			ip = "192.168.0.1"
+		}
+		name := "Alloc"
+		if i%5 == 0 {
+			name = "PollCount"
+		}
+		require.NoError(t, obs.Notify(context.Background(), &models.AuditEvent{
+			Timestamp: int64(i),
+			Metrics:   []string{name},
+			IPAddress: ip,
+		}))
+	}
+	require.NoError(t, obs.Close())
+
+	segments, err := Segments(path)
+	require.NoError(t, err)
+	require.Greater(t, len(segments), 3)
+
+	timestamps := func(events []models.AuditEvent) []int64 {
+		var ts []int64
+		for _, e := range events {
+			ts = append(ts, e.Timestamp)
+		}
+		return ts
+	}
+
+	tests := []struct {
+		name  string
+		query Query
+		want  []int64
+	}{
+		{name: "limit", query: Query{Limit: 3}, want: []int64{19, 18, 17}},
+		{name: "metric", query: Query{Metric: "PollCount"}, want: []int64{15, 10, 5, 0}},
+		{name: "metric pattern and prefix", query: Query{Metric: "Poll*", IP: "192.168.0.0/16"}, want: []int64{15, 5}},
+		{name: "ip", query: Query{IP: "10.0.0.1", Limit: 2}, want: []int64{18, 16}},
+		{name: "time range", query: Query{From: time.Unix(3, 0), To: time.Unix(6, 0)}, want: []int64{6, 5, 4, 3}},
+		{name: "no match", query: Query{Metric: "HeapAlloc"}, want: nil},
+	}
+
+	reader := NewLogReader(path)
+	// Run twice: the second pass answers from the segment index built by the first.
+	for pass := 0; pass < 2; pass++ {
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				events, err := reader.Query(context.Background(), tt.query)
+				require.NoError(t, err)
+				assert.Equal(t, tt.want, timestamps(events))
+			})
+		}
+	}
+	assert.Len(t, reader.index, len(segments)-1)
+}
@@ -0,0 +1,56 @@
+package audit
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"strings"
+)
+
+// MaxClientRequestIDLength is the maximum length of a client request ID kept for auditing.
+const MaxClientRequestIDLength = 64
+
+type requestIDKey struct{}
+
+type requestIDs struct {
+	id, clientID string
+}
+
+// NewRequestID returns a random request ID generated by the server.
+func NewRequestID() string {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return ""
+	}
+	return hex.EncodeToString(b)
+}
+
+// SanitizeClientRequestID makes a request ID sent by a client safe to record: characters other than printable ASCII
+// are dropped and the result is cut to MaxClientRequestIDLength. The client can choose the value freely,
+// so it is only recorded next to the ID generated by the server, never in its place.
+func SanitizeClientRequestID(id string) string {
+	var b strings.Builder
+	for i := 0; i < len(id) && b.Len() < MaxClientRequestIDLength; i++ {
+		if c := id[i]; c > ' ' && c < 0x7f {
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
+
+// WithRequestID records in ctx the request ID generated by the server and the sanitized ID sent by the client, if any.
+func WithRequestID(ctx context.Context, id, clientID string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, requestIDs{id: id, clientID: SanitizeClientRequestID(clientID)})
+}
+
+// RequestID returns the server request ID recorded by WithRequestID, or an empty string if there is none.
+func RequestID(ctx context.Context) string {
+	ids, _ := ctx.Value(requestIDKey{}).(requestIDs)
+	return ids.id
+}
+
+// ClientRequestID returns the sanitized client request ID recorded by WithRequestID, or an empty string if there is none.
+func ClientRequestID(ctx context.Context) string {
+	ids, _ := ctx.Value(requestIDKey{}).(requestIDs)
+	return ids.clientID
+}
@@ -0,0 +1,36 @@
+package audit
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSanitizeClientRequestID(t *testing.T) {
+	tests := []struct {
+		name string
+		id   string
+		want string
+	}{
+		{name: "plain", id: "req-42", want: "req-42"},
+		{name: "control and non-ASCII characters", id: "req\r\n-42\x00 ж", want: "req-42"},
+		{name: "too long", id: strings.Repeat("a", MaxClientRequestIDLength+10), want: strings.Repeat("a", MaxClientRequestIDLength)},
+		{name: "empty", id: "", want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, SanitizeClientRequestID(tt.id))
+		})
+	}
+}
+
+func TestWithRequestID(t *testing.T) {
+	ctx := WithRequestID(context.Background(), "0123abcd", "req\n-42")
+	assert.Equal(t, "0123abcd", RequestID(ctx))
+	assert.Equal(t, "req-42", ClientRequestID(ctx))
+
+	assert.Empty(t, RequestID(context.Background()))
+	assert.Empty(t, ClientRequestID(context.Background()))
+}
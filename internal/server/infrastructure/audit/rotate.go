@@ -0,0 +1,356 @@
+package audit
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	// segmentTimeFormat names rotated segments by rotation time; it is fixed width so names sort chronologically.
+	segmentTimeFormat = "20060102T150405.000000000"
+	gzipExt           = ".gz"
+)
+
+// RotationOptions control rotation of the audit file. The zero value never rotates.
+type RotationOptions struct {
+	// MaxSize is the size in bytes the file may not exceed; it is rotated before a write that would exceed it.
+	MaxSize int64
+	// MaxAge is how long the file is written to before it is rotated. An existing file is aged from its last modification.
+	MaxAge time.Duration
+	// MaxBackups is the number of rotated segments to keep; 0 keeps all.
+	MaxBackups int
+	// Compress gzips rotated segments.
+	Compress bool
+	// OnError is called with errors that do not stop the observer: failures of compression and removal of old
+	// segments, which happen in the background, and unparsable records skipped when the chain is restored.
+	OnError func(error)
+}
+
+// rotatedName returns the name of the segment the active file at path is renamed to when rotated at t,
+// e.g. audit-20261016T120000.000000000.json for audit.json.
+func rotatedName(path string, t time.Time) string {
+	ext := filepath.Ext(path)
+	return strings.TrimSuffix(path, ext) + "-" + t.UTC().Format(segmentTimeFormat) + ext
+}
+
+// Segments returns the rotated segments of the audit file at path, oldest first, followed by path itself if it exists.
+// Compressed segments have the .gz extension; use OpenSegment to read them.
+func Segments(path string) ([]string, error) {
+	ext := filepath.Ext(path)
+	prefix := strings.TrimSuffix(filepath.Base(path), ext) + "-"
+
+	entries, err := os.ReadDir(filepath.Dir(path))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list audit segments: %w", err)
+	}
+
+	var segments []string
+	for _, entry := range entries {
+		name := entry.Name()
+		stamp, ok := strings.CutPrefix(strings.TrimSuffix(name, gzipExt), prefix)
+		if !ok || entry.IsDir() {
+			continue
+		}
+		stamp, ok = strings.CutSuffix(stamp, ext)
+		if !ok {
+			continue
+		}
+		if _, err = time.Parse(segmentTimeFormat, stamp); err != nil {
+			continue
+		}
+		segments = append(segments, filepath.Join(filepath.Dir(path), name))
+	}
+	sort.Strings(segments)
+
+	if _, err = os.Stat(path); err == nil {
+		segments = append(segments, path)
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("failed to stat audit file: %w", err)
+	}
+	return segments, nil
+}
+
+// OpenSegment opens an audit log segment for reading, decompressing it if its name ends with .gz.
+func OpenSegment(name string) (io.ReadCloser, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open audit segment: %w", err)
+	}
+	if !strings.HasSuffix(name, gzipExt) {
+		return f, nil
+	}
+
+	zr, err := gzip.NewReader(f)
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("failed to read compressed audit segment %q: %w", name, err)
+	}
+	return &gzipSegment{Reader: zr, file: f}, nil
+}
+
+type gzipSegment struct {
+	*gzip.Reader
+	file *os.File
+}
+
+func (s *gzipSegment) Close() error {
+	return errors.Join(s.Reader.Close(), s.file.Close())
+}
+
+// shouldRotate reports whether the active file must be rotated before n more bytes are written to it.
+// An empty file is never rotated.
+func (fao *FileAuditObserver) shouldRotate(n int64) bool {
+	if fao.size == 0 {
+		return false
+	}
+	if fao.rotation.MaxSize > 0 && fao.size+n > fao.rotation.MaxSize {
+		return true
+	}
+	return fao.rotation.MaxAge > 0 && time.Since(fao.openedAt) >= fao.rotation.MaxAge
+}
+
+// rotate renames the active file to a new segment and continues in a new file at the original path.
+// The caller must hold fao.mu. Compression and removal of old segments happen in the background.
+func (fao *FileAuditObserver) rotate() error {
+	fao.writer.mu.Lock()
+	defer fao.writer.mu.Unlock()
+
+	segment, err := fao.nextSegmentName()
+	if err != nil {
+		return err
+	}
+	if err = os.Rename(fao.path, segment); err != nil {
+		return fmt.Errorf("failed to rotate audit file: %w", err)
+	}
+
+	f, err := os.OpenFile(fao.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		// Keep writing to the current file rather than losing events.
+		if renameErr := os.Rename(segment, fao.path); renameErr != nil {
+			return fmt.Errorf("failed to open new audit file: %w; the previous file remains at %q", err, segment)
+		}
+		return fmt.Errorf("failed to open new audit file: %w", err)
+	}
+
+	closeErr := fao.file.Close()
+	fao.setFile(f, 0, time.Now())
+
+	fao.cleanup.Add(1)
+	go func() {
+		defer fao.cleanup.Done()
+		fao.postRotate(segment)
+	}()
+
+	if closeErr != nil {
+		return fmt.Errorf("failed to close rotated audit file: %w", closeErr)
+	}
+	return nil
+}
+
+// nextSegmentName returns a segment name for the current time that is not taken yet.
+func (fao *FileAuditObserver) nextSegmentName() (string, error) {
+	t := time.Now()
+	for {
+		name := rotatedName(fao.path, t)
+		_, err := os.Stat(name)
+		if errors.Is(err, os.ErrNotExist) {
+			_, err = os.Stat(name + gzipExt)
+		}
+		if errors.Is(err, os.ErrNotExist) {
+			return name, nil
+		}
+		if err != nil {
+			return "", fmt.Errorf("failed to check audit segment: %w", err)
+		}
+		t = t.Add(time.Nanosecond)
+	}
+}
+
+// postRotate compresses a rotated segment and removes the segments beyond MaxBackups.
+// Runs are serialized so that removal never races with compression of an older segment.
+func (fao *FileAuditObserver) postRotate(segment string) {
+	fao.cleanupMu.Lock()
+	defer fao.cleanupMu.Unlock()
+
+	if fao.rotation.Compress {
+		if err := compressSegment(segment); err != nil {
+			fao.reportError(err)
+		}
+	}
+	if fao.rotation.MaxBackups <= 0 {
+		return
+	}
+
+	segments, err := Segments(fao.path)
+	if err != nil {
+		fao.reportError(err)
+		return
+	}
+	// The active file is not a backup.
+	if n := len(segments); n > 0 && segments[n-1] == fao.path {
+		segments = segments[:n-1]
+	}
+	for len(segments) > fao.rotation.MaxBackups {
+		if err = os.Remove(segments[0]); err != nil {
+			fao.reportError(fmt.Errorf("failed to remove old audit segment: %w", err))
+		}
+		segments = segments[1:]
+	}
+}
+
+func (fao *FileAuditObserver) reportError(err error) {
+	if fao.rotation.OnError != nil {
+		fao.rotation.OnError(err)
+	}
+}
+
+// compressSegment replaces a segment with its gzipped copy. The copy is written under a temporary name first,
+// so an interrupted compression never leaves a truncated .gz segment behind.
+func compressSegment(name string) error {
+	src, err := os.Open(name)
+	if err != nil {
+		return fmt.Errorf("failed to open audit segment for compression: %w", err)
+	}
+	defer src.Close()
+
+	tmp := name + gzipExt + ".tmp"
+	dst, err := os.OpenFile(tmp, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to create compressed audit segment: %w", err)
+	}
+
+	zw := gzip.NewWriter(dst)
+	_, err = io.Copy(zw, src)
+	if err == nil {
+		err = zw.Close()
+	}
+	if err == nil {
+		err = dst.Sync()
+	}
+	if closeErr := dst.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp, name+gzipExt)
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to compress audit segment %q: %w", name, err)
+	}
+
+	if err = os.Remove(name); err != nil {
+		return fmt.Errorf("failed to remove compressed audit segment: %w", err)
+	}
+	return nil
+}
+
+// Reopen closes the audit file and opens the file at its path again, creating it if it was moved away.
+// It is meant for external rotation, e.g. by logrotate followed by SIGHUP.
+// The chain continues from the last record written, wherever the previous file was moved to.
+func (fao *FileAuditObserver) Reopen() error {
+	fao.mu.Lock()
+	defer fao.mu.Unlock()
+	fao.writer.mu.Lock()
+	defer fao.writer.mu.Unlock()
+
+	f, err := os.OpenFile(fao.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to reopen audit file: %w", err)
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return fmt.Errorf("failed to stat audit file: %w", err)
+	}
+
+	closeErr := fao.file.Close()
+	fao.setFile(f, info.Size(), segmentStart(info))
+	if closeErr != nil {
+		return fmt.Errorf("failed to close audit file: %w", closeErr)
+	}
+	return nil
+}
+
+// setFile makes f the active file. The caller must hold fao.writer.mu or own fao exclusively.
+func (fao *FileAuditObserver) setFile(f *os.File, size int64, openedAt time.Time) {
+	fao.file = f
+	fao.writer.w = f
+	fao.size = size
+	fao.openedAt = openedAt
+}
+
+// segmentStart returns the time an existing file is aged from.
+func segmentStart(info os.FileInfo) time.Time {
+	if info.Size() == 0 {
+		return time.Now()
+	}
+	return info.ModTime()
+}
+
+// restoreChainState restores the chain position from the active file, or from the newest rotated segment
+// if the active file is missing or empty, so that the chain continues across rotation and restarts.
+// Unparsable records at the end of a file are skipped and counted in skipped.
+func restoreChainState(path, key string) (state chainState, skipped int, err error) {
+	state, skipped, err = readChainState(path, key)
+	if err != nil || state != (chainState{}) {
+		return state, skipped, err
+	}
+
+	segments, err := Segments(path)
+	if err != nil {
+		return chainState{}, skipped, err
+	}
+	for i := len(segments) - 1; i >= 0; i-- {
+		if segments[i] == path {
+			continue
+		}
+		var n int
+		if !strings.HasSuffix(segments[i], gzipExt) {
+			state, n, err = readChainState(segments[i], key)
+		} else {
+			state, n, err = readGzipChainState(segments[i], key)
+		}
+		skipped += n
+		if err != nil || state != (chainState{}) {
+			return state, skipped, err
+		}
+	}
+	return chainState{}, skipped, nil
+}
+
+// readGzipChainState restores the chain position from the last parsable record of a compressed segment.
+// Unparsable records after it are counted in skipped.
+func readGzipChainState(name, key string) (state chainState, skipped int, err error) {
+	r, err := OpenSegment(name)
+	if err != nil {
+		return chainState{}, 0, err
+	}
+	defer r.Close()
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxRecordSize)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		if parsed, err := parseChainState(line, key); err == nil {
+			state, skipped = parsed, 0
+		} else {
+			skipped++
+		}
+	}
+	if err = scanner.Err(); err != nil {
+		return chainState{}, 0, fmt.Errorf("failed to read audit segment %q: %w", name, err)
+	}
+	return state, skipped, nil
+}
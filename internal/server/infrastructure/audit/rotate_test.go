@@ -0,0 +1,150 @@
+package audit
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Pro100x3mal/go-musthave-metrics/internal/server/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func readSegments(t *testing.T, segments []string) string {
+	t.Helper()
+
+	var sb strings.Builder
+	for _, name := range segments {
+		r, err := OpenSegment(name)
+		require.NoError(t, err)
+		_, err = io.Copy(&sb, r)
+		require.NoError(t, err)
+		require.NoError(t, r.Close())
+	}
+	return sb.String()
+}
+
+func TestFileAuditObserver_RotatesBySize(t *testing.T) {
+	for _, compress := range []bool{false, true} {
+		t.Run(fmt.Sprintf("compress=%v", compress), func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "audit.json")
+			obs, err := NewFileAuditObserver(path, "", RotationOptions{MaxSize: 300, Compress: compress})
+			require.NoError(t, err)
+
+			for i := 0; i < 10; i++ {
+				require.NoError(t, obs.Notify(context.Background(), &models.AuditEvent{
+					Timestamp: int64(i),
+					Metrics:   []string{"Alloc"},
+					IPAddress: "10.0.0.1",
+				}))
+			}
+			require.NoError(t, obs.Close())
+
+			segments, err := Segments(path)
+			require.NoError(t, err)
+			require.Greater(t, len(segments), 2)
+			assert.Equal(t, path, segments[len(segments)-1])
+			for _, name := range segments[:len(segments)-1] {
+				assert.Equal(t, compress, strings.HasSuffix(name, ".gz"), name)
+				if !compress {
+					info, err := os.Stat(name)
+					require.NoError(t, err)
+					assert.LessOrEqual(t, info.Size(), int64(300))
+				}
+			}
+
+			// The chain continues across segments.
+			report, err := VerifyChain(strings.NewReader(readSegments(t, segments)), "", ChainStart{})
+			require.NoError(t, err)
+			assert.Nil(t, report.Broken)
+			assert.Equal(t, 10, report.Records)
+			assert.Equal(t, uint64(1), report.FirstSeq)
+		})
+	}
+}
+
+func TestFileAuditObserver_RotatesByAge(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.json")
+	obs, err := NewFileAuditObserver(path, "", RotationOptions{MaxAge: 20 * time.Millisecond})
+	require.NoError(t, err)
+	defer obs.Close()
+
+	require.NoError(t, obs.Notify(context.Background(), &models.AuditEvent{Timestamp: 1}))
+	require.NoError(t, obs.Notify(context.Background(), &models.AuditEvent{Timestamp: 2}))
+	time.Sleep(30 * time.Millisecond)
+	require.NoError(t, obs.Notify(context.Background(), &models.AuditEvent{Timestamp: 3}))
+
+	segments, err := Segments(path)
+	require.NoError(t, err)
+	require.Len(t, segments, 2)
+	assert.Equal(t, 2, strings.Count(readSegments(t, segments[:1]), "\n"))
+}
+
+func TestFileAuditObserver_KeepsMaxBackups(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.json")
+	obs, err := NewFileAuditObserver(path, "", RotationOptions{MaxSize: 1, MaxBackups: 2, Compress: true})
+	require.NoError(t, err)
+
+	for i := 0; i < 6; i++ {
+		require.NoError(t, obs.Notify(context.Background(), &models.AuditEvent{Timestamp: int64(i)}))
+	}
+	require.NoError(t, obs.Close())
+
+	segments, err := Segments(path)
+	require.NoError(t, err)
+	require.Len(t, segments, 3)
+
+	// The newest records are kept, and a restarted observer continues the chain after the last segment.
+	data := readSegments(t, segments)
+	assert.Contains(t, data, `"seq":4`)
+	assert.NotContains(t, data, `"seq":3`)
+
+	require.NoError(t, os.Remove(path))
+	obs, err = NewFileAuditObserver(path, "", RotationOptions{})
+	require.NoError(t, err)
+	require.NoError(t, obs.Notify(context.Background(), &models.AuditEvent{Timestamp: 7}))
+	require.NoError(t, obs.Close())
+
+	// The records before seq 4 were removed with the old segments, so the chain is expected to start there.
+	report, err := VerifyChain(strings.NewReader(readSegments(t, append(segments[:2], path))), "", ChainStart{Seq: 3})
+	require.NoError(t, err)
+	assert.Nil(t, report.Broken)
+	assert.Equal(t, uint64(6), report.LastSeq)
+}
+
+func TestFileAuditObserver_Reopen(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "audit.json")
+	obs, err := NewFileAuditObserver(path, "", RotationOptions{})
+	require.NoError(t, err)
+	defer obs.Close()
+
+	var wg sync.WaitGroup
+	for w := 0; w < 4; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 50; i++ {
+				assert.NoError(t, obs.Notify(context.Background(), &models.AuditEvent{Timestamp: int64(i)}))
+			}
+		}()
+	}
+
+	// Simulate logrotate moving the file away while events are being written.
+	time.Sleep(time.Millisecond)
+	moved := filepath.Join(dir, "audit.json.1")
+	require.NoError(t, os.Rename(path, moved))
+	require.NoError(t, obs.Reopen())
+	wg.Wait()
+
+	report, err := VerifyChain(strings.NewReader(readSegments(t, []string{moved, path})), "", ChainStart{})
+	require.NoError(t, err)
+	assert.Nil(t, report.Broken)
+	assert.Equal(t, 200, report.Records)
+}
@@ -0,0 +1,242 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Pro100x3mal/go-musthave-metrics/internal/server/models"
+)
+
+const (
+	// syslogSeverity is the RFC 5424 severity of audit messages (5, notice).
+	syslogSeverity = 5
+	// syslogSDID is the structured-data ID of audit messages.
+	// 32473 is the private enterprise number reserved for documentation (RFC 5612).
+	syslogSDID     = "audit@32473"
+	syslogMsgID    = "audit"
+	syslogTimeout  = 5 * time.Second
+	maxAppNameSize = 48
+)
+
+var syslogFacilities = map[string]int{
+	"kern": 0, "user": 1, "mail": 2, "daemon": 3, "auth": 4, "syslog": 5, "lpr": 6, "news": 7,
+	"uucp": 8, "cron": 9, "authpriv": 10, "ftp": 11,
+	"local0": 16, "local1": 17, "local2": 18, "local3": 19, "local4": 20, "local5": 21, "local6": 22, "local7": 23,
+}
+
+// SyslogOptions configure a SyslogAuditObserver.
+type SyslogOptions struct {
+	// Address is unix:///dev/log, udp://host:514 or tcp://host:514.
+	Address string
+	// Facility is a facility name such as "auth" or "local0".
+	Facility string
+	// AppName is the APP-NAME field of the messages.
+	AppName string
+}
+
+// SyslogAuditObserver sends events to a syslog daemon as RFC 5424 messages. A summary of the event
+// (address, metrics, request and agent) is carried as structured data, and the whole event as JSON in the message.
+// Messages over TCP are framed by octet counting (RFC 6587); over UDP and unix sockets each message is one write.
+type SyslogAuditObserver struct {
+	network  string
+	address  string
+	priority int
+	appName  string
+	hostname string
+	procID   string
+
+	// mu serializes writes and reconnection.
+	mu   sync.Mutex
+	conn net.Conn
+	// connNetwork is the network conn was dialed with; unix sockets may be datagram or stream.
+	connNetwork string
+}
+
+// NewSyslogAuditObserver validates the options and returns an observer that connects on first use
+// and reconnects after a failed write, so that it does not depend on the daemon being up at startup.
+func NewSyslogAuditObserver(opts SyslogOptions) (*SyslogAuditObserver, error) {
+	u, err := url.Parse(opts.Address)
+	if err != nil {
+		return nil, fmt.Errorf("invalid syslog address %q: %w", opts.Address, err)
+	}
+
+	sao := &SyslogAuditObserver{
+		network: u.Scheme,
+		procID:  strconv.Itoa(os.Getpid()),
+	}
+	switch u.Scheme {
+	case "unix":
+		sao.address = u.Path
+	case "udp", "tcp":
+		sao.address = u.Host
+	default:
+		return nil, fmt.Errorf("unsupported syslog network %q: use unix, udp or tcp", u.Scheme)
+	}
+	if sao.address == "" {
+		return nil, fmt.Errorf("invalid syslog address %q: missing host or path", opts.Address)
+	}
+
+	facility, ok := syslogFacilities[strings.ToLower(opts.Facility)]
+	if !ok {
+		return nil, fmt.Errorf("unknown syslog facility %q", opts.Facility)
+	}
+	sao.priority = facility*8 + syslogSeverity
+
+	sao.appName = headerField(opts.AppName, maxAppNameSize)
+	if hostname, err := os.Hostname(); err == nil {
+		sao.hostname = headerField(hostname, 255)
+	} else {
+		sao.hostname = "-"
+	}
+	return sao, nil
+}
+
+// Close closes the connection to the syslog daemon.
+func (sao *SyslogAuditObserver) Close() error {
+	sao.mu.Lock()
+	defer sao.mu.Unlock()
+
+	if sao.conn == nil {
+		return nil
+	}
+	err := sao.conn.Close()
+	sao.conn = nil
+	return err
+}
+
+func (sao *SyslogAuditObserver) Notify(ctx context.Context, event *models.AuditEvent) error {
+	msg, err := sao.format(event)
+	if err != nil {
+		return err
+	}
+
+	sao.mu.Lock()
+	defer sao.mu.Unlock()
+
+	if sao.conn == nil {
+		if err = sao.dial(ctx); err != nil {
+			return err
+		}
+	}
+
+	deadline := time.Now().Add(syslogTimeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	if err = sao.conn.SetWriteDeadline(deadline); err == nil {
+		_, err = sao.conn.Write(sao.frame(msg))
+	}
+	if err != nil {
+		// Reconnect on the next event, e.g. after the daemon was restarted.
+		sao.conn.Close()
+		sao.conn = nil
+		return fmt.Errorf("failed to send audit event to syslog: %w", err)
+	}
+	return nil
+}
+
+func (sao *SyslogAuditObserver) dial(ctx context.Context) error {
+	dialer := net.Dialer{Timeout: syslogTimeout}
+	if sao.network != "unix" {
+		conn, err := dialer.DialContext(ctx, sao.network, sao.address)
+		if err != nil {
+			return fmt.Errorf("failed to connect to syslog: %w", err)
+		}
+		sao.conn, sao.connNetwork = conn, sao.network
+		return nil
+	}
+
+	// Local daemons usually listen on a datagram socket; fall back to a stream socket like log/syslog does.
+	network := "unixgram"
+	conn, err := dialer.DialContext(ctx, network, sao.address)
+	if err != nil {
+		var streamErr error
+		network = "unix"
+		if conn, streamErr = dialer.DialContext(ctx, network, sao.address); streamErr != nil {
+			return fmt.Errorf("failed to connect to syslog: %w", errors.Join(err, streamErr))
+		}
+	}
+	sao.conn, sao.connNetwork = conn, network
+	return nil
+}
+
+// frame prepares msg for the transport: octet counting over TCP, a trailing newline over unix stream sockets.
+func (sao *SyslogAuditObserver) frame(msg []byte) []byte {
+	switch sao.connNetwork {
+	case "tcp":
+		return append([]byte(strconv.Itoa(len(msg))+" "), msg...)
+	case "unix":
+		return append(msg, '\n')
+	default:
+		return msg
+	}
+}
+
+// format returns the RFC 5424 message for event:
+// <PRI>1 TIMESTAMP HOSTNAME APP-NAME PROCID MSGID [audit@32473 ...] BOM{event JSON}
+func (sao *SyslogAuditObserver) format(event *models.AuditEvent) ([]byte, error) {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal audit event: %w", err)
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "<%d>1 %s %s %s %s %s [%s",
+		sao.priority,
+		time.Unix(event.Timestamp, 0).UTC().Format(time.RFC3339),
+		sao.hostname, sao.appName, sao.procID, syslogMsgID, syslogSDID,
+	)
+	writeSDParam(&b, "ip", event.IPAddress)
+	writeSDParam(&b, "metrics", strings.Join(event.Metrics, ","))
+	writeSDParam(&b, "request_id", event.RequestID)
+	writeSDParam(&b, "client_request_id", event.ClientRequestID)
+	if event.Agent != nil {
+		writeSDParam(&b, "agent_key_id", event.Agent.KeyID)
+		writeSDParam(&b, "agent_tls_cn", event.Agent.TLSCommonName)
+	}
+	b.WriteString("] \ufeff")
+	b.Write(data)
+	return []byte(b.String()), nil
+}
+
+// writeSDParam appends a structured-data parameter, escaping '"', '\' and ']' as RFC 5424 requires.
+// Empty values are omitted.
+func writeSDParam(b *strings.Builder, name, value string) {
+	if value == "" {
+		return
+	}
+	b.WriteString(" " + name + `="`)
+	for _, r := range value {
+		if r == '"' || r == '\\' || r == ']' {
+			b.WriteByte('\\')
+		}
+		b.WriteRune(r)
+	}
+	b.WriteByte('"')
+}
+
+// headerField returns s restricted to printable US-ASCII without spaces and truncated to size, or "-" if empty.
+func headerField(s string, size int) string {
+	s = strings.Map(func(r rune) rune {
+		if r < 33 || r > 126 {
+			return -1
+		}
+		return r
+	}, s)
+	if len(s) > size {
+		s = s[:size]
+	}
+	if s == "" {
+		return "-"
+	}
+	return s
+}
@@ -0,0 +1,133 @@
+package audit
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Pro100x3mal/go-musthave-metrics/internal/server/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var syslogTestEvent = &models.AuditEvent{
+	Timestamp: 1700000000,
+	Metrics:   []string{"Alloc", "PollCount"},
+	IPAddress: "10.0.0.1",
+	RequestID: `host/a"b]c`,
+	Agent:     &models.AgentIdentity{KeyID: "0123abcd"},
+}
+
+func TestSyslogAuditObserver_Format(t *testing.T) {
+	sao, err := NewSyslogAuditObserver(SyslogOptions{Address: "udp://127.0.0.1:514", Facility: "auth", AppName: "metrics server"})
+	require.NoError(t, err)
+	sao.hostname = "host"
+	sao.procID = "42"
+
+	msg, err := sao.format(syslogTestEvent)
+	require.NoError(t, err)
+
+	header, body, ok := strings.Cut(string(msg), "] \ufeff")
+	require.True(t, ok)
+	assert.Equal(t, `<37>1 2023-11-14T22:13:20Z host metricsserver 42 audit [audit@32473 ip="10.0.0.1" metrics="Alloc,PollCount" `+
+		`request_id="host/a\"b\]c" agent_key_id="0123abcd"`, header)
+	assert.JSONEq(t, `{"ts":1700000000,"metrics":["Alloc","PollCount"],"ip_address":"10.0.0.1","request_id":"host/a\"b]c","agent":{"key_id":"0123abcd"}}`, body)
+}
+
+func TestNewSyslogAuditObserver_InvalidOptions(t *testing.T) {
+	for _, opts := range []SyslogOptions{
+		{Address: "http://localhost:514", Facility: "local0"},
+		{Address: "udp://", Facility: "local0"},
+		{Address: "udp://localhost:514", Facility: "local9"},
+	} {
+		_, err := NewSyslogAuditObserver(opts)
+		assert.Error(t, err, opts.Address)
+	}
+}
+
+func TestSyslogAuditObserver_Transports(t *testing.T) {
+	t.Run("udp", func(t *testing.T) {
+		conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+		require.NoError(t, err)
+		defer conn.Close()
+
+		sendSyslogEvent(t, "udp://"+conn.LocalAddr().String())
+		assertSyslogMessage(t, readPacket(t, conn))
+	})
+
+	t.Run("unixgram", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "log.sock")
+		conn, err := net.ListenPacket("unixgram", path)
+		require.NoError(t, err)
+		defer conn.Close()
+
+		sendSyslogEvent(t, "unix://"+path)
+		assertSyslogMessage(t, readPacket(t, conn))
+	})
+
+	t.Run("tcp", func(t *testing.T) {
+		lis, err := net.Listen("tcp", "127.0.0.1:0")
+		require.NoError(t, err)
+		defer lis.Close()
+
+		received := make(chan string, 1)
+		go func() {
+			conn, err := lis.Accept()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+			r := bufio.NewReader(conn)
+			length, err := r.ReadString(' ')
+			if err != nil {
+				return
+			}
+			n, _ := strconv.Atoi(strings.TrimSpace(length))
+			buf := make([]byte, n)
+			if _, err = r.Read(buf); err == nil {
+				received <- string(buf)
+			}
+		}()
+
+		sendSyslogEvent(t, "tcp://"+lis.Addr().String())
+		select {
+		case msg := <-received:
+			assertSyslogMessage(t, msg)
+		case <-time.After(5 * time.Second):
+			t.Fatal("no message received")
+		}
+	})
+}
+
+func sendSyslogEvent(t *testing.T, address string) {
+	t.Helper()
+
+	sao, err := NewSyslogAuditObserver(SyslogOptions{Address: address, Facility: "local0", AppName: "metrics"})
+	require.NoError(t, err)
+	defer sao.Close()
+	require.NoError(t, sao.Notify(context.Background(), syslogTestEvent))
+}
+
+func readPacket(t *testing.T, conn net.PacketConn) string {
+	t.Helper()
+
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(5*time.Second)))
+	buf := make([]byte, 64*1024)
+	n, _, err := conn.ReadFrom(buf)
+	require.NoError(t, err)
+	return string(buf[:n])
+}
+
+func assertSyslogMessage(t *testing.T, msg string) {
+	t.Helper()
+
+	hostname, _ := os.Hostname()
+	assert.True(t, strings.HasPrefix(msg, "<133>1 2023-11-14T22:13:20Z "+headerField(hostname, 255)+" metrics "), msg)
+	assert.Contains(t, msg, `[audit@32473 ip="10.0.0.1"`)
+}
@@ -0,0 +1,45 @@
+package middlewares
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+
+	"go.uber.org/zap"
+)
+
+// AdminHandler restricts administrative endpoints to requests carrying the admin bearer token.
+type AdminHandler struct {
+	logger *zap.Logger
+	token  string
+}
+
+// NewAdminHandler creates a new AdminHandler with the provided logger and token.
+// If token is empty, the middleware rejects all requests, so administrative endpoints are disabled unless configured.
+func NewAdminHandler(logger *zap.Logger, token string) *AdminHandler {
+	return &AdminHandler{
+		logger: logger,
+		token:  token,
+	}
+}
+
+// Middleware requires an "Authorization: Bearer <token>" header matching the admin token.
+// Returns 403 Forbidden if no token is configured and 401 Unauthorized if the header is missing or wrong.
+func (ah *AdminHandler) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if ah.token == "" {
+			http.Error(w, "Admin API is disabled", http.StatusForbidden)
+			return
+		}
+
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(ah.token)) != 1 {
+			ah.logger.Warn("unauthorized admin request", zap.String("path", r.URL.Path), zap.String("remote_addr", r.RemoteAddr))
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
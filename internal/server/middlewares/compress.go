@@ -88,10 +88,7 @@ func (cw *compressWriter) WriteHeader(statusCode int) {
 
 	cw.writeHeaderCalled = true
 
-	isJSON := strings.Contains(cw.w.Header().Get("Content-Type"), "application/json")
-	isHTML := strings.Contains(cw.w.Header().Get("Content-Type"), "text/html")
-
-	if statusCode < 300 && (isJSON || isHTML) {
+	if statusCode < 300 && isCompressible(cw.w.Header().Get("Content-Type")) {
 		cw.shouldCompress = true
 		cw.w.Header().Set("Content-Encoding", "gzip")
 	}
@@ -99,6 +96,14 @@ func (cw *compressWriter) WriteHeader(statusCode int) {
 	cw.w.WriteHeader(statusCode)
 }
 
+// isCompressible reports whether responses of the given content type are gzip-compressed:
+// JSON, HTML and the Prometheus text exposition format.
+func isCompressible(contentType string) bool {
+	return strings.Contains(contentType, "application/json") ||
+		strings.Contains(contentType, "text/html") ||
+		(strings.Contains(contentType, "text/plain") && strings.Contains(contentType, "version=0.0.4"))
+}
+
 func (cw *compressWriter) Close() error {
 	if cw.shouldCompress {
 		return cw.gzw.Close()
@@ -196,3 +196,29 @@ func TestDecryptHandler_WithoutKeys(t *testing.T) {
 
 	assert.Equal(t, http.StatusOK, rec.Code)
 }
+
+func TestDecryptHandler_LargePayload(t *testing.T) {
+	privateKey, publicKey := generateTestKeyPair(t)
+
+	originalData := bytes.Repeat([]byte(`{"id":"Alloc","type":"gauge","value":123456.789},`), 1000)
+
+	encryptedData, err := crypto.Encrypt(publicKey, originalData)
+	require.NoError(t, err)
+
+	req := httptest.NewRequest(http.MethodPost, "/updates/", bytes.NewReader(encryptedData))
+	rec := httptest.NewRecorder()
+
+	nextHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+		assert.Equal(t, originalData, body, "decrypted data should match original")
+		w.WriteHeader(http.StatusOK)
+	})
+
+	decryptHandler := NewDecryptHandler(zap.NewNop(), privateKey)
+	handler := decryptHandler.Middleware(nextHandler)
+
+	handler.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+}
@@ -0,0 +1,20 @@
+package middlewares
+
+import (
+	"net/http"
+
+	"github.com/Pro100x3mal/go-musthave-metrics/internal/server/infrastructure/audit"
+)
+
+// RequestIDHeader is the header carrying the request ID, both from the client and in the response.
+const RequestIDHeader = "X-Request-Id"
+
+// RequestID gives every request a request ID generated by the server and returns it in the X-Request-Id response header.
+// An X-Request-Id sent by the client is not trusted as the request ID; it is kept, sanitized, as the client request ID.
+func RequestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := audit.NewRequestID()
+		w.Header().Set(RequestIDHeader, id)
+		next.ServeHTTP(w, r.WithContext(audit.WithRequestID(r.Context(), id, r.Header.Get(RequestIDHeader))))
+	})
+}
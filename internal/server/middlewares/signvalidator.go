@@ -8,6 +8,7 @@ import (
 	"io"
 	"net/http"
 
+	"github.com/Pro100x3mal/go-musthave-metrics/internal/server/infrastructure/audit"
 	"go.uber.org/zap"
 )
 
@@ -15,6 +16,7 @@ import (
 type SignHandler struct {
 	logger *zap.Logger
 	key    string
+	keyID  string
 }
 
 // NewSignHandler creates a new SignHandler with the provided logger and HMAC key.
@@ -23,6 +25,7 @@ func NewSignHandler(logger *zap.Logger, key string) *SignHandler {
 	return &SignHandler{
 		logger: logger,
 		key:    key,
+		keyID:  audit.KeyID(key),
 	}
 }
 
@@ -64,7 +67,8 @@ func signBody(body []byte, key string) []byte {
 }
 
 // Middleware validates incoming request signatures via the HashSHA256 header and signs all responses.
-// If a request includes a HashSHA256 header, it verifies the HMAC-SHA256 signature of the request body.
+// If a request includes a HashSHA256 header, it verifies the HMAC-SHA256 signature of the request body
+// and records the key identifier in the request context for auditing.
 // All responses are signed with HMAC-SHA256 and the signature is included in the HashSHA256 response header.
 func (sh *SignHandler) Middleware(next http.Handler) http.Handler {
 	if sh.key == "" {
@@ -94,6 +98,7 @@ func (sh *SignHandler) Middleware(next http.Handler) http.Handler {
 				return
 			}
 			r.Body = io.NopCloser(bytes.NewReader(body))
+			r = r.WithContext(audit.WithSigningKeyID(r.Context(), sh.keyID))
 		}
 		srw := newSignResponseWriter(w)
 
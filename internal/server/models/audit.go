@@ -1,7 +1,52 @@
 package models
 
+// AuditEvent is a record of a metrics update.
+// Metrics lists the updated metric names and is kept for consumers of the original format; Changes has the details.
+// RequestID is generated by the server; ClientRequestID is the sanitized request ID sent by the client, which cannot be relied on.
+// Seq and PrevHash are set by the file audit log, where every record is chained to the previous one.
 type AuditEvent struct {
-	Timestamp int64    `json:"ts"`
-	Metrics   []string `json:"metrics"`
-	IPAddress string   `json:"ip_address"`
+	Timestamp       int64          `json:"ts"`
+	Metrics         []string       `json:"metrics"`
+	IPAddress       string         `json:"ip_address"`
+	RequestID       string         `json:"request_id,omitempty"`
+	ClientRequestID string         `json:"client_request_id,omitempty"`
+	Agent           *AgentIdentity `json:"agent,omitempty"`
+	Changes         []MetricChange `json:"changes,omitempty"`
+	Seq             uint64         `json:"seq,omitempty"`
+	PrevHash        string         `json:"prev_hash,omitempty"`
+}
+
+// AgentIdentity identifies the client that sent an update, as far as the server can tell.
+type AgentIdentity struct {
+	// KeyID identifies the signing key of a request with a valid signature.
+	KeyID string `json:"key_id,omitempty"`
+	// TLSCommonName is the common name of the verified client certificate.
+	TLSCommonName string `json:"tls_cn,omitempty"`
+}
+
+// MetricChange is a single metric update: the written value or delta and the value stored before the update.
+// The previous fields are empty if the series did not exist or its value is unknown.
+// For counters PrevDelta is the previous total.
+type MetricChange struct {
+	ID            string          `json:"id"`
+	MType         string          `json:"type"`
+	Labels        Labels          `json:"labels,omitempty"`
+	Delta         *int64          `json:"delta,omitempty"`
+	Value         *float64        `json:"value,omitempty"`
+	Histogram     *HistogramValue `json:"histogram,omitempty"`
+	PrevDelta     *int64          `json:"prev_delta,omitempty"`
+	PrevValue     *float64        `json:"prev_value,omitempty"`
+	PrevHistogram *HistogramValue `json:"prev_histogram,omitempty"`
+}
+
+// NewMetricChange returns the change written by metric, without the previous value.
+func NewMetricChange(metric *Metrics) MetricChange {
+	return MetricChange{
+		ID:        metric.ID,
+		MType:     metric.MType,
+		Labels:    metric.Labels,
+		Delta:     metric.Delta,
+		Value:     metric.Value,
+		Histogram: metric.Histogram,
+	}
 }
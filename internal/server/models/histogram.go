@@ -0,0 +1,84 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+)
+
+// ErrHistogramBoundsMismatch is returned when histogram observations are merged into a histogram with different bucket bounds.
+var ErrHistogramBoundsMismatch = errors.New("histogram bucket bounds mismatch")
+
+// HistogramValue is a distribution of observations over fixed buckets.
+//
+// Bounds are the finite, strictly increasing upper bounds of the buckets; an implicit last bucket
+// collects everything above the largest bound. Counts holds the non-cumulative number of observations
+// per bucket, so it has exactly len(Bounds)+1 elements. Sum and Count are the sum and the total number of observations.
+//
+// Like counter deltas, histograms sent by clients carry the observations made since the previous report
+// and are added to the stored histogram: bucket counts, sum and count are summed. Histograms of the same
+// series must always use the same bounds; merging histograms with different bounds fails with ErrHistogramBoundsMismatch.
+type HistogramValue struct {
+	Bounds []float64 `json:"bounds"`
+	Counts []uint64  `json:"counts"`
+	Sum    float64   `json:"sum"`
+	Count  uint64    `json:"count"`
+}
+
+// Validate checks that the bounds are ordered and finite and that the counts are consistent with the bounds and the total count.
+func (h *HistogramValue) Validate() error {
+	if h == nil {
+		return fmt.Errorf("%w: missing histogram", ErrInvalidMetricValue)
+	}
+	for i, b := range h.Bounds {
+		if math.IsNaN(b) || math.IsInf(b, 0) {
+			return fmt.Errorf("%w: histogram bound %v is not finite", ErrInvalidMetricValue, b)
+		}
+		if i > 0 && b <= h.Bounds[i-1] {
+			return fmt.Errorf("%w: histogram bounds must be strictly increasing", ErrInvalidMetricValue)
+		}
+	}
+	if len(h.Counts) != len(h.Bounds)+1 {
+		return fmt.Errorf("%w: histogram with %d bounds must have %d bucket counts, got %d",
+			ErrInvalidMetricValue, len(h.Bounds), len(h.Bounds)+1, len(h.Counts))
+	}
+
+	var total uint64
+	for _, c := range h.Counts {
+		total += c
+	}
+	if total != h.Count {
+		return fmt.Errorf("%w: histogram count %d does not match bucket counts total %d", ErrInvalidMetricValue, h.Count, total)
+	}
+	if math.IsNaN(h.Sum) || math.IsInf(h.Sum, 0) {
+		return fmt.Errorf("%w: histogram sum is not finite", ErrInvalidMetricValue)
+	}
+	return nil
+}
+
+// Merge adds the observations of other to h. Both histograms must have the same bounds.
+func (h *HistogramValue) Merge(other *HistogramValue) error {
+	if !slices.Equal(h.Bounds, other.Bounds) || len(h.Counts) != len(other.Counts) {
+		return ErrHistogramBoundsMismatch
+	}
+	for i, c := range other.Counts {
+		h.Counts[i] += c
+	}
+	h.Sum += other.Sum
+	h.Count += other.Count
+	return nil
+}
+
+// Clone returns a deep copy of h.
+func (h *HistogramValue) Clone() *HistogramValue {
+	if h == nil {
+		return nil
+	}
+	return &HistogramValue{
+		Bounds: slices.Clone(h.Bounds),
+		Counts: slices.Clone(h.Counts),
+		Sum:    h.Sum,
+		Count:  h.Count,
+	}
+}
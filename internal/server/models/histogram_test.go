@@ -0,0 +1,57 @@
+package models
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHistogramValue_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		h       *HistogramValue
+		wantErr bool
+	}{
+		{name: "valid", h: &HistogramValue{Bounds: []float64{0.1, 1}, Counts: []uint64{1, 0, 2}, Sum: 5, Count: 3}},
+		{name: "single bucket", h: &HistogramValue{Counts: []uint64{2}, Sum: 1, Count: 2}},
+		{name: "nil", h: nil, wantErr: true},
+		{name: "unordered bounds", h: &HistogramValue{Bounds: []float64{1, 1}, Counts: []uint64{0, 0, 0}}, wantErr: true},
+		{name: "infinite bound", h: &HistogramValue{Bounds: []float64{math.Inf(1)}, Counts: []uint64{0, 0}}, wantErr: true},
+		{name: "wrong number of counts", h: &HistogramValue{Bounds: []float64{1}, Counts: []uint64{1}, Count: 1}, wantErr: true},
+		{name: "count mismatch", h: &HistogramValue{Bounds: []float64{1}, Counts: []uint64{1, 1}, Count: 3}, wantErr: true},
+		{name: "nan sum", h: &HistogramValue{Counts: []uint64{0}, Sum: math.NaN()}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.h.Validate()
+			if tt.wantErr {
+				assert.ErrorIs(t, err, ErrInvalidMetricValue)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestHistogramValue_Merge(t *testing.T) {
+	h := &HistogramValue{Bounds: []float64{0.1, 1}, Counts: []uint64{1, 0, 2}, Sum: 5, Count: 3}
+
+	require.NoError(t, h.Merge(&HistogramValue{Bounds: []float64{0.1, 1}, Counts: []uint64{0, 1, 0}, Sum: 0.5, Count: 1}))
+	assert.Equal(t, &HistogramValue{Bounds: []float64{0.1, 1}, Counts: []uint64{1, 1, 2}, Sum: 5.5, Count: 4}, h)
+
+	err := h.Merge(&HistogramValue{Bounds: []float64{0.5}, Counts: []uint64{1, 0}, Sum: 0.2, Count: 1})
+	assert.ErrorIs(t, err, ErrHistogramBoundsMismatch)
+	assert.Equal(t, uint64(4), h.Count)
+}
+
+func TestHistogramValue_Clone(t *testing.T) {
+	h := &HistogramValue{Bounds: []float64{1}, Counts: []uint64{1, 0}, Sum: 0.5, Count: 1}
+	c := h.Clone()
+	c.Counts[0] = 10
+
+	assert.Equal(t, uint64(1), h.Counts[0])
+	assert.Nil(t, (*HistogramValue)(nil).Clone())
+}
@@ -0,0 +1,16 @@
+package models
+
+import (
+	"errors"
+	"time"
+)
+
+// ErrInvalidTimeRange is returned when a history query has a malformed or inverted time range.
+var ErrInvalidTimeRange = errors.New("invalid time range")
+
+// Sample represents a single timestamped value of a metric.
+// For counter metrics, Value holds the accumulated counter value at the time of the update.
+type Sample struct {
+	Timestamp time.Time `json:"ts"`
+	Value     float64   `json:"value"`
+}
@@ -0,0 +1,174 @@
+package models
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ErrInvalidLabels is returned when label names or label matchers are malformed.
+var ErrInvalidLabels = errors.New("invalid labels")
+
+// Labels is a set of metric dimensions. Together with the metric ID and type it identifies a series.
+// Label names must match [a-zA-Z_][a-zA-Z0-9_]*; values are arbitrary strings.
+type Labels map[string]string
+
+// Validate checks that all label names are well-formed.
+func (l Labels) Validate() error {
+	for name := range l {
+		if !isValidLabelName(name) {
+			return fmt.Errorf("%w: label name %q", ErrInvalidLabels, name)
+		}
+	}
+	return nil
+}
+
+// String returns the canonical representation of the label set: name="value" pairs
+// sorted by name and separated by commas, with values quoted as Go string literals.
+// An empty label set is represented by an empty string.
+func (l Labels) String() string {
+	if len(l) == 0 {
+		return ""
+	}
+
+	names := make([]string, 0, len(l))
+	for name := range l {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var sb strings.Builder
+	for i, name := range names {
+		if i > 0 {
+			sb.WriteByte(',')
+		}
+		sb.WriteString(name)
+		sb.WriteByte('=')
+		sb.WriteString(strconv.Quote(l[name]))
+	}
+	return sb.String()
+}
+
+// Hash returns a stable SHA-256 hex digest of the canonical label set, or an empty string for no labels.
+func (l Labels) Hash() string {
+	if len(l) == 0 {
+		return ""
+	}
+	sum := sha256.Sum256([]byte(l.String()))
+	return hex.EncodeToString(sum[:])
+}
+
+// Matches reports whether every matcher label is present in l with the same value.
+func (l Labels) Matches(matchers Labels) bool {
+	for name, value := range matchers {
+		if v, ok := l[name]; !ok || v != value {
+			return false
+		}
+	}
+	return true
+}
+
+// SeriesKey returns the storage identity of a metric: the ID itself for label-less metrics,
+// or ID{name="value",...} with the canonical label set otherwise.
+func SeriesKey(id string, labels Labels) string {
+	if len(labels) == 0 {
+		return id
+	}
+	return id + "{" + labels.String() + "}"
+}
+
+// SeriesKey returns the storage identity of the metric.
+func (m *Metrics) SeriesKey() string {
+	return SeriesKey(m.ID, m.Labels)
+}
+
+// ParseSeriesKey splits a key produced by SeriesKey back into the metric ID and its labels.
+// Keys without a well-formed label block are returned unchanged as the ID.
+func ParseSeriesKey(key string) (string, Labels) {
+	if !strings.HasSuffix(key, "}") {
+		return key, nil
+	}
+
+	for i := strings.IndexByte(key, '{'); i >= 0; {
+		if labels, err := parseCanonicalLabels(key[i+1 : len(key)-1]); err == nil && len(labels) > 0 {
+			return key[:i], labels
+		}
+		next := strings.IndexByte(key[i+1:], '{')
+		if next < 0 {
+			break
+		}
+		i += next + 1
+	}
+	return key, nil
+}
+
+func parseCanonicalLabels(s string) (Labels, error) {
+	labels := make(Labels)
+	for len(s) > 0 {
+		eq := strings.IndexByte(s, '=')
+		if eq <= 0 {
+			return nil, ErrInvalidLabels
+		}
+		name := s[:eq]
+		if !isValidLabelName(name) {
+			return nil, ErrInvalidLabels
+		}
+
+		quoted, err := strconv.QuotedPrefix(s[eq+1:])
+		if err != nil {
+			return nil, ErrInvalidLabels
+		}
+		value, err := strconv.Unquote(quoted)
+		if err != nil {
+			return nil, ErrInvalidLabels
+		}
+		labels[name] = value
+
+		s = s[eq+1+len(quoted):]
+		if len(s) > 0 {
+			if s[0] != ',' || len(s) == 1 {
+				return nil, ErrInvalidLabels
+			}
+			s = s[1:]
+		}
+	}
+	return labels, nil
+}
+
+// ParseLabels parses a comma-separated list of name=value pairs, as used in query parameters
+// (e.g. "host=h42,dc=eu"). An empty string yields no labels.
+func ParseLabels(s string) (Labels, error) {
+	if s == "" {
+		return nil, nil
+	}
+
+	labels := make(Labels)
+	for _, pair := range strings.Split(s, ",") {
+		name, value, ok := strings.Cut(pair, "=")
+		name = strings.TrimSpace(name)
+		if !ok || !isValidLabelName(name) {
+			return nil, fmt.Errorf("%w: %q", ErrInvalidLabels, pair)
+		}
+		labels[name] = value
+	}
+	return labels, nil
+}
+
+func isValidLabelName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for i, c := range name {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c == '_':
+		case c >= '0' && c <= '9' && i > 0:
+		default:
+			return false
+		}
+	}
+	return true
+}
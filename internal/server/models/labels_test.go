@@ -0,0 +1,79 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSeriesKey_RoundTrip(t *testing.T) {
+	tests := []struct {
+		name   string
+		id     string
+		labels Labels
+		want   string
+	}{
+		{name: "no labels", id: "Alloc", want: "Alloc"},
+		{name: "sorted labels", id: "Load", labels: Labels{"host": "h42", "dc": "eu"}, want: `Load{dc="eu",host="h42"}`},
+		{name: "escaped value", id: "Load", labels: Labels{"path": `a,"b"}`}, want: `Load{path="a,\"b\"}"}`},
+		{name: "id with braces", id: "odd{name}", labels: Labels{"host": "h42"}, want: `odd{name}{host="h42"}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			key := SeriesKey(tt.id, tt.labels)
+			assert.Equal(t, tt.want, key)
+
+			id, labels := ParseSeriesKey(key)
+			assert.Equal(t, tt.id, id)
+			assert.Equal(t, tt.labels, labels)
+		})
+	}
+}
+
+func TestValidateMetricID(t *testing.T) {
+	assert.NoError(t, ValidateMetricID("CPUutilization0"))
+	assert.NoError(t, ValidateMetricID("disk.io_time"))
+	for _, id := range []string{"", `cpu{host="a"}`, "cpu{", "cpu}", `cpu"`} {
+		assert.ErrorIs(t, ValidateMetricID(id), ErrInvalidMetricID, id)
+	}
+}
+
+func TestParseSeriesKey_PlainID(t *testing.T) {
+	id, labels := ParseSeriesKey("metric{not labels}")
+	assert.Equal(t, "metric{not labels}", id)
+	assert.Nil(t, labels)
+}
+
+func TestParseLabels(t *testing.T) {
+	labels, err := ParseLabels("host=h42, dc=eu")
+	require.NoError(t, err)
+	assert.Equal(t, Labels{"host": "h42", "dc": "eu"}, labels)
+
+	labels, err = ParseLabels("")
+	require.NoError(t, err)
+	assert.Nil(t, labels)
+
+	_, err = ParseLabels("host")
+	assert.ErrorIs(t, err, ErrInvalidLabels)
+
+	_, err = ParseLabels("1host=h42")
+	assert.ErrorIs(t, err, ErrInvalidLabels)
+}
+
+func TestLabels_Matches(t *testing.T) {
+	labels := Labels{"host": "h42", "dc": "eu"}
+
+	assert.True(t, labels.Matches(nil))
+	assert.True(t, labels.Matches(Labels{"dc": "eu"}))
+	assert.False(t, labels.Matches(Labels{"dc": "us"}))
+	assert.False(t, labels.Matches(Labels{"rack": "1"}))
+	assert.False(t, Labels(nil).Matches(Labels{"dc": "eu"}))
+}
+
+func TestLabels_Hash(t *testing.T) {
+	assert.Empty(t, Labels(nil).Hash())
+	assert.Equal(t, Labels{"a": "1", "b": "2"}.Hash(), Labels{"b": "2", "a": "1"}.Hash())
+	assert.NotEqual(t, Labels{"a": "1"}.Hash(), Labels{"a": "2"}.Hash())
+}
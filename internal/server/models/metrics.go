@@ -1,12 +1,19 @@
 package models
 
-import "errors"
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
 
 const (
 	// Counter represents the counter metric type.
 	Counter = "counter"
 	// Gauge represents the gauge metric type.
 	Gauge = "gauge"
+	// Histogram represents the histogram metric type.
+	Histogram = "histogram"
 )
 
 var (
@@ -16,13 +23,59 @@ var (
 	ErrInvalidMetricValue = errors.New("invalid metric value")
 	// ErrUnsupportedMetricType is returned when an unsupported metric type is used.
 	ErrUnsupportedMetricType = errors.New("unsupported metric type")
+	// ErrInvalidIdempotencyKey is returned when an idempotency key is malformed.
+	ErrInvalidIdempotencyKey = errors.New("invalid idempotency key")
+	// ErrInvalidMetricID is returned when a metric ID is empty or contains characters reserved for series keys.
+	ErrInvalidMetricID = errors.New("invalid metric id")
+	// ErrDuplicateIdempotencyKey is returned when a batch with the same idempotency key has already been applied.
+	ErrDuplicateIdempotencyKey = errors.New("batch with this idempotency key has already been applied")
 )
 
 // Metrics represents a single metric with its type and value.
 // For counter metrics, Delta field is used. For gauge metrics, Value field is used.
+// For histogram metrics, Histogram field is used.
+// Optional Labels distinguish series of the same metric, e.g. per host.
 type Metrics struct {
-	ID    string   `json:"id"`
-	MType string   `json:"type"`
-	Delta *int64   `json:"delta,omitempty"`
-	Value *float64 `json:"value,omitempty"`
+	ID        string          `json:"id"`
+	MType     string          `json:"type"`
+	Delta     *int64          `json:"delta,omitempty"`
+	Value     *float64        `json:"value,omitempty"`
+	Histogram *HistogramValue `json:"histogram,omitempty"`
+	Labels    Labels          `json:"labels,omitempty"`
+}
+
+// ValidateMetricID checks that id can identify a series: it must not be empty and must not contain
+// the characters {, } and ", which delimit the label set in a series key.
+func ValidateMetricID(id string) error {
+	if id == "" || strings.ContainsAny(id, "{}\"") {
+		return fmt.Errorf("%w: %q", ErrInvalidMetricID, id)
+	}
+	return nil
+}
+
+// ParseMetric builds a gauge or counter update from its textual type, name and value, as given in an update URL.
+func ParseMetric(mType, mName, mValue string) (*Metrics, error) {
+	metric := &Metrics{ID: mName, MType: mType}
+
+	switch mType {
+	case Gauge:
+		value, err := strconv.ParseFloat(mValue, 64)
+		if err != nil {
+			return nil, ErrInvalidMetricValue
+		}
+		metric.Value = &value
+	case Counter:
+		delta, err := strconv.ParseInt(mValue, 10, 64)
+		if err != nil {
+			return nil, ErrInvalidMetricValue
+		}
+		metric.Delta = &delta
+	default:
+		return nil, ErrUnsupportedMetricType
+	}
+
+	if err := ValidateMetricID(mName); err != nil {
+		return nil, err
+	}
+	return metric, nil
 }
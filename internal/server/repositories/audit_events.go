@@ -0,0 +1,150 @@
+package repositories
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/Pro100x3mal/go-musthave-metrics/internal/server/models"
+)
+
+const auditEventColumns = 7
+
+// Widths of the VARCHAR columns of audit_events, in characters.
+const (
+	auditIPAddressWidth = 64
+	auditRequestIDWidth = 255
+	auditKeyIDWidth     = 64
+	auditTLSCNWidth     = 255
+	auditMetricWidth    = 255
+)
+
+// InsertAuditEvents stores audit events in the audit_events table in a single transaction,
+// with one multi-row INSERT per chunk of events.
+func (db *DB) InsertAuditEvents(ctx context.Context, events []*models.AuditEvent) error {
+	if len(events) == 0 {
+		return nil
+	}
+
+	tx, err := db.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	for start := 0; start < len(events); start += defaultChunkSize {
+		chunk := events[start:min(start+defaultChunkSize, len(events))]
+
+		values := make([]string, 0, len(chunk))
+		args := make([]any, 0, len(chunk)*auditEventColumns)
+		for i, e := range chunk {
+			row, err := auditEventRow(e)
+			if err != nil {
+				return err
+			}
+
+			base := i * auditEventColumns
+			values = append(values, fmt.Sprintf("($%d, $%d, $%d, $%d, $%d, $%d, $%d)",
+				base+1, base+2, base+3, base+4, base+5, base+6, base+7))
+			args = append(args, row...)
+		}
+
+		query := `INSERT INTO audit_events (ts, ip_address, request_id, agent_key_id, agent_tls_cn, metrics, event) VALUES ` +
+			strings.Join(values, ",")
+		if _, err = tx.Exec(ctx, query, args...); err != nil {
+			if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, context.Canceled) {
+				return err
+			}
+			return fmt.Errorf("failed to insert audit events: %w", err)
+		}
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// auditEventRow returns the values of the audit_events columns for an event. Values PostgreSQL would reject,
+// e.g. from an oversized client header or one with invalid UTF-8, are truncated and sanitized so that they
+// do not fail the whole batch; the event column keeps the complete event.
+func auditEventRow(e *models.AuditEvent) ([]any, error) {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal audit event: %w", err)
+	}
+	data = stripJSONNUL(data)
+
+	var keyID, tlsCN string
+	if e.Agent != nil {
+		keyID, tlsCN = e.Agent.KeyID, e.Agent.TLSCommonName
+	}
+	metrics := make([]string, len(e.Metrics))
+	for i, m := range e.Metrics {
+		metrics[i] = truncateColumn(m, auditMetricWidth)
+	}
+
+	return []any{
+		time.Unix(e.Timestamp, 0).UTC(),
+		truncateColumn(e.IPAddress, auditIPAddressWidth),
+		truncateColumn(e.RequestID, auditRequestIDWidth),
+		truncateColumn(keyID, auditKeyIDWidth),
+		truncateColumn(tlsCN, auditTLSCNWidth),
+		metrics,
+		data,
+	}, nil
+}
+
+// truncateColumn makes s valid UTF-8 without NUL bytes, which PostgreSQL text cannot hold, and cuts it
+// to at most width characters, the unit of a VARCHAR width. Invalid bytes are replaced with U+FFFD.
+func truncateColumn(s string, width int) string {
+	s = strings.ReplaceAll(strings.ToValidUTF8(s, "\uFFFD"), "\x00", "")
+	if utf8.RuneCountInString(s) <= width {
+		return s
+	}
+	runes := []rune(s)
+	return string(runes[:width])
+}
+
+// stripJSONNUL removes the \u0000 escapes, which JSONB rejects, from the strings of JSON encoded by json.Marshal.
+// json.Marshal already replaces invalid UTF-8 and escapes every backslash, so an escape is a NUL only if
+// it is preceded by an even number of backslashes.
+func stripJSONNUL(data []byte) []byte {
+	const nul = `\u0000`
+	if !bytes.Contains(data, []byte(nul)) {
+		return data
+	}
+
+	out := make([]byte, 0, len(data))
+	backslashes := 0
+	for i := 0; i < len(data); i++ {
+		if backslashes%2 == 0 && bytes.HasPrefix(data[i:], []byte(nul)) {
+			i += len(nul) - 1
+			continue
+		}
+		if data[i] == '\\' {
+			backslashes++
+		} else {
+			backslashes = 0
+		}
+		out = append(out, data[i])
+	}
+	return out
+}
+
+// DeleteAuditEventsBefore deletes audit events with a timestamp before t and returns the number of deleted rows.
+func (db *DB) DeleteAuditEventsBefore(ctx context.Context, t time.Time) (int64, error) {
+	tag, err := db.pool.Exec(ctx, `DELETE FROM audit_events WHERE ts < $1`, t)
+	if err != nil {
+		if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, context.Canceled) {
+			return 0, err
+		}
+		return 0, fmt.Errorf("failed to delete expired audit events: %w", err)
+	}
+	return tag.RowsAffected(), nil
+}
@@ -0,0 +1,79 @@
+package repositories
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/Pro100x3mal/go-musthave-metrics/internal/server/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAuditEventRow_TruncatesOversizedValues(t *testing.T) {
+	// An X-Forwarded-For chain and an X-Request-ID far longer than their columns, with multi-byte characters.
+	forwarded := strings.Repeat("10.0.0.1, ", 50)
+	requestID := strings.Repeat("ж", 1000)
+	event := &models.AuditEvent{
+		Timestamp: 1,
+		Metrics:   []string{"Alloc", strings.Repeat("m", 300)},
+		IPAddress: forwarded,
+		RequestID: requestID,
+		Agent:     &models.AgentIdentity{KeyID: "key", TLSCommonName: strings.Repeat("cn", 200)},
+	}
+
+	row, err := auditEventRow(event)
+	require.NoError(t, err)
+	require.Len(t, row, auditEventColumns)
+
+	assert.Equal(t, forwarded[:auditIPAddressWidth], row[1])
+	assert.Equal(t, strings.Repeat("ж", auditRequestIDWidth), row[2])
+	assert.True(t, utf8.ValidString(row[2].(string)))
+	assert.Equal(t, "key", row[3])
+	assert.Len(t, row[4], auditTLSCNWidth)
+	assert.Equal(t, []string{"Alloc", strings.Repeat("m", auditMetricWidth)}, row[5])
+
+	// The event column keeps the complete values.
+	var stored models.AuditEvent
+	require.NoError(t, json.Unmarshal(row[6].([]byte), &stored))
+	assert.Equal(t, forwarded, stored.IPAddress)
+	assert.Equal(t, requestID, stored.RequestID)
+}
+
+func TestAuditEventRow_InvalidUTF8(t *testing.T) {
+	// X-Forwarded-For and X-Request-ID values with bytes PostgreSQL rejects in text and JSONB.
+	event := &models.AuditEvent{
+		Timestamp: 1,
+		Metrics:   []string{"Alloc\x00"},
+		IPAddress: "10.0.0.\xff",
+		RequestID: "req\x00-42",
+		Agent:     &models.AgentIdentity{TLSCommonName: `agent\u0000`},
+	}
+
+	row, err := auditEventRow(event)
+	require.NoError(t, err)
+
+	assert.Equal(t, "10.0.0.\uFFFD", row[1])
+	assert.Equal(t, "req-42", row[2])
+	assert.Equal(t, `agent\u0000`, row[4], "an escaped backslash is not a NUL")
+	assert.Equal(t, []string{"Alloc"}, row[5])
+	for _, v := range row[1:5] {
+		assert.True(t, utf8.ValidString(v.(string)))
+	}
+
+	data := row[6].([]byte)
+	assert.True(t, utf8.Valid(data))
+	assert.NotContains(t, string(data), `"req\u0000-42"`)
+	var stored models.AuditEvent
+	require.NoError(t, json.Unmarshal(data, &stored))
+	assert.Equal(t, "req-42", stored.RequestID)
+	assert.Equal(t, `agent\u0000`, stored.Agent.TLSCommonName)
+}
+
+func TestAuditEventRow_NilMetrics(t *testing.T) {
+	row, err := auditEventRow(&models.AuditEvent{Timestamp: 1})
+	require.NoError(t, err)
+	assert.Equal(t, []string{}, row[5])
+	assert.Equal(t, "", row[3])
+}
@@ -3,6 +3,7 @@ package repositories
 import (
 	"context"
 	"embed"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"sort"
@@ -106,72 +107,326 @@ func (db *DB) Ping(ctx context.Context) error {
 	return nil
 }
 
+// UpdateMetrics applies a batch in a single transaction, so that a failure such as a histogram bounds mismatch
+// leaves the storage unchanged, as with MemStorage.
 func (db *DB) UpdateMetrics(ctx context.Context, metrics []models.Metrics) error {
+	gaugeChunks, counterChunks, histogramChunks, err := prepareMetricsChunks(metrics)
+	if err != nil {
+		return err
+	}
+
+	tx, err := db.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if err = execMetricsBatch(ctx, tx, gaugeChunks, counterChunks, histogramChunks); err != nil {
+		return err
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+func (db *DB) UpdateMetricsIdempotent(ctx context.Context, key string, metrics []models.Metrics) error {
+	if err := validateIdempotencyKey(key); err != nil {
+		return err
+	}
+
+	gaugeChunks, counterChunks, histogramChunks, err := prepareMetricsChunks(metrics)
+	if err != nil {
+		return err
+	}
+
+	tx, err := db.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if err = claimIdempotencyKey(ctx, tx, key); err != nil {
+		return err
+	}
+
+	if err = execMetricsBatch(ctx, tx, gaugeChunks, counterChunks, histogramChunks); err != nil {
+		return err
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+func (db *DB) UpdateMetricsWithPrevious(ctx context.Context, key string, metrics []models.Metrics) ([]models.Metrics, error) {
+	if key != "" {
+		if err := validateIdempotencyKey(key); err != nil {
+			return nil, err
+		}
+	}
+
+	gaugeChunks, counterChunks, histogramChunks, err := prepareMetricsChunks(metrics)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := db.pool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if key != "" {
+		if err = claimIdempotencyKey(ctx, tx, key); err != nil {
+			return nil, err
+		}
+	}
+
+	previous, err := lockPrevious(ctx, tx, gaugeChunks, counterChunks, histogramChunks)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = execMetricsBatch(ctx, tx, gaugeChunks, counterChunks, histogramChunks); err != nil {
+		return nil, err
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return previous, nil
+}
+
+// claimIdempotencyKey records key within tx, after deleting expired keys.
+// It returns models.ErrDuplicateIdempotencyKey if the key is already recorded.
+func claimIdempotencyKey(ctx context.Context, tx pgx.Tx, key string) error {
+	_, err := tx.Exec(ctx, `DELETE FROM idempotency_keys WHERE applied_at < $1`, time.Now().Add(-IdempotencyKeyTTL))
+	if err != nil {
+		if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, context.Canceled) {
+			return err
+		}
+		return fmt.Errorf("failed to delete expired idempotency keys: %w", err)
+	}
+
+	tag, err := tx.Exec(ctx, `INSERT INTO idempotency_keys (key) VALUES ($1) ON CONFLICT (key) DO NOTHING`, key)
+	if err != nil {
+		if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, context.Canceled) {
+			return err
+		}
+		return fmt.Errorf("failed to insert idempotency key: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return models.ErrDuplicateIdempotencyKey
+	}
+	return nil
+}
+
+// lockPrevious locks the stored rows of the series in the chunks for the rest of tx and returns their values.
+// Series that do not exist yet cannot be locked; a concurrent batch creating the same series may go unnoticed.
+func lockPrevious(ctx context.Context, tx pgx.Tx, gaugeChunks, counterChunks, histogramChunks [][]models.Metrics) ([]models.Metrics, error) {
+	var previous []models.Metrics
+
+	for _, chunk := range gaugeChunks {
+		locked, err := lockScalars(ctx, tx, "gauges", "value", chunk, func(m *models.Metrics, v float64) { m.Value = &v })
+		if err != nil {
+			return nil, err
+		}
+		previous = append(previous, locked...)
+	}
+
+	for _, chunk := range counterChunks {
+		locked, err := lockScalars(ctx, tx, "counters", "delta", chunk, func(m *models.Metrics, v int64) { m.Delta = &v })
+		if err != nil {
+			return nil, err
+		}
+		previous = append(previous, locked...)
+	}
+
+	for _, chunk := range histogramChunks {
+		for _, m := range chunk {
+			stored, err := scanHistogram(tx.QueryRow(ctx, `
+				SELECT bounds, counts, sum, count FROM histograms WHERE id = $1 AND labels_hash = $2 FOR UPDATE
+			`, m.ID, m.Labels.Hash()))
+			if errors.Is(err, pgx.ErrNoRows) {
+				continue
+			}
+			if err != nil {
+				if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, context.Canceled) {
+					return nil, err
+				}
+				return nil, fmt.Errorf("failed to lock histogram %q: %w", m.ID, err)
+			}
+			previous = append(previous, models.Metrics{ID: m.ID, MType: m.MType, Labels: m.Labels, Histogram: stored})
+		}
+	}
+
+	return previous, nil
+}
+
+// lockScalars locks the rows of the gauge or counter series in chunk and returns their values, set with set.
+// Rows are locked in (id, labels_hash) order, so that concurrent batches lock them in the same order.
+func lockScalars[T float64 | int64](ctx context.Context, tx pgx.Tx, table, column string, chunk []models.Metrics, set func(m *models.Metrics, v T)) ([]models.Metrics, error) {
+	type seriesID struct{ id, hash string }
+	byID := make(map[seriesID]models.Metrics, len(chunk))
+	conds := make([]string, 0, len(chunk))
+	args := make([]any, 0, len(chunk)*2)
+	for i, m := range chunk {
+		hash := m.Labels.Hash()
+		byID[seriesID{m.ID, hash}] = m
+		conds = append(conds, fmt.Sprintf("($%d, $%d)", i*2+1, i*2+2))
+		args = append(args, m.ID, hash)
+	}
+
+	query := `SELECT id, labels_hash, ` + column + ` FROM ` + table +
+		` WHERE (id, labels_hash) IN (` + strings.Join(conds, ",") + `) ORDER BY id, labels_hash FOR UPDATE`
+	rows, err := tx.Query(ctx, query, args...)
+	if err != nil {
+		if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, context.Canceled) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to lock %s: %w", table, err)
+	}
+	defer rows.Close()
+
+	var locked []models.Metrics
+	for rows.Next() {
+		var (
+			sid   seriesID
+			value T
+		)
+		if err = rows.Scan(&sid.id, &sid.hash, &value); err != nil {
+			return nil, fmt.Errorf("failed to scan %s: %w", table, err)
+		}
+		m := byID[sid]
+		prev := models.Metrics{ID: m.ID, MType: m.MType, Labels: m.Labels}
+		set(&prev, value)
+		locked = append(locked, prev)
+	}
+	if err = rows.Err(); err != nil {
+		if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, context.Canceled) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to lock %s: %w", table, err)
+	}
+	return locked, nil
+}
+
+// execMetricsBatch writes the chunks of a batch within tx.
+func execMetricsBatch(ctx context.Context, tx pgx.Tx, gaugeChunks, counterChunks, histogramChunks [][]models.Metrics) error {
+	for i, chunk := range gaugeChunks {
+		if err := execMetricsChunk(ctx, tx, chunk, nil); err != nil {
+			return fmt.Errorf("failed to update gauge chunk %d/%d: %w", i+1, len(gaugeChunks), err)
+		}
+	}
+
+	for i, chunk := range counterChunks {
+		if err := execMetricsChunk(ctx, tx, nil, chunk); err != nil {
+			return fmt.Errorf("failed to update counter chunk %d/%d: %w", i+1, len(counterChunks), err)
+		}
+	}
+
+	for i, chunk := range histogramChunks {
+		if err := execHistogramsChunk(ctx, tx, chunk); err != nil {
+			return fmt.Errorf("failed to update histogram chunk %d/%d: %w", i+1, len(histogramChunks), err)
+		}
+	}
+
+	return nil
+}
+
+func prepareMetricsChunks(metrics []models.Metrics) ([][]models.Metrics, [][]models.Metrics, [][]models.Metrics, error) {
 	if metrics == nil {
-		return errors.New("no metrics provided: slice is nil")
+		return nil, nil, nil, errors.New("no metrics provided: slice is nil")
 	}
-	gaugeMap := make(map[string]float64)
-	counterMap := make(map[string]int64)
+	gaugeMap := make(map[string]models.Metrics)
+	counterMap := make(map[string]models.Metrics)
+	histogramMap := make(map[string]models.Metrics)
 
 	for _, m := range metrics {
 		switch m.MType {
 		case models.Gauge:
 			if m.Value == nil {
-				return errors.New("nil gauge value")
+				return nil, nil, nil, errors.New("nil gauge value")
+			}
+			value := *m.Value
+			gaugeMap[m.SeriesKey()] = models.Metrics{
+				ID:     m.ID,
+				MType:  models.Gauge,
+				Value:  &value,
+				Labels: m.Labels,
 			}
-			gaugeMap[m.ID] = *m.Value
 		case models.Counter:
 			if m.Delta == nil {
-				return errors.New("nil counter delta")
+				return nil, nil, nil, errors.New("nil counter delta")
+			}
+			key := m.SeriesKey()
+			delta := *m.Delta
+			if prev, ok := counterMap[key]; ok {
+				delta += *prev.Delta
+			}
+			counterMap[key] = models.Metrics{
+				ID:     m.ID,
+				MType:  models.Counter,
+				Delta:  &delta,
+				Labels: m.Labels,
+			}
+		case models.Histogram:
+			if m.Histogram == nil {
+				return nil, nil, nil, errors.New("nil histogram value")
+			}
+			key := m.SeriesKey()
+			if prev, ok := histogramMap[key]; ok {
+				if err := prev.Histogram.Merge(m.Histogram); err != nil {
+					return nil, nil, nil, err
+				}
+				continue
+			}
+			histogramMap[key] = models.Metrics{
+				ID:        m.ID,
+				MType:     models.Histogram,
+				Histogram: m.Histogram.Clone(),
+				Labels:    m.Labels,
 			}
-			counterMap[m.ID] += *m.Delta
 		}
 	}
 
 	gauges := make([]models.Metrics, 0, len(gaugeMap))
-	for id, v := range gaugeMap {
-		value := v
-		gauges = append(gauges, models.Metrics{
-			ID:    id,
-			Value: &value,
-			MType: models.Gauge,
-		})
+	for _, m := range gaugeMap {
+		gauges = append(gauges, m)
 	}
 
 	counters := make([]models.Metrics, 0, len(counterMap))
-	for id, d := range counterMap {
-		delta := d
-		counters = append(counters, models.Metrics{
-			ID:    id,
-			Delta: &delta,
-			MType: models.Counter,
-		})
+	for _, m := range counterMap {
+		counters = append(counters, m)
+	}
+
+	histograms := make([]models.Metrics, 0, len(histogramMap))
+	for _, m := range histogramMap {
+		histograms = append(histograms, m)
 	}
 
 	sort.Slice(gauges, func(i, j int) bool {
-		return gauges[i].ID < gauges[j].ID
+		return gauges[i].SeriesKey() < gauges[j].SeriesKey()
 	})
 
 	sort.Slice(counters, func(i, j int) bool {
-		return counters[i].ID < counters[j].ID
+		return counters[i].SeriesKey() < counters[j].SeriesKey()
 	})
 
-	gaugeChunks := splitMetricsIntoChunks(gauges, defaultChunkSize)
-	counterChunks := splitMetricsIntoChunks(counters, defaultChunkSize)
-
-	for i, chunk := range gaugeChunks {
-		if err := db.updateMetricsChunk(ctx, chunk, nil); err != nil {
-			return fmt.Errorf("failed to update gauge chunk %d/%d: %w", i+1, len(gaugeChunks), err)
-		}
-	}
-
-	for i, chunk := range counterChunks {
-		if err := db.updateMetricsChunk(ctx, nil, chunk); err != nil {
-			return fmt.Errorf("failed to update counter chunk %d/%d: %w", i+1, len(counterChunks), err)
-		}
-	}
+	sort.Slice(histograms, func(i, j int) bool {
+		return histograms[i].SeriesKey() < histograms[j].SeriesKey()
+	})
 
-	return nil
+	return splitMetricsIntoChunks(gauges, defaultChunkSize),
+		splitMetricsIntoChunks(counters, defaultChunkSize),
+		splitMetricsIntoChunks(histograms, defaultChunkSize),
+		nil
 }
 
 func splitMetricsIntoChunks(items []models.Metrics, chunkSize int) [][]models.Metrics {
@@ -194,30 +449,24 @@ func splitMetricsIntoChunks(items []models.Metrics, chunkSize int) [][]models.Me
 	return chunks
 }
 
-func (db *DB) updateMetricsChunk(ctx context.Context, gauges, counters []models.Metrics) error {
-	if len(gauges) == 0 && len(counters) == 0 {
-		return nil
-	}
-
-	tx, err := db.pool.Begin(ctx)
-	if err != nil {
-		return fmt.Errorf("failed to begin transaction: %w", err)
-	}
-	defer tx.Rollback(ctx)
-
+func execMetricsChunk(ctx context.Context, tx pgx.Tx, gauges, counters []models.Metrics) error {
 	if len(gauges) > 0 {
 		values := make([]string, 0, len(gauges))
-		args := make([]any, 0, len(gauges)*2)
+		args := make([]any, 0, len(gauges)*4)
 		for i, m := range gauges {
-			base := i * 2
-			params := fmt.Sprintf("($%d, $%d)", base+1, base+2)
+			base := i * 4
+			params := fmt.Sprintf("($%d, $%d, $%d, $%d)", base+1, base+2, base+3, base+4)
 			values = append(values, params)
-			args = append(args, m.ID, *m.Value)
+			args = append(args, m.ID, m.Labels.Hash(), labelsJSON(m.Labels), *m.Value)
 		}
 
-		query := `INSERT INTO gauges (id, value) VALUES ` + strings.Join(values, ",") + ` ON CONFLICT (id) DO UPDATE SET value = EXCLUDED.value`
+		query := `WITH upserted AS (
+			INSERT INTO gauges (id, labels_hash, labels, value) VALUES ` + strings.Join(values, ",") + ` ON CONFLICT (id, labels_hash) DO UPDATE SET value = EXCLUDED.value
+			RETURNING id, labels_hash, value
+		)
+		INSERT INTO metrics_history (id, labels_hash, mtype, value) SELECT id, labels_hash, 'gauge', value FROM upserted`
 
-		_, err = tx.Exec(ctx, query, args...)
+		_, err := tx.Exec(ctx, query, args...)
 		if err != nil {
 			if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, context.Canceled) {
 				return err
@@ -228,17 +477,21 @@ func (db *DB) updateMetricsChunk(ctx context.Context, gauges, counters []models.
 
 	if len(counters) > 0 {
 		values := make([]string, 0, len(counters))
-		args := make([]any, 0, len(counters)*2)
+		args := make([]any, 0, len(counters)*4)
 		for i, m := range counters {
-			base := i * 2
-			params := fmt.Sprintf("($%d, $%d)", base+1, base+2)
+			base := i * 4
+			params := fmt.Sprintf("($%d, $%d, $%d, $%d)", base+1, base+2, base+3, base+4)
 			values = append(values, params)
-			args = append(args, m.ID, *m.Delta)
+			args = append(args, m.ID, m.Labels.Hash(), labelsJSON(m.Labels), *m.Delta)
 		}
 
-		query := `INSERT INTO counters (id, delta) VALUES ` + strings.Join(values, ",") + ` ON CONFLICT (id) DO UPDATE SET delta = counters.delta + EXCLUDED.delta`
+		query := `WITH upserted AS (
+			INSERT INTO counters (id, labels_hash, labels, delta) VALUES ` + strings.Join(values, ",") + ` ON CONFLICT (id, labels_hash) DO UPDATE SET delta = counters.delta + EXCLUDED.delta
+			RETURNING id, labels_hash, delta
+		)
+		INSERT INTO metrics_history (id, labels_hash, mtype, value) SELECT id, labels_hash, 'counter', delta FROM upserted`
 
-		_, err = tx.Exec(ctx, query, args...)
+		_, err := tx.Exec(ctx, query, args...)
 		if err != nil {
 			if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, context.Canceled) {
 				return err
@@ -247,6 +500,24 @@ func (db *DB) updateMetricsChunk(ctx context.Context, gauges, counters []models.
 		}
 	}
 
+	return nil
+}
+
+func (db *DB) updateHistogramsChunk(ctx context.Context, histograms []models.Metrics) error {
+	if len(histograms) == 0 {
+		return nil
+	}
+
+	tx, err := db.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if err = execHistogramsChunk(ctx, tx, histograms); err != nil {
+		return err
+	}
+
 	if err = tx.Commit(ctx); err != nil {
 		return fmt.Errorf("failed to commit transaction: %w", err)
 	}
@@ -254,19 +525,106 @@ func (db *DB) updateMetricsChunk(ctx context.Context, gauges, counters []models.
 	return nil
 }
 
+// execHistogramsChunk merges histograms into the stored ones. A new series is inserted as is;
+// an existing row is locked, merged in Go so that mismatching bounds are detected, and written back.
+func execHistogramsChunk(ctx context.Context, tx pgx.Tx, histograms []models.Metrics) error {
+	for _, m := range histograms {
+		h := m.Histogram
+		hash := m.Labels.Hash()
+
+		tag, err := tx.Exec(ctx, `
+			INSERT INTO histograms (id, labels_hash, labels, bounds, counts, sum, count)
+			VALUES ($1, $2, $3, $4, $5, $6, $7)
+			ON CONFLICT (id, labels_hash) DO NOTHING
+		`, m.ID, hash, labelsJSON(m.Labels), h.Bounds, countsToDB(h.Counts), h.Sum, int64(h.Count))
+		if err != nil {
+			if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, context.Canceled) {
+				return err
+			}
+			return fmt.Errorf("failed to insert histogram %q: %w", m.ID, err)
+		}
+		if tag.RowsAffected() == 1 {
+			continue
+		}
+
+		stored, err := scanHistogram(tx.QueryRow(ctx, `
+			SELECT bounds, counts, sum, count FROM histograms WHERE id = $1 AND labels_hash = $2 FOR UPDATE
+		`, m.ID, hash))
+		if err != nil {
+			if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, context.Canceled) {
+				return err
+			}
+			return fmt.Errorf("failed to lock histogram %q: %w", m.ID, err)
+		}
+		if err = stored.Merge(h); err != nil {
+			return err
+		}
+
+		_, err = tx.Exec(ctx, `
+			UPDATE histograms SET counts = $3, sum = $4, count = $5 WHERE id = $1 AND labels_hash = $2
+		`, m.ID, hash, countsToDB(stored.Counts), stored.Sum, int64(stored.Count))
+		if err != nil {
+			if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, context.Canceled) {
+				return err
+			}
+			return fmt.Errorf("failed to update histogram %q: %w", m.ID, err)
+		}
+	}
+
+	return nil
+}
+
+func scanHistogram(row pgx.Row) (*models.HistogramValue, error) {
+	var (
+		h      models.HistogramValue
+		counts []int64
+		count  int64
+	)
+	if err := row.Scan(&h.Bounds, &counts, &h.Sum, &count); err != nil {
+		return nil, err
+	}
+	h.Counts = make([]uint64, len(counts))
+	for i, c := range counts {
+		h.Counts[i] = uint64(c)
+	}
+	h.Count = uint64(count)
+	return &h, nil
+}
+
+func countsToDB(counts []uint64) []int64 {
+	out := make([]int64, len(counts))
+	for i, c := range counts {
+		out[i] = int64(c)
+	}
+	return out
+}
+
+func labelsJSON(labels models.Labels) []byte {
+	if len(labels) == 0 {
+		return []byte("{}")
+	}
+	data, _ := json.Marshal(labels)
+	return data
+}
+
 func (db *DB) UpdateGauge(ctx context.Context, metric *models.Metrics) error {
 	if metric.Value == nil {
 		return errors.New("nil gauge value")
 	}
 
 	query := `
-		INSERT INTO gauges (id, value)
-		VALUES ( $1, $2 )
-		ON CONFLICT (id) DO UPDATE
-		SET value = $2
+		WITH upserted AS (
+			INSERT INTO gauges (id, labels_hash, labels, value)
+			VALUES ( $1, $2, $3, $4 )
+			ON CONFLICT (id, labels_hash) DO UPDATE
+			SET value = $4
+			RETURNING id, labels_hash, value
+		)
+		INSERT INTO metrics_history (id, labels_hash, mtype, value)
+		SELECT id, labels_hash, 'gauge', value FROM upserted
     `
 
-	_, err := db.pool.Exec(ctx, query, metric.ID, *metric.Value)
+	_, err := db.pool.Exec(ctx, query, metric.ID, metric.Labels.Hash(), labelsJSON(metric.Labels), *metric.Value)
 
 	if err != nil {
 		if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, context.Canceled) {
@@ -284,13 +642,18 @@ func (db *DB) UpdateCounter(ctx context.Context, metric *models.Metrics) error {
 	}
 
 	query := `
-		INSERT INTO counters (id, delta)
-		VALUES ( $1, $2 )
-		ON CONFLICT (id) DO UPDATE
-		SET delta = counters.delta + $2
+		WITH upserted AS (
+			INSERT INTO counters (id, labels_hash, labels, delta)
+			VALUES ( $1, $2, $3, $4 )
+			ON CONFLICT (id, labels_hash) DO UPDATE
+			SET delta = counters.delta + $4
+			RETURNING id, labels_hash, delta
+		)
+		INSERT INTO metrics_history (id, labels_hash, mtype, value)
+		SELECT id, labels_hash, 'counter', delta FROM upserted
     `
 
-	_, err := db.pool.Exec(ctx, query, metric.ID, *metric.Delta)
+	_, err := db.pool.Exec(ctx, query, metric.ID, metric.Labels.Hash(), labelsJSON(metric.Labels), *metric.Delta)
 
 	if err != nil {
 		if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, context.Canceled) {
@@ -302,12 +665,28 @@ func (db *DB) UpdateCounter(ctx context.Context, metric *models.Metrics) error {
 	return nil
 }
 
-func (db *DB) GetGauge(ctx context.Context, id string) (float64, error) {
+func (db *DB) UpdateHistogram(ctx context.Context, metric *models.Metrics) error {
+	if metric.Histogram == nil {
+		return errors.New("nil histogram value")
+	}
+
+	if err := db.updateHistogramsChunk(ctx, []models.Metrics{*metric}); err != nil {
+		if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, context.Canceled) || errors.Is(err, models.ErrHistogramBoundsMismatch) {
+			return err
+		}
+		return fmt.Errorf("database error: %w", err)
+	}
+
+	return nil
+}
+
+func (db *DB) GetGauge(ctx context.Context, key string) (float64, error) {
 	query := `
-		SELECT value FROM gauges WHERE id = $1
+		SELECT value FROM gauges WHERE id = $1 AND labels_hash = $2
 	`
 
-	row := db.pool.QueryRow(ctx, query, id)
+	id, labels := models.ParseSeriesKey(key)
+	row := db.pool.QueryRow(ctx, query, id, labels.Hash())
 	var value float64
 	err := row.Scan(&value)
 
@@ -324,12 +703,13 @@ func (db *DB) GetGauge(ctx context.Context, id string) (float64, error) {
 	return value, nil
 }
 
-func (db *DB) GetCounter(ctx context.Context, id string) (int64, error) {
+func (db *DB) GetCounter(ctx context.Context, key string) (int64, error) {
 	query := `
-		SELECT delta FROM counters WHERE id = $1
+		SELECT delta FROM counters WHERE id = $1 AND labels_hash = $2
 	`
 
-	row := db.pool.QueryRow(ctx, query, id)
+	id, labels := models.ParseSeriesKey(key)
+	row := db.pool.QueryRow(ctx, query, id, labels.Hash())
 	var delta int64
 	err := row.Scan(&delta)
 
@@ -346,9 +726,30 @@ func (db *DB) GetCounter(ctx context.Context, id string) (int64, error) {
 	return delta, nil
 }
 
+func (db *DB) GetHistogram(ctx context.Context, key string) (*models.HistogramValue, error) {
+	query := `
+		SELECT bounds, counts, sum, count FROM histograms WHERE id = $1 AND labels_hash = $2
+	`
+
+	id, labels := models.ParseSeriesKey(key)
+	h, err := scanHistogram(db.pool.QueryRow(ctx, query, id, labels.Hash()))
+
+	if err != nil {
+		if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, context.Canceled) {
+			return nil, err
+		}
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, models.ErrMetricNotFound
+		}
+		return nil, fmt.Errorf("database error: failed to get histogram metric: %w", err)
+	}
+
+	return h, nil
+}
+
 func (db *DB) GetAllGauges(ctx context.Context) (map[string]float64, error) {
 	query := `
-		SELECT id, value FROM gauges
+		SELECT id, labels, value FROM gauges
 	`
 
 	rows, err := db.pool.Query(ctx, query)
@@ -363,12 +764,13 @@ func (db *DB) GetAllGauges(ctx context.Context) (map[string]float64, error) {
 	m := make(map[string]float64)
 	for rows.Next() {
 		var id string
+		var labels models.Labels
 		var value float64
 
-		if err = rows.Scan(&id, &value); err != nil {
+		if err = rows.Scan(&id, &labels, &value); err != nil {
 			return nil, fmt.Errorf("database error: failed to scan gauge metrics values from database: %w", err)
 		}
-		m[id] = value
+		m[models.SeriesKey(id, labels)] = value
 	}
 
 	if err = rows.Err(); err != nil {
@@ -384,7 +786,7 @@ func (db *DB) GetAllGauges(ctx context.Context) (map[string]float64, error) {
 
 func (db *DB) GetAllCounters(ctx context.Context) (map[string]int64, error) {
 	query := `
-		SELECT id, delta FROM counters
+		SELECT id, labels, delta FROM counters
 	`
 
 	rows, err := db.pool.Query(ctx, query)
@@ -399,12 +801,13 @@ func (db *DB) GetAllCounters(ctx context.Context) (map[string]int64, error) {
 	m := make(map[string]int64)
 	for rows.Next() {
 		var id string
+		var labels models.Labels
 		var delta int64
 
-		if err = rows.Scan(&id, &delta); err != nil {
+		if err = rows.Scan(&id, &labels, &delta); err != nil {
 			return nil, fmt.Errorf("database error: failed to scan counter metrics values from database: %w", err)
 		}
-		m[id] = delta
+		m[models.SeriesKey(id, labels)] = delta
 	}
 
 	if err = rows.Err(); err != nil {
@@ -417,3 +820,90 @@ func (db *DB) GetAllCounters(ctx context.Context) (map[string]int64, error) {
 
 	return m, nil
 }
+
+func (db *DB) GetAllHistograms(ctx context.Context) (map[string]*models.HistogramValue, error) {
+	query := `
+		SELECT id, labels, bounds, counts, sum, count FROM histograms
+	`
+
+	rows, err := db.pool.Query(ctx, query)
+	if err != nil {
+		if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, context.Canceled) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("database error: failed to execute query to get all histogram metrics: %w", err)
+	}
+	defer rows.Close()
+
+	m := make(map[string]*models.HistogramValue)
+	for rows.Next() {
+		var (
+			id     string
+			labels models.Labels
+			h      models.HistogramValue
+			counts []int64
+			count  int64
+		)
+
+		if err = rows.Scan(&id, &labels, &h.Bounds, &counts, &h.Sum, &count); err != nil {
+			return nil, fmt.Errorf("database error: failed to scan histogram metrics values from database: %w", err)
+		}
+		h.Counts = make([]uint64, len(counts))
+		for i, c := range counts {
+			h.Counts[i] = uint64(c)
+		}
+		h.Count = uint64(count)
+		m[models.SeriesKey(id, labels)] = &h
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("database error: error occurred while iterating over histogram metrics: %w", err)
+	}
+
+	return m, nil
+}
+
+func (db *DB) GetHistory(ctx context.Context, mType, key string, from, to time.Time) ([]models.Sample, error) {
+	query := `
+		SELECT recorded_at, value FROM metrics_history
+		WHERE mtype = $1 AND id = $2 AND labels_hash = $3 AND recorded_at BETWEEN $4 AND $5
+		ORDER BY recorded_at
+	`
+
+	id, labels := models.ParseSeriesKey(key)
+	rows, err := db.pool.Query(ctx, query, mType, id, labels.Hash(), from, to)
+	if err != nil {
+		if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, context.Canceled) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("database error: failed to execute query to get metric history: %w", err)
+	}
+	defer rows.Close()
+
+	samples := make([]models.Sample, 0)
+	for rows.Next() {
+		var sample models.Sample
+		if err = rows.Scan(&sample.Timestamp, &sample.Value); err != nil {
+			return nil, fmt.Errorf("database error: failed to scan metric history from database: %w", err)
+		}
+		samples = append(samples, sample)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("database error: error occurred while iterating over metric history: %w", err)
+	}
+
+	return samples, nil
+}
+
+// DeleteHistoryBefore deletes history samples recorded before t and returns the number of deleted rows.
+func (db *DB) DeleteHistoryBefore(ctx context.Context, t time.Time) (int64, error) {
+	tag, err := db.pool.Exec(ctx, `DELETE FROM metrics_history WHERE recorded_at < $1`, t)
+	if err != nil {
+		if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, context.Canceled) {
+			return 0, err
+		}
+		return 0, fmt.Errorf("database error: failed to delete expired metric history: %w", err)
+	}
+	return tag.RowsAffected(), nil
+}
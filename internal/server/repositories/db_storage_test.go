@@ -0,0 +1,58 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/Pro100x3mal/go-musthave-metrics/internal/server/configs"
+	"github.com/Pro100x3mal/go-musthave-metrics/internal/server/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// newTestDB connects to the PostgreSQL database in TEST_DATABASE_DSN, skipping the test if it is not set.
+func newTestDB(t *testing.T) *DB {
+	t.Helper()
+
+	dsn := os.Getenv("TEST_DATABASE_DSN")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_DSN is not set")
+	}
+	db, err := NewDB(context.Background(), &configs.ServerConfig{DatabaseDSN: dsn}, zap.NewNop())
+	require.NoError(t, err)
+	t.Cleanup(db.Close)
+	return db
+}
+
+func TestDB_UpdateMetricsHistogramBoundsMismatch(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDB(t)
+
+	// Unique names keep the test independent of data left in the database.
+	suffix := fmt.Sprintf("_%d", time.Now().UnixNano())
+	latency, pollCount, alloc := "latency"+suffix, "PollCount"+suffix, "Alloc"+suffix
+
+	stored := &models.HistogramValue{Bounds: []float64{0.1, 1}, Counts: []uint64{1, 0, 0}, Sum: 0.05, Count: 1}
+	require.NoError(t, db.UpdateMetrics(ctx, []models.Metrics{{ID: latency, MType: models.Histogram, Histogram: stored}}))
+
+	delta, value := int64(1), 2.5
+	err := db.UpdateMetrics(ctx, []models.Metrics{
+		{ID: alloc, MType: models.Gauge, Value: &value},
+		{ID: pollCount, MType: models.Counter, Delta: &delta},
+		{ID: latency, MType: models.Histogram, Histogram: &models.HistogramValue{Bounds: []float64{5}, Counts: []uint64{1, 0}, Count: 1}},
+	})
+	assert.ErrorIs(t, err, models.ErrHistogramBoundsMismatch)
+
+	_, err = db.GetGauge(ctx, alloc)
+	assert.ErrorIs(t, err, models.ErrMetricNotFound, "batch with mismatching bounds must not be applied")
+	_, err = db.GetCounter(ctx, pollCount)
+	assert.ErrorIs(t, err, models.ErrMetricNotFound, "batch with mismatching bounds must not be applied")
+
+	h, err := db.GetHistogram(ctx, latency)
+	require.NoError(t, err)
+	assert.Equal(t, stored, h)
+}
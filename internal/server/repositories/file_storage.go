@@ -14,6 +14,8 @@ import (
 	"go.uber.org/zap"
 )
 
+// FileStorage is a MemStorage that saves the current metric values to a file and restores them on start.
+// The metric history is not saved and is lost when the server restarts.
 type FileStorage struct {
 	*MemStorage
 	logger    *zap.Logger
@@ -125,6 +127,11 @@ func (fs *FileStorage) restore() error {
 			if err != nil {
 				return fmt.Errorf("failed to update %s metric %q: %w", metric.MType, metric.ID, err)
 			}
+		case models.Histogram:
+			err = fs.MemStorage.UpdateHistogram(ctx, metric)
+			if err != nil {
+				return fmt.Errorf("failed to update %s metric %q: %w", metric.MType, metric.ID, err)
+			}
 		}
 	}
 	return nil
@@ -136,20 +143,33 @@ func (fs *FileStorage) save() error {
 
 	var list []*models.Metrics
 
-	for name, value := range fs.MemStorage.gauges {
+	for key, value := range fs.MemStorage.gauges {
 		v := value
+		id, labels := models.ParseSeriesKey(key)
 		list = append(list, &models.Metrics{
-			ID:    name,
-			MType: models.Gauge,
-			Value: &v,
+			ID:     id,
+			MType:  models.Gauge,
+			Value:  &v,
+			Labels: labels,
 		})
 	}
-	for name, delta := range fs.MemStorage.counters {
+	for key, delta := range fs.MemStorage.counters {
 		d := delta
+		id, labels := models.ParseSeriesKey(key)
+		list = append(list, &models.Metrics{
+			ID:     id,
+			MType:  models.Counter,
+			Delta:  &d,
+			Labels: labels,
+		})
+	}
+	for key, h := range fs.MemStorage.histograms {
+		id, labels := models.ParseSeriesKey(key)
 		list = append(list, &models.Metrics{
-			ID:    name,
-			MType: models.Counter,
-			Delta: &d,
+			ID:        id,
+			MType:     models.Histogram,
+			Histogram: h.Clone(),
+			Labels:    labels,
 		})
 	}
 
@@ -202,6 +222,17 @@ func (fs *FileStorage) UpdateCounter(ctx context.Context, metric *models.Metrics
 	return nil
 }
 
+func (fs *FileStorage) UpdateHistogram(ctx context.Context, metric *models.Metrics) error {
+	if err := fs.MemStorage.UpdateHistogram(ctx, metric); err != nil {
+		return err
+	}
+
+	if fs.isSync {
+		return fs.save()
+	}
+	return nil
+}
+
 func (fs *FileStorage) UpdateMetrics(ctx context.Context, metrics []models.Metrics) error {
 	if err := fs.MemStorage.UpdateMetrics(ctx, metrics); err != nil {
 		return err
@@ -212,3 +243,26 @@ func (fs *FileStorage) UpdateMetrics(ctx context.Context, metrics []models.Metri
 	}
 	return nil
 }
+
+func (fs *FileStorage) UpdateMetricsIdempotent(ctx context.Context, key string, metrics []models.Metrics) error {
+	if err := fs.MemStorage.UpdateMetricsIdempotent(ctx, key, metrics); err != nil {
+		return err
+	}
+
+	if fs.isSync {
+		return fs.save()
+	}
+	return nil
+}
+
+func (fs *FileStorage) UpdateMetricsWithPrevious(ctx context.Context, key string, metrics []models.Metrics) ([]models.Metrics, error) {
+	previous, err := fs.MemStorage.UpdateMetricsWithPrevious(ctx, key, metrics)
+	if err != nil {
+		return nil, err
+	}
+
+	if fs.isSync {
+		return previous, fs.save()
+	}
+	return previous, nil
+}
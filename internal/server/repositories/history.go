@@ -0,0 +1,111 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"github.com/Pro100x3mal/go-musthave-metrics/internal/server/models"
+	"go.uber.org/zap"
+)
+
+// DefaultHistorySize is the number of samples kept per metric by in-memory storages.
+// The history of in-memory storages is not persisted: FileStorage saves only the current values,
+// so the history starts over when the server restarts.
+const DefaultHistorySize = 1000
+
+// maxHistoryRetentionInterval is the longest pause between two history retention runs.
+const maxHistoryRetentionInterval = time.Hour
+
+// HistoryPruner deletes old history samples. DB and MemStorage implement it.
+type HistoryPruner interface {
+	DeleteHistoryBefore(ctx context.Context, t time.Time) (int64, error)
+}
+
+// RunHistoryRetention deletes history samples older than maxAge right away and then periodically, every maxAge
+// but at least once an hour, until ctx is cancelled. Failures are logged and retried on the next run.
+func RunHistoryRetention(ctx context.Context, store HistoryPruner, maxAge time.Duration, logger *zap.Logger) {
+	if maxAge <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(min(maxAge, maxHistoryRetentionInterval))
+	defer ticker.Stop()
+
+	for {
+		deleteExpiredHistory(ctx, store, maxAge, logger)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func deleteExpiredHistory(ctx context.Context, store HistoryPruner, maxAge time.Duration, logger *zap.Logger) {
+	cutoff := time.Now().Add(-maxAge)
+	deleted, err := store.DeleteHistoryBefore(ctx, cutoff)
+	if err != nil {
+		if ctx.Err() == nil {
+			logger.Error("failed to delete expired metric history", zap.Error(err))
+		}
+		return
+	}
+	if deleted > 0 {
+		logger.Info("deleted expired metric history", zap.Int64("count", deleted), zap.Time("before", cutoff))
+	}
+}
+
+func historyKey(mType, mName string) string {
+	return mType + ":" + mName
+}
+
+// sampleRing is a fixed-capacity ring buffer of samples ordered by insertion time.
+type sampleRing struct {
+	buf   []models.Sample
+	start int
+	size  int
+}
+
+func newSampleRing(capacity int) *sampleRing {
+	if capacity <= 0 {
+		capacity = DefaultHistorySize
+	}
+	return &sampleRing{
+		buf: make([]models.Sample, capacity),
+	}
+}
+
+func (r *sampleRing) push(s models.Sample) {
+	if r.size < len(r.buf) {
+		r.buf[(r.start+r.size)%len(r.buf)] = s
+		r.size++
+		return
+	}
+	r.buf[r.start] = s
+	r.start = (r.start + 1) % len(r.buf)
+}
+
+func (r *sampleRing) between(from, to time.Time) []models.Sample {
+	out := make([]models.Sample, 0)
+	for i := 0; i < r.size; i++ {
+		s := r.buf[(r.start+i)%len(r.buf)]
+		if s.Timestamp.Before(from) || s.Timestamp.After(to) {
+			continue
+		}
+		out = append(out, s)
+	}
+	return out
+}
+
+// dropBefore removes the samples recorded before t and returns their number.
+func (r *sampleRing) dropBefore(t time.Time) int {
+	dropped := 0
+	for r.size > 0 && r.buf[r.start].Timestamp.Before(t) {
+		r.buf[r.start] = models.Sample{}
+		r.start = (r.start + 1) % len(r.buf)
+		r.size--
+		dropped++
+	}
+	return dropped
+}
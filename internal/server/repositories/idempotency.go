@@ -0,0 +1,48 @@
+package repositories
+
+import (
+	"time"
+
+	"github.com/Pro100x3mal/go-musthave-metrics/internal/server/models"
+)
+
+const (
+	// IdempotencyKeyTTL is how long an applied batch key is remembered. Agents must not retry a batch for longer,
+	// which bounds the age of their spooled batches.
+	IdempotencyKeyTTL = 10 * time.Minute
+
+	maxIdempotencyKeyLength = 255
+)
+
+func validateIdempotencyKey(key string) error {
+	if key == "" || len(key) > maxIdempotencyKeyLength {
+		return models.ErrInvalidIdempotencyKey
+	}
+	return nil
+}
+
+type idempotencyKeys struct {
+	ttl  time.Duration
+	seen map[string]time.Time
+}
+
+func newIdempotencyKeys(ttl time.Duration) *idempotencyKeys {
+	return &idempotencyKeys{
+		ttl:  ttl,
+		seen: make(map[string]time.Time),
+	}
+}
+
+func (k *idempotencyKeys) contains(key string, now time.Time) bool {
+	appliedAt, ok := k.seen[key]
+	return ok && now.Sub(appliedAt) < k.ttl
+}
+
+func (k *idempotencyKeys) add(key string, now time.Time) {
+	for seenKey, appliedAt := range k.seen {
+		if now.Sub(appliedAt) >= k.ttl {
+			delete(k.seen, seenKey)
+		}
+	}
+	k.seen[key] = now
+}
@@ -2,23 +2,40 @@ package repositories
 
 import (
 	"context"
+	"time"
 
 	"github.com/Pro100x3mal/go-musthave-metrics/internal/server/models"
 )
 
 // RepositoryReader provides read-only operations for metrics storage.
+// Metrics are identified by their series key (see models.SeriesKey), which equals the metric name
+// for metrics without labels; the maps returned by GetAllGauges and GetAllCounters are keyed the same way.
 type RepositoryReader interface {
 	GetGauge(ctx context.Context, mName string) (float64, error)
 	GetCounter(ctx context.Context, mName string) (int64, error)
 	GetAllGauges(ctx context.Context) (map[string]float64, error)
 	GetAllCounters(ctx context.Context) (map[string]int64, error)
+	GetHistogram(ctx context.Context, mName string) (*models.HistogramValue, error)
+	// GetAllHistograms returns an empty map if no histograms are stored.
+	GetAllHistograms(ctx context.Context) (map[string]*models.HistogramValue, error)
+	// GetHistory returns samples of the metric recorded within [from, to] in chronological order.
+	GetHistory(ctx context.Context, mType, mName string, from, to time.Time) ([]models.Sample, error)
 }
 
 // RepositoryWriter provides write operations for metrics storage.
 type RepositoryWriter interface {
 	UpdateGauge(ctx context.Context, metric *models.Metrics) error
 	UpdateCounter(ctx context.Context, metric *models.Metrics) error
+	// UpdateHistogram merges the observations of metric into the stored histogram (see models.HistogramValue).
+	UpdateHistogram(ctx context.Context, metric *models.Metrics) error
 	UpdateMetrics(ctx context.Context, metrics []models.Metrics) error
+	// UpdateMetricsIdempotent applies the batch only if key has not been seen recently.
+	// It returns models.ErrDuplicateIdempotencyKey if the batch has already been applied.
+	UpdateMetricsIdempotent(ctx context.Context, key string, metrics []models.Metrics) error
+	// UpdateMetricsWithPrevious applies the batch like UpdateMetrics, or like UpdateMetricsIdempotent if key is not empty,
+	// and returns the values the series of the batch held before it, read atomically with the update.
+	// Series that did not exist are not returned.
+	UpdateMetricsWithPrevious(ctx context.Context, key string, metrics []models.Metrics) ([]models.Metrics, error)
 }
 
 // Repository combines read and write operations for metrics storage.
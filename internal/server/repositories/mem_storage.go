@@ -3,32 +3,71 @@ package repositories
 import (
 	"context"
 	"errors"
+	"slices"
 	"sync"
+	"time"
 
 	"github.com/Pro100x3mal/go-musthave-metrics/internal/server/models"
 )
 
 type MemStorage struct {
-	mu       *sync.RWMutex
-	gauges   map[string]float64
-	counters map[string]int64
+	mu         *sync.RWMutex
+	gauges     map[string]float64
+	counters   map[string]int64
+	histograms map[string]*models.HistogramValue
+	keys       *idempotencyKeys
+	history    map[string]*sampleRing
+	histSize   int
 }
 
 func NewMemStorage() *MemStorage {
 	return &MemStorage{
-		mu:       &sync.RWMutex{},
-		gauges:   make(map[string]float64),
-		counters: make(map[string]int64),
+		mu:         &sync.RWMutex{},
+		gauges:     make(map[string]float64),
+		counters:   make(map[string]int64),
+		histograms: make(map[string]*models.HistogramValue),
+		keys:       newIdempotencyKeys(IdempotencyKeyTTL),
+		history:    make(map[string]*sampleRing),
+		histSize:   DefaultHistorySize,
 	}
 }
 
+func (m *MemStorage) setGauge(id string, value float64, now time.Time) {
+	m.gauges[id] = value
+	m.recordSample(models.Gauge, id, value, now)
+}
+
+func (m *MemStorage) addCounter(id string, delta int64, now time.Time) {
+	m.counters[id] += delta
+	m.recordSample(models.Counter, id, float64(m.counters[id]), now)
+}
+
+func (m *MemStorage) mergeHistogram(id string, h *models.HistogramValue) error {
+	stored, ok := m.histograms[id]
+	if !ok {
+		m.histograms[id] = h.Clone()
+		return nil
+	}
+	return stored.Merge(h)
+}
+
+func (m *MemStorage) recordSample(mType, id string, value float64, now time.Time) {
+	key := historyKey(mType, id)
+	ring, ok := m.history[key]
+	if !ok {
+		ring = newSampleRing(m.histSize)
+		m.history[key] = ring
+	}
+	ring.push(models.Sample{Timestamp: now, Value: value})
+}
+
 func (m *MemStorage) UpdateGauge(_ context.Context, metric *models.Metrics) error {
 	if metric.Value == nil {
 		return errors.New("nil gauge value")
 	}
 	m.mu.Lock()
 	defer m.mu.Unlock()
-	m.gauges[metric.ID] = *metric.Value
+	m.setGauge(metric.SeriesKey(), *metric.Value, time.Now())
 	return nil
 }
 
@@ -38,26 +77,175 @@ func (m *MemStorage) UpdateCounter(_ context.Context, metric *models.Metrics) er
 	}
 	m.mu.Lock()
 	defer m.mu.Unlock()
-	m.counters[metric.ID] += *metric.Delta
+	m.addCounter(metric.SeriesKey(), *metric.Delta, time.Now())
 	return nil
 }
 
+func (m *MemStorage) UpdateHistogram(_ context.Context, metric *models.Metrics) error {
+	if metric.Histogram == nil {
+		return errors.New("nil histogram value")
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.mergeHistogram(metric.SeriesKey(), metric.Histogram)
+}
+
 func (m *MemStorage) UpdateMetrics(_ context.Context, metrics []models.Metrics) error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
+	return m.updateMetrics(metrics)
+}
+
+func (m *MemStorage) UpdateMetricsIdempotent(_ context.Context, key string, metrics []models.Metrics) error {
+	if err := validateIdempotencyKey(key); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	if m.keys.contains(key, now) {
+		return models.ErrDuplicateIdempotencyKey
+	}
+
+	if err := m.updateMetrics(metrics); err != nil {
+		return err
+	}
+
+	m.keys.add(key, now)
+	return nil
+}
+
+func (m *MemStorage) UpdateMetricsWithPrevious(_ context.Context, key string, metrics []models.Metrics) ([]models.Metrics, error) {
+	if key != "" {
+		if err := validateIdempotencyKey(key); err != nil {
+			return nil, err
+		}
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	if key != "" && m.keys.contains(key, now) {
+		return nil, models.ErrDuplicateIdempotencyKey
+	}
+
+	previous := m.previousValues(metrics)
+	if err := m.updateMetrics(metrics); err != nil {
+		return nil, err
+	}
+
+	if key != "" {
+		m.keys.add(key, now)
+	}
+	return previous, nil
+}
+
+// previousValues returns the stored values of the series of metrics that exist. The caller holds m.mu.
+func (m *MemStorage) previousValues(metrics []models.Metrics) []models.Metrics {
+	var previous []models.Metrics
+	seen := make(map[string]bool)
+	for _, metric := range metrics {
+		key := metric.SeriesKey()
+		hk := historyKey(metric.MType, key)
+		if seen[hk] {
+			continue
+		}
+		seen[hk] = true
+
+		prev := models.Metrics{ID: metric.ID, MType: metric.MType, Labels: metric.Labels}
+		switch metric.MType {
+		case models.Gauge:
+			value, ok := m.gauges[key]
+			if !ok {
+				continue
+			}
+			prev.Value = &value
+		case models.Counter:
+			delta, ok := m.counters[key]
+			if !ok {
+				continue
+			}
+			prev.Delta = &delta
+		case models.Histogram:
+			h, ok := m.histograms[key]
+			if !ok {
+				continue
+			}
+			prev.Histogram = h.Clone()
+		default:
+			continue
+		}
+		previous = append(previous, prev)
+	}
+	return previous
+}
+
+func (m *MemStorage) updateMetrics(metrics []models.Metrics) error {
+	if err := m.checkBatch(metrics); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	for _, metric := range metrics {
+		switch metric.MType {
+		case models.Gauge:
+			m.setGauge(metric.SeriesKey(), *metric.Value, now)
+		case models.Counter:
+			m.addCounter(metric.SeriesKey(), *metric.Delta, now)
+		case models.Histogram:
+			if err := m.mergeHistogram(metric.SeriesKey(), metric.Histogram); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// checkBatch verifies that every metric of the batch has a value and that all histograms can be merged
+// before anything is applied, so that a failing batch leaves the storage unchanged.
+func (m *MemStorage) checkBatch(metrics []models.Metrics) error {
+	bounds := make(map[string][]float64)
 	for _, metric := range metrics {
 		switch metric.MType {
 		case models.Gauge:
 			if metric.Value == nil {
 				return errors.New("nil gauge value")
 			}
-			m.gauges[metric.ID] = *metric.Value
 		case models.Counter:
 			if metric.Delta == nil {
 				return errors.New("nil counter delta")
 			}
-			m.counters[metric.ID] += *metric.Delta
+		case models.Histogram:
+			if metric.Histogram == nil {
+				return errors.New("nil histogram value")
+			}
+			if err := m.checkHistogramBounds(bounds, metric); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// checkHistogramBounds verifies that the histogram has the bounds of the stored series, or of the first
+// histogram of the series in the batch, recorded in bounds, if the series is new.
+func (m *MemStorage) checkHistogramBounds(bounds map[string][]float64, metric models.Metrics) error {
+	key := metric.SeriesKey()
+	expected, ok := bounds[key]
+	if !ok {
+		stored, exists := m.histograms[key]
+		if !exists {
+			bounds[key] = metric.Histogram.Bounds
+			return nil
 		}
+		expected = stored.Bounds
+		bounds[key] = expected
+	}
+	if !slices.Equal(expected, metric.Histogram.Bounds) {
+		return models.ErrHistogramBoundsMismatch
 	}
 	return nil
 }
@@ -82,6 +270,16 @@ func (m *MemStorage) GetCounter(_ context.Context, id string) (int64, error) {
 	return v, nil
 }
 
+func (m *MemStorage) GetHistogram(_ context.Context, id string) (*models.HistogramValue, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	h, exist := m.histograms[id]
+	if !exist {
+		return nil, models.ErrMetricNotFound
+	}
+	return h.Clone(), nil
+}
+
 func (m *MemStorage) GetAllGauges(_ context.Context) (map[string]float64, error) {
 	m.mu.RLock()
 	defer m.mu.RUnlock()
@@ -101,3 +299,40 @@ func (m *MemStorage) GetAllCounters(_ context.Context) (map[string]int64, error)
 	}
 	return m.counters, nil
 }
+
+func (m *MemStorage) GetAllHistograms(_ context.Context) (map[string]*models.HistogramValue, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	histograms := make(map[string]*models.HistogramValue, len(m.histograms))
+	for id, h := range m.histograms {
+		histograms[id] = h.Clone()
+	}
+	return histograms, nil
+}
+
+func (m *MemStorage) GetHistory(_ context.Context, mType, id string, from, to time.Time) ([]models.Sample, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	ring, ok := m.history[historyKey(mType, id)]
+	if !ok {
+		return []models.Sample{}, nil
+	}
+	return ring.between(from, to), nil
+}
+
+// DeleteHistoryBefore deletes history samples recorded before t and returns the number of deleted samples.
+func (m *MemStorage) DeleteHistoryBefore(_ context.Context, t time.Time) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var deleted int64
+	for key, ring := range m.history {
+		deleted += int64(ring.dropBefore(t))
+		if ring.size == 0 {
+			delete(m.history, key)
+		}
+	}
+	return deleted, nil
+}
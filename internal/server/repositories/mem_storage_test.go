@@ -0,0 +1,240 @@
+package repositories
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Pro100x3mal/go-musthave-metrics/internal/server/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemStorage_UpdateMetricsIdempotent(t *testing.T) {
+	ctx := context.Background()
+	ms := NewMemStorage()
+
+	delta := int64(5)
+	batch := []models.Metrics{{ID: "PollCount", MType: models.Counter, Delta: &delta}}
+
+	require.NoError(t, ms.UpdateMetricsIdempotent(ctx, "batch-1", batch))
+
+	err := ms.UpdateMetricsIdempotent(ctx, "batch-1", batch)
+	assert.ErrorIs(t, err, models.ErrDuplicateIdempotencyKey)
+
+	require.NoError(t, ms.UpdateMetricsIdempotent(ctx, "batch-2", batch))
+
+	got, err := ms.GetCounter(ctx, "PollCount")
+	require.NoError(t, err)
+	assert.Equal(t, int64(10), got)
+
+	err = ms.UpdateMetricsIdempotent(ctx, "", batch)
+	assert.ErrorIs(t, err, models.ErrInvalidIdempotencyKey)
+}
+
+func TestMemStorage_UpdateMetricsIdempotent_FailedBatchNotRemembered(t *testing.T) {
+	ctx := context.Background()
+	ms := NewMemStorage()
+
+	err := ms.UpdateMetricsIdempotent(ctx, "batch-1", []models.Metrics{{ID: "Alloc", MType: models.Gauge}})
+	require.Error(t, err)
+
+	value := 1.5
+	require.NoError(t, ms.UpdateMetricsIdempotent(ctx, "batch-1", []models.Metrics{{ID: "Alloc", MType: models.Gauge, Value: &value}}))
+}
+
+func TestMemStorage_UpdateMetricsIdempotent_RetriedFailedBatch(t *testing.T) {
+	ctx := context.Background()
+	ms := NewMemStorage()
+
+	delta := int64(1)
+	batch := []models.Metrics{
+		{ID: "PollCount", MType: models.Counter, Delta: &delta},
+		{ID: "Alloc", MType: models.Gauge},
+	}
+	for range 2 {
+		require.Error(t, ms.UpdateMetricsIdempotent(ctx, "batch-1", batch))
+	}
+
+	_, err := ms.GetCounter(ctx, "PollCount")
+	assert.ErrorIs(t, err, models.ErrMetricNotFound, "a failing batch is not partially applied")
+
+	value := 1.5
+	batch[1].Value = &value
+	require.NoError(t, ms.UpdateMetricsIdempotent(ctx, "batch-1", batch))
+	got, err := ms.GetCounter(ctx, "PollCount")
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), got)
+}
+
+func TestMemStorage_GetHistory(t *testing.T) {
+	ctx := context.Background()
+	ms := NewMemStorage()
+	ms.histSize = 3
+
+	start := time.Now()
+	for i := 1; i <= 5; i++ {
+		delta := int64(i)
+		require.NoError(t, ms.UpdateCounter(ctx, &models.Metrics{ID: "PollCount", MType: models.Counter, Delta: &delta}))
+	}
+
+	samples, err := ms.GetHistory(ctx, models.Counter, "PollCount", start, time.Now())
+	require.NoError(t, err)
+	require.Len(t, samples, 3)
+	assert.Equal(t, []float64{6, 10, 15}, []float64{samples[0].Value, samples[1].Value, samples[2].Value})
+
+	samples, err = ms.GetHistory(ctx, models.Counter, "PollCount", start.Add(-time.Hour), start.Add(-time.Minute))
+	require.NoError(t, err)
+	assert.Empty(t, samples)
+
+	samples, err = ms.GetHistory(ctx, models.Gauge, "PollCount", start, time.Now())
+	require.NoError(t, err)
+	assert.Empty(t, samples)
+}
+
+func TestMemStorage_DeleteHistoryBefore(t *testing.T) {
+	ctx := context.Background()
+	ms := NewMemStorage()
+	ms.histSize = 3
+
+	start := time.Now().Add(-time.Hour)
+	for i := range 4 {
+		ms.setGauge("Alloc", float64(i), start.Add(time.Duration(i)*time.Minute))
+	}
+	ms.setGauge("HeapInuse", 1, start)
+
+	deleted, err := ms.DeleteHistoryBefore(ctx, start.Add(2*time.Minute))
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), deleted)
+
+	samples, err := ms.GetHistory(ctx, models.Gauge, "Alloc", start, time.Now())
+	require.NoError(t, err)
+	assert.Equal(t, []float64{2, 3}, []float64{samples[0].Value, samples[1].Value})
+	assert.NotContains(t, ms.history, historyKey(models.Gauge, "HeapInuse"))
+
+	// The ring keeps accepting samples after the deletion.
+	ms.setGauge("Alloc", 4, start.Add(4*time.Minute))
+	ms.setGauge("Alloc", 5, start.Add(5*time.Minute))
+	samples, err = ms.GetHistory(ctx, models.Gauge, "Alloc", start, time.Now())
+	require.NoError(t, err)
+	require.Len(t, samples, 3)
+	assert.Equal(t, []float64{3, 4, 5}, []float64{samples[0].Value, samples[1].Value, samples[2].Value})
+}
+
+func TestMemStorage_Labels(t *testing.T) {
+	ctx := context.Background()
+	ms := NewMemStorage()
+
+	one, two := int64(1), int64(2)
+	require.NoError(t, ms.UpdateMetrics(ctx, []models.Metrics{
+		{ID: "Requests", MType: models.Counter, Delta: &one},
+		{ID: "Requests", MType: models.Counter, Delta: &two, Labels: models.Labels{"host": "h1"}},
+		{ID: "Requests", MType: models.Counter, Delta: &two, Labels: models.Labels{"host": "h1"}},
+	}))
+
+	plain, err := ms.GetCounter(ctx, "Requests")
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), plain)
+
+	labeled, err := ms.GetCounter(ctx, models.SeriesKey("Requests", models.Labels{"host": "h1"}))
+	require.NoError(t, err)
+	assert.Equal(t, int64(4), labeled)
+
+	_, err = ms.GetCounter(ctx, models.SeriesKey("Requests", models.Labels{"host": "h2"}))
+	assert.ErrorIs(t, err, models.ErrMetricNotFound)
+
+	counters, err := ms.GetAllCounters(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]int64{"Requests": 1, `Requests{host="h1"}`: 4}, counters)
+}
+
+func TestMemStorage_Histograms(t *testing.T) {
+	ctx := context.Background()
+	ms := NewMemStorage()
+
+	histogram := func(counts ...uint64) *models.HistogramValue {
+		var total uint64
+		for _, c := range counts {
+			total += c
+		}
+		return &models.HistogramValue{Bounds: []float64{0.1, 1}, Counts: counts, Sum: float64(total), Count: total}
+	}
+
+	require.NoError(t, ms.UpdateMetrics(ctx, []models.Metrics{
+		{ID: "latency", MType: models.Histogram, Histogram: histogram(1, 0, 0)},
+		{ID: "latency", MType: models.Histogram, Histogram: histogram(0, 2, 1)},
+	}))
+	require.NoError(t, ms.UpdateHistogram(ctx, &models.Metrics{ID: "latency", MType: models.Histogram, Histogram: histogram(1, 0, 0)}))
+
+	h, err := ms.GetHistogram(ctx, "latency")
+	require.NoError(t, err)
+	assert.Equal(t, histogram(2, 2, 1), h)
+
+	delta := int64(1)
+	err = ms.UpdateMetrics(ctx, []models.Metrics{
+		{ID: "PollCount", MType: models.Counter, Delta: &delta},
+		{ID: "latency", MType: models.Histogram, Histogram: &models.HistogramValue{Bounds: []float64{5}, Counts: []uint64{1, 0}, Count: 1}},
+	})
+	assert.ErrorIs(t, err, models.ErrHistogramBoundsMismatch)
+
+	_, err = ms.GetCounter(ctx, "PollCount")
+	assert.ErrorIs(t, err, models.ErrMetricNotFound, "batch with mismatching bounds must not be applied")
+
+	histograms, err := ms.GetAllHistograms(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]*models.HistogramValue{"latency": histogram(2, 2, 1)}, histograms)
+}
+
+func TestMemStorage_UpdateMetricsWithPrevious(t *testing.T) {
+	ctx := context.Background()
+	ms := NewMemStorage()
+
+	// Concurrent updates of the same series see each other's values: the previous values form a chain.
+	const updates = 50
+	previous := make(chan int64, updates)
+	var wg sync.WaitGroup
+	for i := 0; i < updates; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			delta := int64(1)
+			prev, err := ms.UpdateMetricsWithPrevious(ctx, "", []models.Metrics{{ID: "PollCount", MType: models.Counter, Delta: &delta}})
+			assert.NoError(t, err)
+			if len(prev) == 0 {
+				previous <- 0
+				return
+			}
+			previous <- *prev[0].Delta
+		}()
+	}
+	wg.Wait()
+	close(previous)
+
+	var got []int64
+	for p := range previous {
+		got = append(got, p)
+	}
+	slices.Sort(got)
+	want := make([]int64, updates)
+	for i := range want {
+		want[i] = int64(i)
+	}
+	assert.Equal(t, want, got)
+
+	// Only the series that existed are returned, and a replayed batch is not applied again.
+	value, delta := 1.5, int64(1)
+	batch := []models.Metrics{
+		{ID: "Alloc", MType: models.Gauge, Value: &value},
+		{ID: "PollCount", MType: models.Counter, Delta: &delta},
+	}
+	prev, err := ms.UpdateMetricsWithPrevious(ctx, "batch-1", batch)
+	require.NoError(t, err)
+	require.Len(t, prev, 1)
+	assert.Equal(t, "PollCount", prev[0].ID)
+	assert.Equal(t, int64(updates), *prev[0].Delta)
+
+	_, err = ms.UpdateMetricsWithPrevious(ctx, "batch-1", batch)
+	assert.ErrorIs(t, err, models.ErrDuplicateIdempotencyKey)
+}
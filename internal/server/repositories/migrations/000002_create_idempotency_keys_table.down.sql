@@ -0,0 +1,6 @@
+BEGIN TRANSACTION;
+
+DROP INDEX IF EXISTS idx_idempotency_keys_applied_at;
+DROP TABLE IF EXISTS idempotency_keys;
+
+COMMIT;
\ No newline at end of file
@@ -0,0 +1,11 @@
+BEGIN TRANSACTION;
+
+CREATE TABLE IF NOT EXISTS idempotency_keys
+(
+    "key"      VARCHAR(255) NOT NULL PRIMARY KEY,
+    applied_at TIMESTAMPTZ  NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_idempotency_keys_applied_at ON idempotency_keys (applied_at);
+
+COMMIT;
\ No newline at end of file
@@ -0,0 +1,6 @@
+BEGIN TRANSACTION;
+
+DROP INDEX IF EXISTS idx_metrics_history_metric_time;
+DROP TABLE IF EXISTS metrics_history;
+
+COMMIT;
\ No newline at end of file
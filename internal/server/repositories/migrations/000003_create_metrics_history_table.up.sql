@@ -0,0 +1,13 @@
+BEGIN TRANSACTION;
+
+CREATE TABLE IF NOT EXISTS metrics_history
+(
+    "id"        VARCHAR(255)     NOT NULL,
+    mtype       VARCHAR(16)      NOT NULL,
+    value       DOUBLE PRECISION NOT NULL,
+    recorded_at TIMESTAMPTZ      NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_metrics_history_metric_time ON metrics_history (mtype, id, recorded_at);
+
+COMMIT;
\ No newline at end of file
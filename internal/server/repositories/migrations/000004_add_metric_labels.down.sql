@@ -0,0 +1,20 @@
+BEGIN TRANSACTION;
+
+DROP INDEX IF EXISTS idx_metrics_history_metric_time;
+DELETE FROM metrics_history WHERE labels_hash <> '';
+ALTER TABLE metrics_history DROP COLUMN IF EXISTS labels_hash;
+CREATE INDEX idx_metrics_history_metric_time ON metrics_history (mtype, id, recorded_at);
+
+DELETE FROM counters WHERE labels_hash <> '';
+ALTER TABLE counters DROP CONSTRAINT IF EXISTS counters_pkey;
+ALTER TABLE counters DROP COLUMN IF EXISTS labels_hash;
+ALTER TABLE counters DROP COLUMN IF EXISTS labels;
+ALTER TABLE counters ADD CONSTRAINT counters_pkey PRIMARY KEY (id);
+
+DELETE FROM gauges WHERE labels_hash <> '';
+ALTER TABLE gauges DROP CONSTRAINT IF EXISTS gauges_pkey;
+ALTER TABLE gauges DROP COLUMN IF EXISTS labels_hash;
+ALTER TABLE gauges DROP COLUMN IF EXISTS labels;
+ALTER TABLE gauges ADD CONSTRAINT gauges_pkey PRIMARY KEY (id);
+
+COMMIT;
\ No newline at end of file
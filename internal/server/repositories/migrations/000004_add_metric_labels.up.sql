@@ -0,0 +1,19 @@
+BEGIN TRANSACTION;
+
+ALTER TABLE gauges ADD COLUMN IF NOT EXISTS labels JSONB NOT NULL DEFAULT '{}';
+ALTER TABLE gauges ADD COLUMN IF NOT EXISTS labels_hash VARCHAR(64) NOT NULL DEFAULT '';
+ALTER TABLE gauges DROP CONSTRAINT IF EXISTS gauges_id_key;
+ALTER TABLE gauges DROP CONSTRAINT IF EXISTS gauges_pkey;
+ALTER TABLE gauges ADD CONSTRAINT gauges_pkey PRIMARY KEY (id, labels_hash);
+
+ALTER TABLE counters ADD COLUMN IF NOT EXISTS labels JSONB NOT NULL DEFAULT '{}';
+ALTER TABLE counters ADD COLUMN IF NOT EXISTS labels_hash VARCHAR(64) NOT NULL DEFAULT '';
+ALTER TABLE counters DROP CONSTRAINT IF EXISTS counters_id_key;
+ALTER TABLE counters DROP CONSTRAINT IF EXISTS counters_pkey;
+ALTER TABLE counters ADD CONSTRAINT counters_pkey PRIMARY KEY (id, labels_hash);
+
+ALTER TABLE metrics_history ADD COLUMN IF NOT EXISTS labels_hash VARCHAR(64) NOT NULL DEFAULT '';
+DROP INDEX IF EXISTS idx_metrics_history_metric_time;
+CREATE INDEX idx_metrics_history_metric_time ON metrics_history (mtype, id, labels_hash, recorded_at);
+
+COMMIT;
\ No newline at end of file
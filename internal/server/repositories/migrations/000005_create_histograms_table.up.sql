@@ -0,0 +1,15 @@
+BEGIN TRANSACTION;
+
+CREATE TABLE IF NOT EXISTS histograms
+(
+    "id"        VARCHAR(255)       NOT NULL,
+    labels_hash VARCHAR(64)        NOT NULL DEFAULT '',
+    labels      JSONB              NOT NULL DEFAULT '{}',
+    bounds      DOUBLE PRECISION[] NOT NULL,
+    counts      BIGINT[]           NOT NULL,
+    sum         DOUBLE PRECISION   NOT NULL,
+    count       BIGINT             NOT NULL,
+    PRIMARY KEY (id, labels_hash)
+);
+
+COMMIT;
\ No newline at end of file
@@ -0,0 +1,7 @@
+BEGIN TRANSACTION;
+
+DROP INDEX IF EXISTS idx_audit_events_metrics;
+DROP INDEX IF EXISTS idx_audit_events_ts;
+DROP TABLE IF EXISTS audit_events;
+
+COMMIT;
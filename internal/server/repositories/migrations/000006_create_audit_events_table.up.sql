@@ -0,0 +1,19 @@
+BEGIN TRANSACTION;
+
+CREATE TABLE IF NOT EXISTS audit_events
+(
+    event_id     BIGSERIAL PRIMARY KEY,
+    ts           TIMESTAMPTZ    NOT NULL,
+    ip_address   VARCHAR(64)    NOT NULL DEFAULT '',
+    request_id   VARCHAR(255)   NOT NULL DEFAULT '',
+    agent_key_id VARCHAR(64)    NOT NULL DEFAULT '',
+    agent_tls_cn VARCHAR(255)   NOT NULL DEFAULT '',
+    metrics      VARCHAR(255)[] NOT NULL,
+    event        JSONB          NOT NULL,
+    recorded_at  TIMESTAMPTZ    NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_audit_events_ts ON audit_events (ts);
+CREATE INDEX idx_audit_events_metrics ON audit_events USING GIN (metrics);
+
+COMMIT;
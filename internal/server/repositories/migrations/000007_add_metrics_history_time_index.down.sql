@@ -0,0 +1,5 @@
+BEGIN TRANSACTION;
+
+DROP INDEX IF EXISTS idx_metrics_history_recorded_at;
+
+COMMIT;
@@ -0,0 +1,5 @@
+BEGIN TRANSACTION;
+
+CREATE INDEX IF NOT EXISTS idx_metrics_history_recorded_at ON metrics_history (recorded_at);
+
+COMMIT;
@@ -112,12 +112,37 @@ func (r *RepoWithRetry) UpdateCounter(ctx context.Context, metric *models.Metric
 	})
 }
 
+func (r *RepoWithRetry) UpdateHistogram(ctx context.Context, metric *models.Metrics) error {
+	return r.withRetry(ctx, func(retryCtx context.Context) error {
+		return r.inner.UpdateHistogram(retryCtx, metric)
+	})
+}
+
 func (r *RepoWithRetry) UpdateMetrics(ctx context.Context, metrics []models.Metrics) error {
 	return r.withRetry(ctx, func(retryCtx context.Context) error {
 		return r.inner.UpdateMetrics(retryCtx, metrics)
 	})
 }
 
+func (r *RepoWithRetry) UpdateMetricsIdempotent(ctx context.Context, key string, metrics []models.Metrics) error {
+	return r.withRetry(ctx, func(retryCtx context.Context) error {
+		return r.inner.UpdateMetricsIdempotent(retryCtx, key, metrics)
+	})
+}
+
+func (r *RepoWithRetry) UpdateMetricsWithPrevious(ctx context.Context, key string, metrics []models.Metrics) ([]models.Metrics, error) {
+	var out []models.Metrics
+	err := r.withRetry(ctx, func(retryCtx context.Context) error {
+		previous, err := r.inner.UpdateMetricsWithPrevious(retryCtx, key, metrics)
+		if err != nil {
+			return err
+		}
+		out = previous
+		return nil
+	})
+	return out, err
+}
+
 func (r *RepoWithRetry) GetGauge(ctx context.Context, id string) (float64, error) {
 	var out float64
 	err := r.withRetry(ctx, func(retryCtx context.Context) error {
@@ -144,6 +169,19 @@ func (r *RepoWithRetry) GetCounter(ctx context.Context, id string) (int64, error
 	return out, err
 }
 
+func (r *RepoWithRetry) GetHistogram(ctx context.Context, id string) (*models.HistogramValue, error) {
+	var out *models.HistogramValue
+	err := r.withRetry(ctx, func(retryCtx context.Context) error {
+		h, err := r.inner.GetHistogram(retryCtx, id)
+		if err != nil {
+			return err
+		}
+		out = h
+		return nil
+	})
+	return out, err
+}
+
 func (r *RepoWithRetry) GetAllGauges(ctx context.Context) (map[string]float64, error) {
 	var out map[string]float64
 	err := r.withRetry(ctx, func(retryCtx context.Context) error {
@@ -177,6 +215,38 @@ func (r *RepoWithRetry) GetAllCounters(ctx context.Context) (map[string]int64, e
 	return out, nil
 }
 
+func (r *RepoWithRetry) GetAllHistograms(ctx context.Context) (map[string]*models.HistogramValue, error) {
+	var out map[string]*models.HistogramValue
+	err := r.withRetry(ctx, func(retryCtx context.Context) error {
+		histograms, err := r.inner.GetAllHistograms(retryCtx)
+		if err != nil {
+			return err
+		}
+		out = histograms
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (r *RepoWithRetry) GetHistory(ctx context.Context, mType, mName string, from, to time.Time) ([]models.Sample, error) {
+	var out []models.Sample
+	err := r.withRetry(ctx, func(retryCtx context.Context) error {
+		samples, err := r.inner.GetHistory(retryCtx, mType, mName, from, to)
+		if err != nil {
+			return err
+		}
+		out = samples
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 func (r *RepoWithRetry) Ping(ctx context.Context) error {
 	type dbPinger interface {
 		Ping(ctx context.Context) error
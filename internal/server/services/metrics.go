@@ -2,9 +2,12 @@ package services
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"sort"
 	"strconv"
+	"time"
 
 	"github.com/Pro100x3mal/go-musthave-metrics/internal/server/models"
 	"github.com/Pro100x3mal/go-musthave-metrics/internal/server/repositories"
@@ -37,29 +40,17 @@ func NewMetricsService(repository repositories.Repository) *MetricsService {
 
 // UpdateMetricFromParams updates a metric using URL parameters.
 // It parses the metric value according to its type and updates the repository.
+// Histograms cannot be expressed as a single value and are only accepted in JSON form.
 func (ms *MetricsService) UpdateMetricFromParams(ctx context.Context, mType, mName, mValue string) error {
-	var metric models.Metrics
-	metric.ID = mName
-	metric.MType = mType
+	metric, err := models.ParseMetric(mType, mName, mValue)
+	if err != nil {
+		return err
+	}
 
-	switch mType {
-	case models.Gauge:
-		value, err := strconv.ParseFloat(mValue, 64)
-		if err != nil {
-			return models.ErrInvalidMetricValue
-		}
-		metric.Value = &value
-		return ms.writer.UpdateGauge(ctx, &metric)
-	case models.Counter:
-		delta, err := strconv.ParseInt(mValue, 10, 64)
-		if err != nil {
-			return models.ErrInvalidMetricValue
-		}
-		metric.Delta = &delta
-		return ms.writer.UpdateCounter(ctx, &metric)
-	default:
-		return models.ErrUnsupportedMetricType
+	if metric.MType == models.Gauge {
+		return ms.writer.UpdateGauge(ctx, metric)
 	}
+	return ms.writer.UpdateCounter(ctx, metric)
 }
 
 // UpdateJSONMetric updates a single metric from a JSON request.
@@ -67,93 +58,376 @@ func (ms *MetricsService) UpdateJSONMetric(ctx context.Context, metric *models.M
 	if metric == nil {
 		return models.ErrMetricNotFound
 	}
+	if err := models.ValidateMetricID(metric.ID); err != nil {
+		return err
+	}
+	if err := metric.Labels.Validate(); err != nil {
+		return err
+	}
 
 	switch metric.MType {
 	case models.Gauge:
 		return ms.writer.UpdateGauge(ctx, metric)
 	case models.Counter:
 		return ms.writer.UpdateCounter(ctx, metric)
+	case models.Histogram:
+		if err := metric.Histogram.Validate(); err != nil {
+			return err
+		}
+		return ms.writer.UpdateHistogram(ctx, metric)
 	default:
 		return models.ErrUnsupportedMetricType
 	}
 }
 
 // UpdateJSONMetrics updates multiple metrics from a JSON request in a batch operation.
+// Gauges overwrite each other, counter deltas and histograms of the same series are summed.
 func (ms *MetricsService) UpdateJSONMetrics(ctx context.Context, metrics []models.Metrics) error {
 	if metrics == nil {
 		return models.ErrMetricNotFound
 	}
+	if err := validateMetrics(metrics); err != nil {
+		return err
+	}
 	return ms.writer.UpdateMetrics(ctx, metrics)
 }
 
-// GetMetricValue retrieves a metric value as a string by its type and name.
-func (ms *MetricsService) GetMetricValue(ctx context.Context, mType, mName string) (string, error) {
+// UpdateJSONMetricsIdempotent updates multiple metrics in a batch operation unless a batch
+// with the same idempotency key has already been applied.
+// It returns models.ErrDuplicateIdempotencyKey for replayed batches.
+func (ms *MetricsService) UpdateJSONMetricsIdempotent(ctx context.Context, key string, metrics []models.Metrics) error {
+	if metrics == nil {
+		return models.ErrMetricNotFound
+	}
+	if err := validateMetrics(metrics); err != nil {
+		return err
+	}
+	return ms.writer.UpdateMetricsIdempotent(ctx, key, metrics)
+}
+
+// validateMetrics checks the whole batch before anything is written, so that an invalid metric
+// does not leave the metrics before it applied.
+func validateMetrics(metrics []models.Metrics) error {
+	for _, m := range metrics {
+		if err := models.ValidateMetricID(m.ID); err != nil {
+			return err
+		}
+		if err := m.Labels.Validate(); err != nil {
+			return err
+		}
+		switch m.MType {
+		case models.Gauge:
+			if m.Value == nil {
+				return fmt.Errorf("%w: missing value of gauge %q", models.ErrInvalidMetricValue, m.ID)
+			}
+		case models.Counter:
+			if m.Delta == nil {
+				return fmt.Errorf("%w: missing delta of counter %q", models.ErrInvalidMetricValue, m.ID)
+			}
+		case models.Histogram:
+			if err := m.Histogram.Validate(); err != nil {
+				return err
+			}
+		default:
+			return models.ErrUnsupportedMetricType
+		}
+	}
+	return nil
+}
+
+// GetMetricValue retrieves a metric value as a string by its type, name and exact label set.
+// Histograms are returned as JSON.
+func (ms *MetricsService) GetMetricValue(ctx context.Context, mType, mName string, labels models.Labels) (string, error) {
+	if err := models.ValidateMetricID(mName); err != nil {
+		return "", err
+	}
+	key := models.SeriesKey(mName, labels)
 	switch mType {
 	case models.Gauge:
-		value, err := ms.reader.GetGauge(ctx, mName)
+		value, err := ms.reader.GetGauge(ctx, key)
 		if err != nil {
 			return "", err
 		}
 		return strconv.FormatFloat(value, 'f', -1, 64), nil
 	case models.Counter:
-		value, err := ms.reader.GetCounter(ctx, mName)
+		value, err := ms.reader.GetCounter(ctx, key)
 		if err != nil {
 			return "", err
 		}
 		return strconv.FormatInt(value, 10), nil
+	case models.Histogram:
+		h, err := ms.reader.GetHistogram(ctx, key)
+		if err != nil {
+			return "", err
+		}
+		return formatHistogram(h)
 	default:
 		return "", models.ErrUnsupportedMetricType
 	}
 }
 
+func formatHistogram(h *models.HistogramValue) (string, error) {
+	data, err := json.Marshal(h)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode histogram: %w", err)
+	}
+	return string(data), nil
+}
+
 // GetJSONMetricValue retrieves a metric value and returns it as a Metrics object.
 func (ms *MetricsService) GetJSONMetricValue(ctx context.Context, metric *models.Metrics) (*models.Metrics, error) {
 	if metric == nil {
 		return nil, models.ErrMetricNotFound
 	}
+	if err := models.ValidateMetricID(metric.ID); err != nil {
+		return nil, err
+	}
+	if err := metric.Labels.Validate(); err != nil {
+		return nil, err
+	}
 
 	switch metric.MType {
 	case models.Gauge:
-		value, err := ms.reader.GetGauge(ctx, metric.ID)
+		value, err := ms.reader.GetGauge(ctx, metric.SeriesKey())
 		if err != nil {
 			return nil, err
 		}
 		metric.Value = &value
 		return metric, nil
 	case models.Counter:
-		delta, err := ms.reader.GetCounter(ctx, metric.ID)
+		delta, err := ms.reader.GetCounter(ctx, metric.SeriesKey())
 		if err != nil {
 			return nil, err
 		}
 		metric.Delta = &delta
 		return metric, nil
+	case models.Histogram:
+		h, err := ms.reader.GetHistogram(ctx, metric.SeriesKey())
+		if err != nil {
+			return nil, err
+		}
+		metric.Histogram = h
+		return metric, nil
 	default:
 		return nil, models.ErrUnsupportedMetricType
 	}
 }
 
-// GetAllMetrics retrieves all stored metrics as a map of name to value strings.
-func (ms *MetricsService) GetAllMetrics(ctx context.Context) (map[string]string, error) {
+// UpdateJSONMetricsWithChanges applies the metrics like UpdateJSONMetrics, or like UpdateJSONMetricsIdempotent
+// if key is not empty, and returns the audit changes they made. The values their series held before are read
+// by the storage atomically with the update; metrics of the same series within the batch see the values
+// written by the ones before them.
+func (ms *MetricsService) UpdateJSONMetricsWithChanges(ctx context.Context, key string, metrics []models.Metrics) ([]models.MetricChange, error) {
+	if metrics == nil {
+		return nil, models.ErrMetricNotFound
+	}
+	if err := validateMetrics(metrics); err != nil {
+		return nil, err
+	}
+
+	previous, err := ms.writer.UpdateMetricsWithPrevious(ctx, key, metrics)
+	if err != nil {
+		return nil, err
+	}
+	return buildChanges(metrics, previous), nil
+}
+
+// buildChanges returns the changes made by metrics to series that held the previous values before the batch.
+func buildChanges(metrics []models.Metrics, previous []models.Metrics) []models.MetricChange {
+	stored := make(map[string]*models.Metrics, len(previous))
+	for i := range previous {
+		stored[previous[i].MType+"/"+previous[i].SeriesKey()] = &previous[i]
+	}
+
+	changes := make([]models.MetricChange, 0, len(metrics))
+	pending := make(map[string]*models.MetricChange)
+	for i := range metrics {
+		metric := &metrics[i]
+		change := models.NewMetricChange(metric)
+		key := metric.MType + "/" + metric.SeriesKey()
+
+		if prev, ok := pending[key]; ok {
+			applyPrevious(&change, prev)
+		} else if prev, ok := stored[key]; ok {
+			change.PrevValue, change.PrevDelta, change.PrevHistogram = prev.Value, prev.Delta, prev.Histogram
+		}
+
+		changes = append(changes, change)
+		pending[key] = &changes[len(changes)-1]
+	}
+	return changes
+}
+
+// applyPrevious sets the previous value of change to the value stored after prev is applied.
+func applyPrevious(change *models.MetricChange, prev *models.MetricChange) {
+	switch change.MType {
+	case models.Gauge:
+		change.PrevValue = prev.Value
+	case models.Counter:
+		total := int64(0)
+		if prev.PrevDelta != nil {
+			total = *prev.PrevDelta
+		}
+		if prev.Delta != nil {
+			total += *prev.Delta
+		}
+		change.PrevDelta = &total
+	case models.Histogram:
+		if prev.PrevHistogram == nil {
+			change.PrevHistogram = prev.Histogram
+			return
+		}
+		merged := prev.PrevHistogram.Clone()
+		if prev.Histogram != nil && merged.Merge(prev.Histogram) == nil {
+			change.PrevHistogram = merged
+		}
+	}
+}
+
+// GetAllMetrics retrieves all stored metrics as a map of series key to value strings.
+// Only series whose labels include all matchers are returned; nil matchers select every series.
+func (ms *MetricsService) GetAllMetrics(ctx context.Context, matchers models.Labels) (map[string]string, error) {
 	list := make(map[string]string)
 
 	gauges, err := ms.reader.GetAllGauges(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("database error: %w", err)
 	}
-	for name, value := range gauges {
-		list[name] = strconv.FormatFloat(value, 'f', -1, 64)
+	for key, value := range gauges {
+		if matchSeries(key, matchers) {
+			list[key] = strconv.FormatFloat(value, 'f', -1, 64)
+		}
 	}
 
 	counters, err := ms.reader.GetAllCounters(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("database error: %w", err)
 	}
-	for name, delta := range counters {
-		list[name] = strconv.FormatInt(delta, 10)
+	for key, delta := range counters {
+		if matchSeries(key, matchers) {
+			list[key] = strconv.FormatInt(delta, 10)
+		}
+	}
+
+	histograms, err := ms.reader.GetAllHistograms(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("database error: %w", err)
+	}
+	for key, h := range histograms {
+		if !matchSeries(key, matchers) {
+			continue
+		}
+		value, err := formatHistogram(h)
+		if err != nil {
+			return nil, err
+		}
+		list[key] = value
 	}
 	return list, nil
 }
 
+func matchSeries(key string, matchers models.Labels) bool {
+	if len(matchers) == 0 {
+		return true
+	}
+	_, labels := models.ParseSeriesKey(key)
+	return labels.Matches(matchers)
+}
+
+// ListMetrics retrieves all stored metrics with their types and labels, sorted by type, name and labels.
+// Only series whose labels include all matchers are returned. Unlike GetAllMetrics, an empty storage is not an error.
+func (ms *MetricsService) ListMetrics(ctx context.Context, matchers models.Labels) ([]models.Metrics, error) {
+	gauges, err := ms.reader.GetAllGauges(ctx)
+	if err != nil && !errors.Is(err, models.ErrMetricNotFound) {
+		return nil, fmt.Errorf("database error: %w", err)
+	}
+
+	counters, err := ms.reader.GetAllCounters(ctx)
+	if err != nil && !errors.Is(err, models.ErrMetricNotFound) {
+		return nil, fmt.Errorf("database error: %w", err)
+	}
+
+	histograms, err := ms.reader.GetAllHistograms(ctx)
+	if err != nil && !errors.Is(err, models.ErrMetricNotFound) {
+		return nil, fmt.Errorf("database error: %w", err)
+	}
+
+	list := make([]models.Metrics, 0, len(gauges)+len(counters)+len(histograms))
+	for key, value := range gauges {
+		id, labels := models.ParseSeriesKey(key)
+		if !labels.Matches(matchers) {
+			continue
+		}
+		v := value
+		list = append(list, models.Metrics{ID: id, MType: models.Gauge, Value: &v, Labels: labels})
+	}
+	for key, delta := range counters {
+		id, labels := models.ParseSeriesKey(key)
+		if !labels.Matches(matchers) {
+			continue
+		}
+		d := delta
+		list = append(list, models.Metrics{ID: id, MType: models.Counter, Delta: &d, Labels: labels})
+	}
+	for key, h := range histograms {
+		id, labels := models.ParseSeriesKey(key)
+		if !labels.Matches(matchers) {
+			continue
+		}
+		list = append(list, models.Metrics{ID: id, MType: models.Histogram, Histogram: h, Labels: labels})
+	}
+
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].MType != list[j].MType {
+			return list[i].MType < list[j].MType
+		}
+		if list[i].ID != list[j].ID {
+			return list[i].ID < list[j].ID
+		}
+		return list[i].Labels.String() < list[j].Labels.String()
+	})
+	return list, nil
+}
+
+// GetMetricHistory retrieves samples of a metric series recorded within [from, to].
+// If step is positive, samples are downsampled to at most one (the latest) sample per step-wide bucket starting at from.
+func (ms *MetricsService) GetMetricHistory(ctx context.Context, mType, mName string, labels models.Labels, from, to time.Time, step time.Duration) ([]models.Sample, error) {
+	if mType != models.Gauge && mType != models.Counter {
+		return nil, models.ErrUnsupportedMetricType
+	}
+	if to.Before(from) || step < 0 {
+		return nil, models.ErrInvalidTimeRange
+	}
+	if err := models.ValidateMetricID(mName); err != nil {
+		return nil, err
+	}
+
+	samples, err := ms.reader.GetHistory(ctx, mType, models.SeriesKey(mName, labels), from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	if step == 0 || len(samples) == 0 {
+		return samples, nil
+	}
+	return downsample(samples, from, step), nil
+}
+
+func downsample(samples []models.Sample, from time.Time, step time.Duration) []models.Sample {
+	out := make([]models.Sample, 0, len(samples))
+	lastBucket := int64(-1)
+	for _, s := range samples {
+		bucket := int64(s.Timestamp.Sub(from) / step)
+		if bucket == lastBucket {
+			out[len(out)-1] = s
+			continue
+		}
+		out = append(out, s)
+		lastBucket = bucket
+	}
+	return out
+}
+
 // PingCheck verifies the health of the underlying storage connection.
 func (ms *MetricsService) PingCheck(ctx context.Context) error {
 	if ms.pinger == nil {
@@ -108,7 +108,7 @@ func BenchmarkGetMetricValue_Gauge(b *testing.B) {
 	b.ReportAllocs()
 
 	for i := 0; i < b.N; i++ {
-		_, _ = service.GetMetricValue(ctx, models.Gauge, "test_gauge")
+		_, _ = service.GetMetricValue(ctx, models.Gauge, "test_gauge", nil)
 	}
 }
 
@@ -129,7 +129,7 @@ func BenchmarkGetMetricValue_Counter(b *testing.B) {
 	b.ReportAllocs()
 
 	for i := 0; i < b.N; i++ {
-		_, _ = service.GetMetricValue(ctx, models.Counter, "test_counter")
+		_, _ = service.GetMetricValue(ctx, models.Counter, "test_counter", nil)
 	}
 }
 
@@ -181,7 +181,7 @@ func BenchmarkGetAllMetrics(b *testing.B) {
 			b.ReportAllocs()
 
 			for i := 0; i < b.N; i++ {
-				_, _ = service.GetAllMetrics(ctx)
+				_, _ = service.GetAllMetrics(ctx, nil)
 			}
 		})
 	}
@@ -3,11 +3,13 @@ package services
 import (
 	"context"
 	"testing"
+	"time"
 
 	"github.com/Pro100x3mal/go-musthave-metrics/internal/server/models"
 	mocksrepo "github.com/Pro100x3mal/go-musthave-metrics/internal/server/services/mocks"
 	"github.com/golang/mock/gomock"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func int64Ptr(v int64) *int64 {
@@ -193,6 +195,39 @@ func TestMetricsService_UpdateJSONMetric(t *testing.T) {
 			},
 			wantErr: nil,
 		},
+		{
+			name: "histogram - success",
+			metric: &models.Metrics{
+				ID:        "latency",
+				MType:     "histogram",
+				Histogram: &models.HistogramValue{Bounds: []float64{0.1, 1}, Counts: []uint64{1, 0, 2}, Sum: 7.05, Count: 3},
+			},
+			setupMock: func(m *mocksrepo.MockRepository) {
+				m.EXPECT().
+					UpdateHistogram(gomock.Any(), gomock.Any()).
+					Return(nil)
+			},
+			wantErr: nil,
+		},
+		{
+			name: "histogram - inconsistent counts",
+			metric: &models.Metrics{
+				ID:        "latency",
+				MType:     "histogram",
+				Histogram: &models.HistogramValue{Bounds: []float64{0.1, 1}, Counts: []uint64{1, 2}, Count: 3},
+			},
+			setupMock: func(m *mocksrepo.MockRepository) {},
+			wantErr:   models.ErrInvalidMetricValue,
+		},
+		{
+			name: "histogram - missing value",
+			metric: &models.Metrics{
+				ID:    "latency",
+				MType: "histogram",
+			},
+			setupMock: func(m *mocksrepo.MockRepository) {},
+			wantErr:   models.ErrInvalidMetricValue,
+		},
 	}
 
 	for _, tt := range tests {
@@ -251,6 +286,39 @@ func TestMetricsService_UpdateJSONMetrics(t *testing.T) {
 			},
 			wantErr: nil,
 		},
+		{
+			name: "invalid histogram",
+			metrics: []models.Metrics{
+				{
+					ID:        "latency",
+					MType:     "histogram",
+					Histogram: &models.HistogramValue{Bounds: []float64{1, 0.1}, Counts: []uint64{0, 0, 0}},
+				},
+			},
+			setupMock: func(m *mocksrepo.MockRepository) {},
+			wantErr:   models.ErrInvalidMetricValue,
+		},
+		{
+			name: "missing gauge value after a valid counter",
+			metrics: []models.Metrics{
+				{ID: "PollCount", MType: "counter", Delta: int64Ptr(1)},
+				{ID: "Alloc", MType: "gauge"},
+			},
+			setupMock: func(m *mocksrepo.MockRepository) {},
+			wantErr:   models.ErrInvalidMetricValue,
+		},
+		{
+			name:      "missing counter delta",
+			metrics:   []models.Metrics{{ID: "PollCount", MType: "counter"}},
+			setupMock: func(m *mocksrepo.MockRepository) {},
+			wantErr:   models.ErrInvalidMetricValue,
+		},
+		{
+			name:      "unknown type",
+			metrics:   []models.Metrics{{ID: "test", MType: "summary", Value: float64Ptr(1)}},
+			setupMock: func(m *mocksrepo.MockRepository) {},
+			wantErr:   models.ErrUnsupportedMetricType,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -352,7 +420,7 @@ func TestMetricsService_GetMetricValue(t *testing.T) {
 
 			service := NewMetricsService(mockRepo)
 
-			value, err := service.GetMetricValue(context.Background(), tt.mType, tt.mName)
+			value, err := service.GetMetricValue(context.Background(), tt.mType, tt.mName, nil)
 
 			if tt.wantErr != nil {
 				assert.ErrorIs(t, err, tt.wantErr)
@@ -525,12 +593,18 @@ func TestMetricsService_GetAllMetrics(t *testing.T) {
 						"counter1": 42,
 						"counter2": 100,
 					}, nil)
+				m.EXPECT().
+					GetAllHistograms(gomock.Any()).
+					Return(map[string]*models.HistogramValue{
+						"latency": {Bounds: []float64{0.1}, Counts: []uint64{1, 2}, Sum: 3.5, Count: 3},
+					}, nil)
 			},
 			wantMap: map[string]string{
 				"gauge1":   "3.14",
 				"gauge2":   "2.71",
 				"counter1": "42",
 				"counter2": "100",
+				"latency":  `{"bounds":[0.1],"counts":[1,2],"sum":3.5,"count":3}`,
 			},
 			wantErr: nil,
 		},
@@ -547,7 +621,7 @@ func TestMetricsService_GetAllMetrics(t *testing.T) {
 
 			service := NewMetricsService(mockRepo)
 
-			result, err := service.GetAllMetrics(context.Background())
+			result, err := service.GetAllMetrics(context.Background(), nil)
 
 			if tt.wantErr != nil {
 				assert.ErrorIs(t, err, tt.wantErr)
@@ -559,3 +633,184 @@ func TestMetricsService_GetAllMetrics(t *testing.T) {
 		})
 	}
 }
+
+func TestMetricsService_GetMetricHistory(t *testing.T) {
+	from := time.Unix(1700000000, 0)
+	to := from.Add(time.Hour)
+	samples := []models.Sample{
+		{Timestamp: from.Add(10 * time.Second), Value: 1},
+		{Timestamp: from.Add(20 * time.Second), Value: 2},
+		{Timestamp: from.Add(70 * time.Second), Value: 3},
+		{Timestamp: from.Add(130 * time.Second), Value: 4},
+		{Timestamp: from.Add(150 * time.Second), Value: 5},
+	}
+
+	tests := []struct {
+		name      string
+		mType     string
+		from      time.Time
+		to        time.Time
+		step      time.Duration
+		setupMock func(*mocksrepo.MockRepository)
+		want      []float64
+		wantErr   error
+	}{
+		{
+			name:      "unsupported type",
+			mType:     "unknown",
+			from:      from,
+			to:        to,
+			setupMock: func(m *mocksrepo.MockRepository) {},
+			wantErr:   models.ErrUnsupportedMetricType,
+		},
+		{
+			name:      "inverted range",
+			mType:     models.Gauge,
+			from:      to,
+			to:        from,
+			setupMock: func(m *mocksrepo.MockRepository) {},
+			wantErr:   models.ErrInvalidTimeRange,
+		},
+		{
+			name:  "raw samples",
+			mType: models.Gauge,
+			from:  from,
+			to:    to,
+			setupMock: func(m *mocksrepo.MockRepository) {
+				m.EXPECT().
+					GetHistory(gomock.Any(), models.Gauge, "test", from, to).
+					Return(samples, nil)
+			},
+			want: []float64{1, 2, 3, 4, 5},
+		},
+		{
+			name:  "downsampled by minute",
+			mType: models.Counter,
+			from:  from,
+			to:    to,
+			step:  time.Minute,
+			setupMock: func(m *mocksrepo.MockRepository) {
+				m.EXPECT().
+					GetHistory(gomock.Any(), models.Counter, "test", from, to).
+					Return(samples, nil)
+			},
+			want: []float64{2, 3, 5},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			mockRepo := mocksrepo.NewMockRepository(ctrl)
+			tt.setupMock(mockRepo)
+
+			service := NewMetricsService(mockRepo)
+
+			result, err := service.GetMetricHistory(context.Background(), tt.mType, "test", nil, tt.from, tt.to, tt.step)
+
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+
+			values := make([]float64, 0, len(result))
+			for _, s := range result {
+				values = append(values, s.Value)
+			}
+			assert.Equal(t, tt.want, values)
+		})
+	}
+}
+
+func TestMetricsService_ListMetrics(t *testing.T) {
+	histogram := &models.HistogramValue{Bounds: []float64{1}, Counts: []uint64{0, 1}, Sum: 2, Count: 1}
+
+	tests := []struct {
+		name      string
+		setupMock func(*mocksrepo.MockRepository)
+		want      []models.Metrics
+		wantErr   bool
+	}{
+		{
+			name: "empty storage",
+			setupMock: func(m *mocksrepo.MockRepository) {
+				m.EXPECT().GetAllGauges(gomock.Any()).Return(nil, models.ErrMetricNotFound)
+				m.EXPECT().GetAllCounters(gomock.Any()).Return(nil, models.ErrMetricNotFound)
+				m.EXPECT().GetAllHistograms(gomock.Any()).Return(map[string]*models.HistogramValue{}, nil)
+			},
+			want: []models.Metrics{},
+		},
+		{
+			name: "storage error",
+			setupMock: func(m *mocksrepo.MockRepository) {
+				m.EXPECT().GetAllGauges(gomock.Any()).Return(nil, assert.AnError)
+			},
+			wantErr: true,
+		},
+		{
+			name: "sorted by type and name",
+			setupMock: func(m *mocksrepo.MockRepository) {
+				m.EXPECT().GetAllGauges(gomock.Any()).Return(map[string]float64{"b": 2, "a": 1}, nil)
+				m.EXPECT().GetAllCounters(gomock.Any()).Return(map[string]int64{"c": 3}, nil)
+				m.EXPECT().GetAllHistograms(gomock.Any()).Return(map[string]*models.HistogramValue{"d": histogram}, nil)
+			},
+			want: []models.Metrics{
+				{ID: "c", MType: models.Counter, Delta: int64Ptr(3)},
+				{ID: "a", MType: models.Gauge, Value: float64Ptr(1)},
+				{ID: "b", MType: models.Gauge, Value: float64Ptr(2)},
+				{ID: "d", MType: models.Histogram, Histogram: histogram},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			mockRepo := mocksrepo.NewMockRepository(ctrl)
+			tt.setupMock(mockRepo)
+
+			service := NewMetricsService(mockRepo)
+
+			result, err := service.ListMetrics(context.Background(), nil)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, result)
+		})
+	}
+}
+
+func TestMetricsService_UpdateJSONMetricsWithChanges(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	mockRepo := mocksrepo.NewMockRepository(ctrl)
+
+	metrics := []models.Metrics{
+		{ID: "Alloc", MType: models.Gauge, Value: float64Ptr(2)},
+		{ID: "PollCount", MType: models.Counter, Delta: int64Ptr(5), Labels: models.Labels{"host": "a"}},
+		{ID: "PollCount", MType: models.Counter, Delta: int64Ptr(1), Labels: models.Labels{"host": "a"}},
+		{ID: "New", MType: models.Gauge, Value: float64Ptr(3)},
+		{ID: "Alloc", MType: models.Gauge, Value: float64Ptr(4)},
+	}
+	mockRepo.EXPECT().UpdateMetricsWithPrevious(gomock.Any(), "batch-1", metrics).Return([]models.Metrics{
+		{ID: "Alloc", MType: models.Gauge, Value: float64Ptr(1.5)},
+		{ID: "PollCount", MType: models.Counter, Delta: int64Ptr(10), Labels: models.Labels{"host": "a"}},
+	}, nil)
+
+	service := NewMetricsService(mockRepo)
+	changes, err := service.UpdateJSONMetricsWithChanges(context.Background(), "batch-1", metrics)
+	require.NoError(t, err)
+
+	assert.Equal(t, []models.MetricChange{
+		{ID: "Alloc", MType: models.Gauge, Value: float64Ptr(2), PrevValue: float64Ptr(1.5)},
+		{ID: "PollCount", MType: models.Counter, Delta: int64Ptr(5), Labels: models.Labels{"host": "a"}, PrevDelta: int64Ptr(10)},
+		{ID: "PollCount", MType: models.Counter, Delta: int64Ptr(1), Labels: models.Labels{"host": "a"}, PrevDelta: int64Ptr(15)},
+		{ID: "New", MType: models.Gauge, Value: float64Ptr(3)},
+		{ID: "Alloc", MType: models.Gauge, Value: float64Ptr(4), PrevValue: float64Ptr(2)},
+	}, changes)
+
+	_, err = service.UpdateJSONMetricsWithChanges(context.Background(), "", []models.Metrics{{ID: "x", MType: "summary"}})
+	assert.ErrorIs(t, err, models.ErrUnsupportedMetricType)
+}
@@ -7,6 +7,7 @@ package mocks
 import (
 	context "context"
 	reflect "reflect"
+	time "time"
 
 	models "github.com/Pro100x3mal/go-musthave-metrics/internal/server/models"
 	gomock "github.com/golang/mock/gomock"
@@ -65,6 +66,21 @@ func (mr *MockRepositoryMockRecorder) GetAllGauges(arg0 interface{}) *gomock.Cal
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAllGauges", reflect.TypeOf((*MockRepository)(nil).GetAllGauges), arg0)
 }
 
+// GetAllHistograms mocks base method.
+func (m *MockRepository) GetAllHistograms(arg0 context.Context) (map[string]*models.HistogramValue, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetAllHistograms", arg0)
+	ret0, _ := ret[0].(map[string]*models.HistogramValue)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetAllHistograms indicates an expected call of GetAllHistograms.
+func (mr *MockRepositoryMockRecorder) GetAllHistograms(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAllHistograms", reflect.TypeOf((*MockRepository)(nil).GetAllHistograms), arg0)
+}
+
 // GetCounter mocks base method.
 func (m *MockRepository) GetCounter(arg0 context.Context, arg1 string) (int64, error) {
 	m.ctrl.T.Helper()
@@ -95,6 +111,36 @@ func (mr *MockRepositoryMockRecorder) GetGauge(arg0, arg1 interface{}) *gomock.C
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetGauge", reflect.TypeOf((*MockRepository)(nil).GetGauge), arg0, arg1)
 }
 
+// GetHistogram mocks base method.
+func (m *MockRepository) GetHistogram(arg0 context.Context, arg1 string) (*models.HistogramValue, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetHistogram", arg0, arg1)
+	ret0, _ := ret[0].(*models.HistogramValue)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetHistogram indicates an expected call of GetHistogram.
+func (mr *MockRepositoryMockRecorder) GetHistogram(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetHistogram", reflect.TypeOf((*MockRepository)(nil).GetHistogram), arg0, arg1)
+}
+
+// GetHistory mocks base method.
+func (m *MockRepository) GetHistory(arg0 context.Context, arg1, arg2 string, arg3, arg4 time.Time) ([]models.Sample, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetHistory", arg0, arg1, arg2, arg3, arg4)
+	ret0, _ := ret[0].([]models.Sample)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetHistory indicates an expected call of GetHistory.
+func (mr *MockRepositoryMockRecorder) GetHistory(arg0, arg1, arg2, arg3, arg4 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetHistory", reflect.TypeOf((*MockRepository)(nil).GetHistory), arg0, arg1, arg2, arg3, arg4)
+}
+
 // UpdateCounter mocks base method.
 func (m *MockRepository) UpdateCounter(arg0 context.Context, arg1 *models.Metrics) error {
 	m.ctrl.T.Helper()
@@ -123,6 +169,20 @@ func (mr *MockRepositoryMockRecorder) UpdateGauge(arg0, arg1 interface{}) *gomoc
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateGauge", reflect.TypeOf((*MockRepository)(nil).UpdateGauge), arg0, arg1)
 }
 
+// UpdateHistogram mocks base method.
+func (m *MockRepository) UpdateHistogram(arg0 context.Context, arg1 *models.Metrics) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateHistogram", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateHistogram indicates an expected call of UpdateHistogram.
+func (mr *MockRepositoryMockRecorder) UpdateHistogram(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateHistogram", reflect.TypeOf((*MockRepository)(nil).UpdateHistogram), arg0, arg1)
+}
+
 // UpdateMetrics mocks base method.
 func (m *MockRepository) UpdateMetrics(arg0 context.Context, arg1 []models.Metrics) error {
 	m.ctrl.T.Helper()
@@ -136,3 +196,32 @@ func (mr *MockRepositoryMockRecorder) UpdateMetrics(arg0, arg1 interface{}) *gom
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateMetrics", reflect.TypeOf((*MockRepository)(nil).UpdateMetrics), arg0, arg1)
 }
+
+// UpdateMetricsIdempotent mocks base method.
+func (m *MockRepository) UpdateMetricsIdempotent(arg0 context.Context, arg1 string, arg2 []models.Metrics) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateMetricsIdempotent", arg0, arg1, arg2)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateMetricsIdempotent indicates an expected call of UpdateMetricsIdempotent.
+func (mr *MockRepositoryMockRecorder) UpdateMetricsIdempotent(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateMetricsIdempotent", reflect.TypeOf((*MockRepository)(nil).UpdateMetricsIdempotent), arg0, arg1, arg2)
+}
+
+// UpdateMetricsWithPrevious mocks base method.
+func (m *MockRepository) UpdateMetricsWithPrevious(arg0 context.Context, arg1 string, arg2 []models.Metrics) ([]models.Metrics, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateMetricsWithPrevious", arg0, arg1, arg2)
+	ret0, _ := ret[0].([]models.Metrics)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UpdateMetricsWithPrevious indicates an expected call of UpdateMetricsWithPrevious.
+func (mr *MockRepositoryMockRecorder) UpdateMetricsWithPrevious(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateMetricsWithPrevious", reflect.TypeOf((*MockRepository)(nil).UpdateMetricsWithPrevious), arg0, arg1, arg2)
+}
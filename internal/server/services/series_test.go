@@ -0,0 +1,33 @@
+package services
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Pro100x3mal/go-musthave-metrics/internal/server/models"
+	"github.com/Pro100x3mal/go-musthave-metrics/internal/server/repositories"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// An unlabeled metric must not be able to take over the series key of a labeled one.
+func TestMetricsService_SeriesKeyCollision(t *testing.T) {
+	repo := repositories.NewMemStorage()
+	ms := NewMetricsService(repo)
+	ctx := context.Background()
+
+	labeled := &models.Metrics{ID: "cpu", MType: models.Gauge, Value: float64Ptr(1), Labels: models.Labels{"host": "a"}}
+	require.NoError(t, ms.UpdateJSONMetric(ctx, labeled))
+
+	spoofed := &models.Metrics{ID: `cpu{host="a"}`, MType: models.Gauge, Value: float64Ptr(2)}
+	assert.ErrorIs(t, ms.UpdateJSONMetric(ctx, spoofed), models.ErrInvalidMetricID)
+	assert.ErrorIs(t, ms.UpdateJSONMetrics(ctx, []models.Metrics{*spoofed}), models.ErrInvalidMetricID)
+	assert.ErrorIs(t, ms.UpdateMetricFromParams(ctx, models.Gauge, `cpu{host="a"}`, "2"), models.ErrInvalidMetricID)
+
+	value, err := ms.GetMetricValue(ctx, models.Gauge, "cpu", models.Labels{"host": "a"})
+	require.NoError(t, err)
+	assert.Equal(t, "1", value)
+
+	_, err = ms.GetMetricValue(ctx, models.Gauge, `cpu{host="a"}`, nil)
+	assert.ErrorIs(t, err, models.ErrInvalidMetricID)
+}
@@ -0,0 +1,218 @@
+package statsd
+
+import (
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/Pro100x3mal/go-musthave-metrics/internal/server/models"
+)
+
+// DefaultTimerBuckets are the histogram bounds, in milliseconds, used for timer metrics.
+var DefaultTimerBuckets = []float64{5, 10, 25, 50, 100, 250, 500, 1000, 2500, 5000, 10000}
+
+// idleTTL is how long the aggregator keeps the state of a series that receives no samples:
+// the last value of a gauge and the rounding remainder of a counter.
+const idleTTL = time.Hour
+
+type counterAcc struct {
+	id     string
+	labels models.Labels
+	value  float64
+}
+
+type gaugeAcc struct {
+	id     string
+	labels models.Labels
+	value  float64
+}
+
+type timerAcc struct {
+	id     string
+	labels models.Labels
+	counts []float64
+	sum    float64
+}
+
+// window holds the values received from one source during the current flush interval.
+type window struct {
+	counters map[string]*counterAcc
+	gauges   map[string]*gaugeAcc
+	timers   map[string]*timerAcc
+}
+
+func newWindow() *window {
+	return &window{
+		counters: make(map[string]*counterAcc),
+		gauges:   make(map[string]*gaugeAcc),
+		timers:   make(map[string]*timerAcc),
+	}
+}
+
+// sourceSeries identifies a series sent by one source.
+type sourceSeries struct {
+	source, key string
+}
+
+// idleValue is a value kept across flushes together with the time the series was last updated.
+type idleValue struct {
+	value float64
+	seen  time.Time
+}
+
+// aggregator accumulates samples per source between flushes.
+// Counters and timers are scaled by the inverse sample rate; gauges keep the last value.
+// Scaled counters are rounded to whole deltas, and the fraction left over is carried to the next flush of the series.
+// Relative gauge changes are applied to the last value seen by this listener, starting from zero.
+// The windows are replaced on every flush; the last gauge values and counter remainders are dropped
+// once their series has been idle for idleTTL, so that a relative change after that starts from zero again.
+type aggregator struct {
+	mu         sync.Mutex
+	buckets    []float64
+	windows    map[string]*window
+	gauges     map[string]idleValue
+	remainders map[sourceSeries]idleValue
+	now        func() time.Time
+}
+
+func newAggregator(buckets []float64) *aggregator {
+	return &aggregator{
+		buckets:    buckets,
+		windows:    make(map[string]*window),
+		gauges:     make(map[string]idleValue),
+		remainders: make(map[sourceSeries]idleValue),
+		now:        time.Now,
+	}
+}
+
+func (a *aggregator) add(source string, s sample) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	w, ok := a.windows[source]
+	if !ok {
+		w = newWindow()
+		a.windows[source] = w
+	}
+
+	key := models.SeriesKey(s.name, s.labels)
+	switch s.kind {
+	case kindCounter:
+		acc, ok := w.counters[key]
+		if !ok {
+			acc = &counterAcc{id: s.name, labels: s.labels}
+			w.counters[key] = acc
+		}
+		acc.value += s.value / s.rate
+
+	case kindGauge:
+		value := s.value
+		if s.relative {
+			value += a.gauges[key].value
+		}
+		a.gauges[key] = idleValue{value: value, seen: a.now()}
+		w.gauges[key] = &gaugeAcc{id: s.name, labels: s.labels, value: value}
+
+	case kindTimer, kindHisto:
+		acc, ok := w.timers[key]
+		if !ok {
+			acc = &timerAcc{id: s.name, labels: s.labels, counts: make([]float64, len(a.buckets)+1)}
+			w.timers[key] = acc
+		}
+		weight := 1 / s.rate
+		acc.counts[sort.SearchFloat64s(a.buckets, s.value)] += weight
+		acc.sum += s.value * weight
+	}
+}
+
+// flush returns the aggregated metrics of every source and starts a new interval.
+func (a *aggregator) flush() map[string][]models.Metrics {
+	a.mu.Lock()
+	windows := a.windows
+	a.windows = make(map[string]*window)
+	now := a.now()
+	for source, w := range windows {
+		a.carryRemainders(source, w, now)
+	}
+	a.expire(now)
+	a.mu.Unlock()
+
+	batches := make(map[string][]models.Metrics, len(windows))
+	for source, w := range windows {
+		if metrics := w.metrics(a.buckets); len(metrics) > 0 {
+			batches[source] = metrics
+		}
+	}
+	return batches
+}
+
+// carryRemainders adds the remainders left over by the previous flushes to the counters of w
+// and keeps the fractions that rounding the new totals leaves for the next flush. The caller holds a.mu.
+func (a *aggregator) carryRemainders(source string, w *window, now time.Time) {
+	for key, acc := range w.counters {
+		series := sourceSeries{source: source, key: key}
+		acc.value += a.remainders[series].value
+		if remainder := acc.value - math.Round(acc.value); remainder != 0 {
+			a.remainders[series] = idleValue{value: remainder, seen: now}
+		} else {
+			delete(a.remainders, series)
+		}
+	}
+}
+
+// expire drops the gauge values and counter remainders of the series idle for idleTTL. The caller holds a.mu.
+func (a *aggregator) expire(now time.Time) {
+	cutoff := now.Add(-idleTTL)
+	for key, g := range a.gauges {
+		if g.seen.Before(cutoff) {
+			delete(a.gauges, key)
+		}
+	}
+	for series, r := range a.remainders {
+		if r.seen.Before(cutoff) {
+			delete(a.remainders, series)
+		}
+	}
+}
+
+func (w *window) metrics(buckets []float64) []models.Metrics {
+	metrics := make([]models.Metrics, 0, len(w.counters)+len(w.gauges)+len(w.timers))
+
+	for _, key := range sortedKeys(w.counters) {
+		acc := w.counters[key]
+		delta := int64(math.Round(acc.value))
+		metrics = append(metrics, models.Metrics{ID: acc.id, MType: models.Counter, Delta: &delta, Labels: acc.labels})
+	}
+
+	for _, key := range sortedKeys(w.gauges) {
+		acc := w.gauges[key]
+		value := acc.value
+		metrics = append(metrics, models.Metrics{ID: acc.id, MType: models.Gauge, Value: &value, Labels: acc.labels})
+	}
+
+	for _, key := range sortedKeys(w.timers) {
+		acc := w.timers[key]
+		h := &models.HistogramValue{
+			Bounds: buckets,
+			Counts: make([]uint64, len(acc.counts)),
+			Sum:    acc.sum,
+		}
+		for i, c := range acc.counts {
+			h.Counts[i] = uint64(math.Round(c))
+			h.Count += h.Counts[i]
+		}
+		metrics = append(metrics, models.Metrics{ID: acc.id, MType: models.Histogram, Histogram: h, Labels: acc.labels})
+	}
+
+	return metrics
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
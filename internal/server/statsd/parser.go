@@ -0,0 +1,100 @@
+package statsd
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/Pro100x3mal/go-musthave-metrics/internal/server/models"
+)
+
+// StatsD metric kinds accepted by the listener.
+const (
+	kindCounter = "c"
+	kindGauge   = "g"
+	kindTimer   = "ms"
+	kindHisto   = "h"
+)
+
+// ErrInvalidLine is returned for lines that do not follow the StatsD line protocol.
+var ErrInvalidLine = errors.New("invalid statsd line")
+
+// sample is a single parsed StatsD line.
+type sample struct {
+	name     string
+	kind     string
+	value    float64
+	relative bool
+	rate     float64
+	labels   models.Labels
+}
+
+// parseLine parses a line of the form name:value|type[|@rate][|#tag:value,...].
+// Gauge values with an explicit sign are relative changes. DogStatsD tags become labels; tags without a value are ignored.
+func parseLine(line string) (sample, error) {
+	s := sample{rate: 1}
+
+	name, rest, ok := strings.Cut(line, ":")
+	if !ok || name == "" {
+		return s, fmt.Errorf("%w: missing name in %q", ErrInvalidLine, line)
+	}
+	if err := models.ValidateMetricID(name); err != nil {
+		return s, fmt.Errorf("%w: %w", ErrInvalidLine, err)
+	}
+	s.name = name
+
+	parts := strings.Split(rest, "|")
+	if len(parts) < 2 {
+		return s, fmt.Errorf("%w: missing type in %q", ErrInvalidLine, line)
+	}
+
+	value, err := strconv.ParseFloat(parts[0], 64)
+	if err != nil || math.IsNaN(value) || math.IsInf(value, 0) {
+		return s, fmt.Errorf("%w: bad value in %q", ErrInvalidLine, line)
+	}
+	s.value = value
+
+	s.kind = parts[1]
+	switch s.kind {
+	case kindCounter, kindTimer, kindHisto:
+	case kindGauge:
+		s.relative = strings.HasPrefix(parts[0], "+") || strings.HasPrefix(parts[0], "-")
+	default:
+		return s, fmt.Errorf("%w: unsupported type %q", ErrInvalidLine, s.kind)
+	}
+
+	for _, field := range parts[2:] {
+		switch {
+		case strings.HasPrefix(field, "@"):
+			rate, err := strconv.ParseFloat(field[1:], 64)
+			if err != nil || rate <= 0 || rate > 1 {
+				return s, fmt.Errorf("%w: bad sample rate in %q", ErrInvalidLine, line)
+			}
+			s.rate = rate
+		case strings.HasPrefix(field, "#"):
+			s.labels = parseTags(field[1:])
+			if err = s.labels.Validate(); err != nil {
+				return s, fmt.Errorf("%w: %w", ErrInvalidLine, err)
+			}
+		}
+	}
+
+	return s, nil
+}
+
+func parseTags(tags string) models.Labels {
+	var labels models.Labels
+	for _, tag := range strings.Split(tags, ",") {
+		name, value, ok := strings.Cut(tag, ":")
+		if !ok || name == "" {
+			continue
+		}
+		if labels == nil {
+			labels = make(models.Labels)
+		}
+		labels[name] = value
+	}
+	return labels
+}
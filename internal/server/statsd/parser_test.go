@@ -0,0 +1,47 @@
+package statsd
+
+import (
+	"testing"
+
+	"github.com/Pro100x3mal/go-musthave-metrics/internal/server/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseLine(t *testing.T) {
+	tests := []struct {
+		name    string
+		line    string
+		want    sample
+		wantErr bool
+	}{
+		{name: "counter", line: "api.requests:1|c", want: sample{name: "api.requests", kind: kindCounter, value: 1, rate: 1}},
+		{name: "sampled counter", line: "hits:3|c|@0.1", want: sample{name: "hits", kind: kindCounter, value: 3, rate: 0.1}},
+		{name: "gauge", line: "temp:3.2|g", want: sample{name: "temp", kind: kindGauge, value: 3.2, rate: 1}},
+		{name: "relative gauge", line: "temp:-1|g", want: sample{name: "temp", kind: kindGauge, value: -1, relative: true, rate: 1}},
+		{name: "timer", line: "latency:12|ms", want: sample{name: "latency", kind: kindTimer, value: 12, rate: 1}},
+		{
+			name: "tags",
+			line: "latency:12|ms|@0.5|#host:h1,env:prod,flag",
+			want: sample{name: "latency", kind: kindTimer, value: 12, rate: 0.5, labels: models.Labels{"host": "h1", "env": "prod"}},
+		},
+		{name: "missing type", line: "hits:1", wantErr: true},
+		{name: "missing name", line: ":1|c", wantErr: true},
+		{name: "bad value", line: "hits:x|c", wantErr: true},
+		{name: "unsupported type", line: "users:42|s", wantErr: true},
+		{name: "bad rate", line: "hits:1|c|@2", wantErr: true},
+		{name: "bad tag name", line: "hits:1|c|#1host:h1", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseLine(tt.line)
+			if tt.wantErr {
+				assert.ErrorIs(t, err, ErrInvalidLine)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
@@ -0,0 +1,150 @@
+package statsd
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/Pro100x3mal/go-musthave-metrics/internal/server/infrastructure/audit"
+	"github.com/Pro100x3mal/go-musthave-metrics/internal/server/models"
+	"go.uber.org/zap"
+)
+
+const (
+	maxPacketSize = 65535
+	flushTimeout  = 5 * time.Second
+)
+
+// MetricsWriter stores aggregated metrics.
+type MetricsWriter interface {
+	UpdateJSONMetrics(ctx context.Context, metrics []models.Metrics) error
+}
+
+// Server receives StatsD metrics over UDP and periodically writes their aggregates.
+// Metrics of one flush interval are written as one batch per source address and audited like HTTP batch updates.
+type Server struct {
+	conn          net.PacketConn
+	flushInterval time.Duration
+	writer        MetricsWriter
+	publisher     audit.Publisher
+	agg           *aggregator
+	logger        *zap.Logger
+}
+
+// NewServer binds a UDP socket on addr. The publisher may be nil to disable auditing.
+func NewServer(addr string, flushInterval time.Duration, writer MetricsWriter, publisher audit.Publisher, logger *zap.Logger) (*Server, error) {
+	if flushInterval <= 0 {
+		return nil, fmt.Errorf("invalid statsd flush interval %s", flushInterval)
+	}
+
+	conn, err := net.ListenPacket("udp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on %q: %w", addr, err)
+	}
+
+	return &Server{
+		conn:          conn,
+		flushInterval: flushInterval,
+		writer:        writer,
+		publisher:     publisher,
+		agg:           newAggregator(DefaultTimerBuckets),
+		logger:        logger,
+	}, nil
+}
+
+// Addr returns the local address the server listens on.
+func (s *Server) Addr() net.Addr {
+	return s.conn.LocalAddr()
+}
+
+// Run receives packets and flushes aggregates every flush interval until ctx is done.
+// The socket is closed and the last interval is flushed before Run returns.
+func (s *Server) Run(ctx context.Context) {
+	s.logger.Info("statsd listener started", zap.String("address", s.conn.LocalAddr().String()))
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		s.receive()
+	}()
+
+	ticker := time.NewTicker(s.flushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			s.flush(ctx)
+		case <-ctx.Done():
+			if err := s.conn.Close(); err != nil {
+				s.logger.Error("failed to close statsd socket", zap.Error(err))
+			}
+			<-done
+
+			flushCtx, cancel := context.WithTimeout(context.Background(), flushTimeout)
+			s.flush(flushCtx)
+			cancel()
+
+			s.logger.Info("statsd listener stopped")
+			return
+		}
+	}
+}
+
+func (s *Server) receive() {
+	buf := make([]byte, maxPacketSize)
+	for {
+		n, addr, err := s.conn.ReadFrom(buf)
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return
+			}
+			s.logger.Error("failed to read statsd packet", zap.Error(err))
+			continue
+		}
+		s.handlePacket(sourceIP(addr), buf[:n])
+	}
+}
+
+func (s *Server) handlePacket(source string, packet []byte) {
+	for _, line := range bytes.Split(packet, []byte{'\n'}) {
+		line = bytes.TrimSpace(line)
+		if len(line) == 0 {
+			continue
+		}
+
+		sample, err := parseLine(string(line))
+		if err != nil {
+			s.logger.Debug("skipping statsd line", zap.String("source", source), zap.Error(err))
+			continue
+		}
+		s.agg.add(source, sample)
+	}
+}
+
+func (s *Server) flush(ctx context.Context) {
+	for source, metrics := range s.agg.flush() {
+		if err := s.writer.UpdateJSONMetrics(ctx, metrics); err != nil {
+			s.logger.Error("failed to store statsd metrics", zap.String("source", source), zap.Int("count", len(metrics)), zap.Error(err))
+			continue
+		}
+
+		if s.publisher != nil && s.publisher.HasObservers() {
+			s.publisher.NotifyAll(ctx, audit.NewAuditEventFromMetrics(metrics, source))
+		}
+	}
+}
+
+func sourceIP(addr net.Addr) string {
+	if udpAddr, ok := addr.(*net.UDPAddr); ok {
+		return udpAddr.IP.String()
+	}
+	host, _, err := net.SplitHostPort(addr.String())
+	if err != nil {
+		return addr.String()
+	}
+	return host
+}
@@ -0,0 +1,153 @@
+package statsd
+
+import (
+	"context"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Pro100x3mal/go-musthave-metrics/internal/server/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+type recordingWriter struct {
+	mu      sync.Mutex
+	batches [][]models.Metrics
+}
+
+func (rw *recordingWriter) UpdateJSONMetrics(_ context.Context, metrics []models.Metrics) error {
+	rw.mu.Lock()
+	defer rw.mu.Unlock()
+	rw.batches = append(rw.batches, metrics)
+	return nil
+}
+
+func TestAggregator_Flush(t *testing.T) {
+	agg := newAggregator([]float64{10, 100})
+
+	for _, line := range []string{
+		"hits:1|c",
+		"hits:2|c|@0.5",
+		"temp:20|g",
+		"temp:+5|g",
+		"latency:10|ms",
+		"latency:50|ms|@0.5",
+		"latency:500|ms",
+	} {
+		s, err := parseLine(line)
+		require.NoError(t, err)
+		agg.add("10.0.0.1", s)
+	}
+
+	batches := agg.flush()
+	require.Len(t, batches, 1)
+
+	delta := int64(5)
+	value := 25.0
+	assert.Equal(t, []models.Metrics{
+		{ID: "hits", MType: models.Counter, Delta: &delta},
+		{ID: "temp", MType: models.Gauge, Value: &value},
+		{ID: "latency", MType: models.Histogram, Histogram: &models.HistogramValue{
+			Bounds: []float64{10, 100},
+			Counts: []uint64{1, 2, 1},
+			Sum:    610,
+			Count:  4,
+		}},
+	}, batches["10.0.0.1"])
+
+	assert.Empty(t, agg.flush(), "a flush starts a new interval")
+
+	s, err := parseLine("temp:-10|g")
+	require.NoError(t, err)
+	agg.add("10.0.0.1", s)
+	value = 15
+	assert.Equal(t, []models.Metrics{{ID: "temp", MType: models.Gauge, Value: &value}}, agg.flush()["10.0.0.1"])
+}
+
+func TestAggregator_CounterRemainder(t *testing.T) {
+	agg := newAggregator(DefaultTimerBuckets)
+	s, err := parseLine("hits:1|c|@0.4")
+	require.NoError(t, err)
+
+	// Every sample counts 2.5: the halves lost by rounding are carried over and add up across flushes.
+	var total int64
+	for range 4 {
+		agg.add("10.0.0.1", s)
+		batch := agg.flush()["10.0.0.1"]
+		require.Len(t, batch, 1)
+		total += *batch[0].Delta
+	}
+	assert.Equal(t, int64(10), total)
+	assert.Empty(t, agg.remainders, "no remainder is left once the counts add up to whole deltas")
+}
+
+func TestAggregator_ExpireIdle(t *testing.T) {
+	now := time.Now()
+	agg := newAggregator(DefaultTimerBuckets)
+	agg.now = func() time.Time { return now }
+
+	for _, line := range []string{"temp:20|g", "hits:1|c|@0.4"} {
+		s, err := parseLine(line)
+		require.NoError(t, err)
+		agg.add("10.0.0.1", s)
+	}
+	agg.flush()
+	require.Len(t, agg.gauges, 1)
+	require.Len(t, agg.remainders, 1)
+
+	now = now.Add(idleTTL / 2)
+	agg.flush()
+	assert.Len(t, agg.gauges, 1, "series idle for less than idleTTL are kept")
+	assert.Len(t, agg.remainders, 1)
+
+	now = now.Add(idleTTL)
+	agg.flush()
+	assert.Empty(t, agg.gauges)
+	assert.Empty(t, agg.remainders)
+
+	s, err := parseLine("temp:+5|g")
+	require.NoError(t, err)
+	agg.add("10.0.0.1", s)
+	value := 5.0
+	assert.Equal(t, []models.Metrics{{ID: "temp", MType: models.Gauge, Value: &value}}, agg.flush()["10.0.0.1"],
+		"a relative change to an expired gauge starts from zero")
+}
+
+func TestServer_Run(t *testing.T) {
+	writer := &recordingWriter{}
+	srv, err := NewServer("127.0.0.1:0", time.Hour, writer, nil, zap.NewNop())
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		srv.Run(ctx)
+	}()
+
+	conn, err := net.Dial("udp", srv.Addr().String())
+	require.NoError(t, err)
+	defer conn.Close()
+
+	_, err = conn.Write([]byte("hits:1|c\nhits:2|c\nbroken\n"))
+	require.NoError(t, err)
+
+	require.Eventually(t, func() bool {
+		srv.agg.mu.Lock()
+		defer srv.agg.mu.Unlock()
+		w, ok := srv.agg.windows["127.0.0.1"]
+		return ok && len(w.counters) == 1 && w.counters["hits"].value == 3
+	}, time.Second, 10*time.Millisecond)
+
+	cancel()
+	<-done
+
+	writer.mu.Lock()
+	defer writer.mu.Unlock()
+	require.Len(t, writer.batches, 1, "the last interval is flushed on shutdown")
+	require.Len(t, writer.batches[0], 1)
+	assert.Equal(t, int64(3), *writer.batches[0][0].Delta)
+}
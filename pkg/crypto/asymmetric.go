@@ -1,9 +1,7 @@
 package crypto
 
 import (
-	"crypto/rand"
 	"crypto/rsa"
-	"crypto/sha256"
 	"crypto/x509"
 	"encoding/pem"
 	"fmt"
@@ -56,28 +54,3 @@ func LoadPublicKey(path string) (*rsa.PublicKey, error) {
 	return nil, fmt.Errorf("public key is not an RSA key")
 
 }
-
-func Encrypt(publicKey *rsa.PublicKey, data []byte) ([]byte, error) {
-	if publicKey == nil {
-		return nil, fmt.Errorf("public key is nil")
-	}
-
-	ciphertext, err := rsa.EncryptOAEP(sha256.New(), rand.Reader, publicKey, data, nil)
-	if err != nil {
-		return nil, fmt.Errorf("failed to encrypt data: %w", err)
-	}
-
-	return ciphertext, nil
-}
-
-func Decrypt(privateKey *rsa.PrivateKey, data []byte) ([]byte, error) {
-	if privateKey == nil {
-		return nil, fmt.Errorf("private key is nil")
-	}
-
-	plaintext, err := rsa.DecryptOAEP(sha256.New(), rand.Reader, privateKey, data, nil)
-	if err != nil {
-		return nil, fmt.Errorf("failed to decrypt data: %w", err)
-	}
-	return plaintext, nil
-}
@@ -0,0 +1,135 @@
+package crypto
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// EnvelopeVersion1 is the envelope format produced by Encrypt:
+//
+//	version (1 byte) | wrapped key length (2 bytes, big endian) | RSA-OAEP wrapped AES-256 key | GCM nonce | AES-GCM ciphertext
+//
+// The version byte and key length are authenticated as GCM additional data.
+const EnvelopeVersion1 byte = 1
+
+const (
+	aesKeySize         = 32
+	envelopeHeaderSize = 3
+)
+
+var (
+	// ErrUnsupportedEnvelopeVersion is returned when the envelope carries an unknown format version.
+	ErrUnsupportedEnvelopeVersion = errors.New("unsupported envelope version")
+	// ErrMalformedEnvelope is returned when the envelope is truncated or its lengths are inconsistent.
+	ErrMalformedEnvelope = errors.New("malformed envelope")
+)
+
+// Encrypt seals data into a versioned envelope: the payload is encrypted with a random
+// AES-256-GCM key and that key is wrapped with RSA-OAEP (SHA-256) using publicKey.
+// Unlike plain RSA-OAEP, the payload size is not limited by the RSA key size.
+func Encrypt(publicKey *rsa.PublicKey, data []byte) ([]byte, error) {
+	if publicKey == nil {
+		return nil, fmt.Errorf("public key is nil")
+	}
+
+	key := make([]byte, aesKeySize)
+	if _, err := rand.Read(key); err != nil {
+		return nil, fmt.Errorf("failed to generate data key: %w", err)
+	}
+
+	wrappedKey, err := rsa.EncryptOAEP(sha256.New(), rand.Reader, publicKey, key, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encrypt data key: %w", err)
+	}
+
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err = rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+
+	header := make([]byte, envelopeHeaderSize)
+	header[0] = EnvelopeVersion1
+	binary.BigEndian.PutUint16(header[1:], uint16(len(wrappedKey)))
+
+	out := make([]byte, 0, len(header)+len(wrappedKey)+len(nonce)+len(data)+gcm.Overhead())
+	out = append(out, header...)
+	out = append(out, wrappedKey...)
+	out = append(out, nonce...)
+	out = gcm.Seal(out, nonce, data, header)
+
+	return out, nil
+}
+
+// Decrypt opens an envelope produced by Encrypt using privateKey.
+func Decrypt(privateKey *rsa.PrivateKey, data []byte) ([]byte, error) {
+	if privateKey == nil {
+		return nil, fmt.Errorf("private key is nil")
+	}
+
+	if len(data) < envelopeHeaderSize {
+		return nil, ErrMalformedEnvelope
+	}
+
+	if data[0] != EnvelopeVersion1 {
+		return nil, fmt.Errorf("%w: %d", ErrUnsupportedEnvelopeVersion, data[0])
+	}
+
+	header := data[:envelopeHeaderSize]
+	keyLen := int(binary.BigEndian.Uint16(header[1:]))
+	rest := data[envelopeHeaderSize:]
+	if len(rest) < keyLen {
+		return nil, ErrMalformedEnvelope
+	}
+
+	key, err := rsa.DecryptOAEP(sha256.New(), rand.Reader, privateKey, rest[:keyLen], nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt data key: %w", err)
+	}
+	rest = rest[keyLen:]
+
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(rest) < gcm.NonceSize()+gcm.Overhead() {
+		return nil, ErrMalformedEnvelope
+	}
+
+	nonce, ciphertext := rest[:gcm.NonceSize()], rest[gcm.NonceSize():]
+	plaintext, err := gcm.Open(nil, nonce, ciphertext, header)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt data: %w", err)
+	}
+
+	return plaintext, nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	if len(key) != aesKeySize {
+		return nil, fmt.Errorf("%w: invalid data key size %d", ErrMalformedEnvelope, len(key))
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create AES cipher: %w", err)
+	}
+
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create GCM: %w", err)
+	}
+
+	return gcm, nil
+}
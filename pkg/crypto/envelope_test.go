@@ -0,0 +1,69 @@
+package crypto
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/rsa"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEncryptDecrypt(t *testing.T) {
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{name: "empty", data: []byte{}},
+		{name: "small", data: []byte(`{"id":"PollCount","type":"counter","delta":1}`)},
+		{name: "larger than RSA block", data: bytes.Repeat([]byte("metrics"), 10000)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			envelope, err := Encrypt(&privateKey.PublicKey, tt.data)
+			require.NoError(t, err)
+			assert.Equal(t, EnvelopeVersion1, envelope[0])
+
+			plaintext, err := Decrypt(privateKey, envelope)
+			require.NoError(t, err)
+			assert.Equal(t, string(tt.data), string(plaintext))
+		})
+	}
+}
+
+func TestDecrypt_Errors(t *testing.T) {
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	envelope, err := Encrypt(&privateKey.PublicKey, []byte("payload"))
+	require.NoError(t, err)
+
+	t.Run("unsupported version", func(t *testing.T) {
+		broken := bytes.Clone(envelope)
+		broken[0] = 99
+		_, err := Decrypt(privateKey, broken)
+		assert.ErrorIs(t, err, ErrUnsupportedEnvelopeVersion)
+	})
+
+	t.Run("truncated", func(t *testing.T) {
+		_, err := Decrypt(privateKey, envelope[:10])
+		assert.ErrorIs(t, err, ErrMalformedEnvelope)
+	})
+
+	t.Run("tampered ciphertext", func(t *testing.T) {
+		broken := bytes.Clone(envelope)
+		broken[len(broken)-1] ^= 0xff
+		_, err := Decrypt(privateKey, broken)
+		assert.Error(t, err)
+	})
+
+	t.Run("nil key", func(t *testing.T) {
+		_, err := Decrypt(nil, envelope)
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,4 @@
+// Package metricspb contains the gRPC contract generated from api/metrics.proto.
+package metricspb
+
+//go:generate protoc --proto_path=../../api --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative metrics.proto
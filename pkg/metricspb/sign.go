@@ -0,0 +1,34 @@
+package metricspb
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+
+	"google.golang.org/protobuf/proto"
+)
+
+// SignatureMetadataKey is the metadata key carrying the HMAC-SHA256 signature of unary requests and responses.
+const SignatureMetadataKey = "hashsha256"
+
+// Sign returns the hex HMAC-SHA256 of the deterministic serialization of m.
+func Sign(m proto.Message, key string) (string, error) {
+	data, err := proto.MarshalOptions{Deterministic: true}.Marshal(m)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal message: %w", err)
+	}
+
+	h := hmac.New(sha256.New, []byte(key))
+	h.Write(data)
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// Verify reports whether signature is the valid hex HMAC-SHA256 of m.
+func Verify(m proto.Message, key, signature string) (bool, error) {
+	expected, err := Sign(m, key)
+	if err != nil {
+		return false, err
+	}
+	return hmac.Equal([]byte(expected), []byte(signature)), nil
+}